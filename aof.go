@@ -0,0 +1,679 @@
+package memkey
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrClosed returned when closed resource is used
+var ErrClosed = errors.New("memkey: closed")
+
+// FsyncPolicy defines how often append-only log is synced to disk
+type FsyncPolicy uint8
+
+const (
+	// FsyncEverySecond syncs log to disk once per second if there were any changes
+	FsyncEverySecond FsyncPolicy = iota
+	// FsyncAlways syncs log to disk after every change
+	FsyncAlways
+	// FsyncNever leaves syncing to the operating system
+	FsyncNever
+)
+
+// AOFConfig represents configuration of append-only log
+type AOFConfig struct {
+	// Path is a path to log file, it's created if not exists
+	Path string
+	// Codec is used to encode records, if nil JSONCodec is used
+	Codec Codec
+	// Fsync is a policy of syncing log to disk
+	Fsync FsyncPolicy
+	// RewriteSize is a number of bytes log can grow since last rewrite before it's rewritten in background,
+	// if zero log is rewritten only by calling Rewrite
+	RewriteSize int64
+	// OnError is called with error of a change that can't be encoded, such change is recorded as deletion of its
+	// key, so an outdated value is not restored from the log. Writes made by TrySet and TrySetWithTTL of stores are
+	// rejected instead. It's also called with error of writing the log, see AOF.Err. It's called
+	// while the store lock is held, so it must not call back into the store
+	OnError func(err error)
+}
+
+const (
+	aofFrameHeaderSize = 8
+	aofMaxFrameSize    = 1 << 30
+	aofSyncInterval    = time.Second
+)
+
+// aofRecord is a single change in append-only log, set records are followed by encoded value
+type aofRecord[K comparable] struct {
+	Kind     EventKind  `json:"kind"`
+	Key      K          `json:"key"`
+	Type     string     `json:"type,omitempty"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// valueCoder encodes and decodes values of a store
+type valueCoder[V any] interface {
+	typeName(value V) (string, error)
+	decode(decoder Decoder, typeName string) (V, error)
+}
+
+// typedValues encodes values of TypedStore as is
+type typedValues[V any] struct{}
+
+func (typedValues[V]) typeName(_ V) (string, error) {
+	return "", nil
+}
+
+func (typedValues[V]) decode(decoder Decoder, _ string) (V, error) {
+	var value V
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+// registeredValues encodes values of Store as types registered with Register
+type registeredValues struct{}
+
+func (registeredValues) typeName(value any) (string, error) {
+	registered, ok := registry.lookupValue(value)
+	if !ok {
+		return "", fmt.Errorf("%w: %T", ErrNotRegistered, value)
+	}
+	return registered.name, nil
+}
+
+func (registeredValues) decode(decoder Decoder, typeName string) (any, error) {
+	registered, ok := registry.lookupName(typeName)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNotRegistered, typeName)
+	}
+	return registered.decode(decoder)
+}
+
+// recordCoder encodes and decodes changes of the store as frames
+type recordCoder[K comparable, V any] struct {
+	codec  Codec
+	values valueCoder[V]
+}
+
+// AOF represents append-only log that records all changes of the store
+type AOF[K comparable, V any] struct {
+	config AOFConfig
+	coder  recordCoder[K, V]
+	source journalSource[K, V]
+	detach func()
+
+	lock       sync.Mutex
+	file       *os.File
+	size       int64
+	rewriteAt  int64
+	dirty      bool
+	err        error
+	closed     bool
+	rewriting  bool
+	rewriteBuf bytes.Buffer
+
+	rewriteLock sync.Mutex
+	done        chan struct{}
+	workers     sync.WaitGroup
+	closeOnce   sync.Once
+	closeErr    error
+}
+
+// OpenAOF replays append-only log into the store and records all following changes of the store to it,
+// values are replayed without calling hooks and watchers, a truncated or corrupted tail of the log is discarded.
+// If the store has values that are not in the log, the log is rewritten with all values of the store.
+// Writes of values that can't be encoded are rejected, TrySet and TrySetWithTTL return the error
+func (s *TypedStore[K, V]) OpenAOF(config AOFConfig) (*AOF[K, V], error) {
+	return openAOF[K, V](s, typedValues[V]{}, config)
+}
+
+// OpenAOF replays append-only log into the store and records all following changes of the store to it,
+// values are replayed without calling hooks and watchers, a truncated or corrupted tail of the log is discarded.
+// If the store has values that are not in the log, the log is rewritten with all values of the store.
+// All types of stored values must be registered with Register, writes of values that can't be encoded are rejected,
+// TrySet and TrySetWithTTL return the error
+func (s *Store[K]) OpenAOF(config AOFConfig) (*AOF[K, any], error) {
+	return openAOF[K, any](s, registeredValues{}, config)
+}
+
+func openAOF[K comparable, V any](
+	source journalSource[K, V], values valueCoder[V], config AOFConfig,
+) (*AOF[K, V], error) {
+	if config.Codec == nil {
+		config.Codec = JSONCodec{}
+	}
+
+	a := &AOF[K, V]{
+		config: config,
+		coder:  recordCoder[K, V]{codec: config.Codec, values: values},
+		source: source,
+		done:   make(chan struct{}),
+	}
+
+	//nolint:gomnd // Regular file permissions
+	file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("memkey: open log: %w", err)
+	}
+
+	entries, size, err := a.replay(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	if err = file.Truncate(size); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("memkey: truncate log: %w", err)
+	}
+
+	if _, err = file.Seek(size, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("memkey: seek log: %w", err)
+	}
+
+	a.file = file
+	a.size = size
+	a.rewriteAt = size + config.RewriteSize
+
+	var stored int
+	a.detach, stored = source.attachJournal(entries, a)
+
+	// Values stored before the log was opened are written to it as a baseline
+	if stored > len(entries) {
+		if err = a.Rewrite(); err != nil {
+			_ = a.Close()
+			return nil, err
+		}
+	}
+
+	if config.Fsync == FsyncEverySecond {
+		a.workers.Add(1)
+		go a.syncEverySecond()
+	}
+
+	return a, nil
+}
+
+// replay reads all valid records from log and returns resulting entries and size of valid part of the log
+func (a *AOF[K, V]) replay(r io.Reader) ([]snapshotEntry[K, V], int64, error) {
+	values := make(map[K]snapshotEntry[K, V])
+
+	size, err := readFrames(bufio.NewReader(r), func(payload []byte) error {
+		kind, key, value, deadline, err := a.coder.decodeRecord(payload)
+		if err != nil {
+			return err
+		}
+
+		if kind == EventSet {
+			values[key] = snapshotEntry[K, V]{Key: key, Value: value, Deadline: deadline}
+		} else {
+			delete(values, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	entries := make([]snapshotEntry[K, V], 0, len(values))
+	for _, entry := range values {
+		if entry.Deadline != nil && !entry.Deadline.After(now) {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, size, nil
+}
+
+// record appends change to the log, it's called while the store lock is held
+func (a *AOF[K, V]) record(kind EventKind, key K, value V, deadline time.Time) {
+	var deadlinePtr *time.Time
+	if !deadline.IsZero() {
+		deadlinePtr = &deadline
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.closed || a.err != nil {
+		return
+	}
+
+	frame, err := a.coder.encodeRecord(kind, key, value, deadlinePtr)
+	if err != nil {
+		if a.config.OnError != nil {
+			a.config.OnError(err)
+		}
+
+		frame, err = a.coder.encodeRecord(EventDelete, key, value, nil)
+		if err != nil {
+			return
+		}
+	}
+
+	if a.rewriting {
+		a.rewriteBuf.Write(frame)
+	}
+
+	if err = a.write(frame); err != nil {
+		a.err = err
+		if a.config.OnError != nil {
+			a.config.OnError(err)
+		}
+		return
+	}
+
+	if a.config.RewriteSize > 0 && a.size >= a.rewriteAt && !a.rewriting {
+		a.rewriteAt = a.size + a.config.RewriteSize
+
+		a.workers.Add(1)
+		go func() {
+			defer a.workers.Done()
+			_ = a.Rewrite()
+		}()
+	}
+}
+
+// check returns error if value can't be encoded or if writing the log has failed, it's called while the store lock
+// is held
+func (a *AOF[K, V]) check(key K, value V) error {
+	a.lock.Lock()
+	err := a.err
+	a.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+	return a.coder.checkValue(key, value)
+}
+
+// write writes frame to log file according to fsync policy, must be called while AOF lock is held
+func (a *AOF[K, V]) write(frame []byte) error {
+	n, err := a.file.Write(frame)
+	a.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("memkey: write log: %w", err)
+	}
+
+	switch a.config.Fsync {
+	case FsyncAlways:
+		if err = a.file.Sync(); err != nil {
+			return fmt.Errorf("memkey: sync log: %w", err)
+		}
+	case FsyncEverySecond:
+		a.dirty = true
+	default:
+	}
+
+	return nil
+}
+
+// syncEverySecond syncs log to disk once per second until log is closed
+func (a *AOF[K, V]) syncEverySecond() {
+	defer a.workers.Done()
+
+	ticker := time.NewTicker(aofSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			_ = a.Sync()
+		}
+	}
+}
+
+// Sync syncs log to disk
+func (a *AOF[K, V]) Sync() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.closed {
+		return ErrClosed
+	}
+
+	if !a.dirty {
+		return nil
+	}
+
+	if err := a.file.Sync(); err != nil {
+		return fmt.Errorf("memkey: sync log: %w", err)
+	}
+	a.dirty = false
+
+	return nil
+}
+
+// Err returns the first error that happened while writing the log, after an error no changes are recorded and
+// writes made by TrySet and TrySetWithTTL are rejected with it. Changes that can't be encoded are not errors of the
+// log, see AOFConfig.OnError
+func (a *AOF[K, V]) Err() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.err
+}
+
+// Size returns current size of log in bytes
+func (a *AOF[K, V]) Size() int64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.size
+}
+
+// Rewrite compacts the log by writing current state of the store into a new log, writes to the store are not
+// blocked while the new log is written
+func (a *AOF[K, V]) Rewrite() error {
+	a.rewriteLock.Lock()
+	defer a.rewriteLock.Unlock()
+
+	var startErr error
+	entries := a.source.snapshotEntries(func() {
+		a.lock.Lock()
+		defer a.lock.Unlock()
+
+		if a.closed {
+			startErr = ErrClosed
+			return
+		}
+
+		a.rewriting = true
+		a.rewriteBuf.Reset()
+	})
+	if startErr != nil {
+		return startErr
+	}
+
+	tmpPath := a.config.Path + ".rewrite"
+	file, size, err := a.writeRewrite(tmpPath, entries)
+	if err != nil {
+		a.stopRewrite()
+		return err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.rewriting = false
+	buffered := a.rewriteBuf.Bytes()
+	a.rewriteBuf.Reset()
+
+	if a.closed {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return ErrClosed
+	}
+
+	n, err := file.Write(buffered)
+	size += int64(n)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, a.config.Path)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("memkey: rewrite log: %w", err)
+	}
+
+	_ = a.file.Close()
+	a.file = file
+	a.size = size
+	a.rewriteAt = size + a.config.RewriteSize
+	a.dirty = false
+
+	// Rename is durable only after directory of the log is synced
+	if err = syncDir(filepath.Dir(a.config.Path)); err != nil {
+		return fmt.Errorf("memkey: rewrite log: %w", err)
+	}
+
+	return nil
+}
+
+// syncDir syncs directory to disk, so changes of its entries (for example, renames) are durable
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	err = dir.Sync()
+	if closeErr := dir.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeRewrite writes entries into a new log file and returns it opened for appending
+func (a *AOF[K, V]) writeRewrite(path string, entries []snapshotEntry[K, V]) (*os.File, int64, error) {
+	//nolint:gomnd // Regular file permissions
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("memkey: create rewrite log: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	var size int64
+	for _, entry := range entries {
+		var frame []byte
+		frame, err = a.coder.encodeRecord(EventSet, entry.Key, entry.Value, entry.Deadline)
+		if err != nil {
+			break
+		}
+
+		var n int
+		n, err = writer.Write(frame)
+		size += int64(n)
+		if err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return nil, 0, fmt.Errorf("memkey: write rewrite log: %w", err)
+	}
+
+	return file, size, nil
+}
+
+// stopRewrite discards changes buffered during rewrite
+func (a *AOF[K, V]) stopRewrite() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.rewriting = false
+	a.rewriteBuf.Reset()
+}
+
+// Close stops recording changes of the store, waits for background rewrite, syncs and closes the log,
+// it is safe to call Close multiple times
+func (a *AOF[K, V]) Close() error {
+	a.closeOnce.Do(func() {
+		a.detach()
+
+		a.lock.Lock()
+		a.closed = true
+		a.lock.Unlock()
+
+		close(a.done)
+		a.workers.Wait()
+
+		// Wait for rewrite started by the user
+		a.rewriteLock.Lock()
+		defer a.rewriteLock.Unlock()
+
+		a.lock.Lock()
+		defer a.lock.Unlock()
+
+		err := a.file.Sync()
+		if closeErr := a.file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			a.closeErr = fmt.Errorf("memkey: close log: %w", err)
+		}
+	})
+
+	return a.closeErr
+}
+
+// encodeRecord encodes record and its value into a frame
+func (c recordCoder[K, V]) encodeRecord(kind EventKind, key K, value V, deadline *time.Time) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, aofFrameHeaderSize))
+	encoder := c.codec.NewEncoder(buf)
+
+	rec := aofRecord[K]{
+		Kind: kind,
+		Key:  key,
+	}
+
+	if kind == EventSet {
+		typeName, err := c.values.typeName(value)
+		if err != nil {
+			return nil, err
+		}
+
+		rec.Type = typeName
+		rec.Deadline = deadline
+	}
+
+	if err := encoder.Encode(&rec); err != nil {
+		return nil, fmt.Errorf("memkey: encode log record: %w", err)
+	}
+
+	if kind == EventSet {
+		if err := encoder.Encode(value); err != nil {
+			return nil, fmt.Errorf("memkey: encode log value of %v: %w", key, err)
+		}
+	}
+
+	return sealFrame(buf.Bytes()), nil
+}
+
+// checkValue returns error if value can't be encoded
+func (c recordCoder[K, V]) checkValue(key K, value V) error {
+	if _, err := c.values.typeName(value); err != nil {
+		return err
+	}
+
+	if err := c.codec.NewEncoder(io.Discard).Encode(value); err != nil {
+		return fmt.Errorf("memkey: encode log value of %v: %w", key, err)
+	}
+
+	return nil
+}
+
+// encodeFrame encodes message into a frame
+func encodeFrame(codec Codec, message any) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, aofFrameHeaderSize))
+	if err := codec.NewEncoder(buf).Encode(message); err != nil {
+		return nil, err
+	}
+	return sealFrame(buf.Bytes()), nil
+}
+
+// sealFrame fills header of frame with length and checksum of the payload that follows it
+func sealFrame(frame []byte) []byte {
+	payload := frame[aofFrameHeaderSize:]
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+	return frame
+}
+
+// decodeRecord decodes record and its value from frame payload
+func (c recordCoder[K, V]) decodeRecord(payload []byte) (EventKind, K, V, *time.Time, error) {
+	decoder := c.codec.NewDecoder(bytes.NewReader(payload))
+
+	var rec aofRecord[K]
+	if err := decoder.Decode(&rec); err != nil {
+		return 0, rec.Key, zero[V](), nil, fmt.Errorf("memkey: decode log record: %w", err)
+	}
+
+	if rec.Kind != EventSet {
+		return rec.Kind, rec.Key, zero[V](), nil, nil
+	}
+
+	value, err := c.values.decode(decoder, rec.Type)
+	if err != nil {
+		return 0, rec.Key, zero[V](), nil, fmt.Errorf("memkey: decode log value of %v: %w", rec.Key, err)
+	}
+
+	return rec.Kind, rec.Key, value, rec.Deadline, nil
+}
+
+// errFrameCorrupted returned when frame has invalid length or checksum
+var errFrameCorrupted = errors.New("memkey: corrupted frame")
+
+// readFrames reads frames and calls f with payload of each frame, reading stops on the first incomplete or
+// corrupted frame, returns size of valid frames
+func readFrames(r io.Reader, f func(payload []byte) error) (int64, error) {
+	var size int64
+
+	for {
+		payload, err := readFrame(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, errFrameCorrupted) {
+				return size, nil
+			}
+			return 0, fmt.Errorf("memkey: read log: %w", err)
+		}
+
+		if err = f(payload); err != nil {
+			return 0, err
+		}
+
+		size += int64(aofFrameHeaderSize + len(payload))
+	}
+}
+
+// readFrame reads payload of a single frame and verifies its checksum
+func readFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, aofFrameHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > aofMaxFrameSize {
+		return nil, errFrameCorrupted
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errFrameCorrupted
+	}
+
+	return payload, nil
+}
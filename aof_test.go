@@ -0,0 +1,269 @@
+package memkey
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedStore_OpenAOF(t *testing.T) {
+	codecs := map[string]Codec{
+		"gob":  GobCodec{},
+		"json": JSONCodec{},
+	}
+
+	for name, codec := range codecs {
+		codec := codec
+		t.Run(name, func(t *testing.T) {
+			config := AOFConfig{
+				Path:  filepath.Join(t.TempDir(), "store.aof"),
+				Codec: codec,
+				Fsync: FsyncAlways,
+			}
+
+			s := &TypedStore[string, int]{}
+			aof, err := s.OpenAOF(config)
+			require.NoError(t, err)
+
+			s.Set("a", 1)
+			s.Set("b", 2)
+			s.SetWithTTL("c", 3, time.Hour)
+			s.SetWithTTL("d", 4, time.Millisecond)
+			s.Delete("b")
+			s.Set("a", 5)
+
+			require.NoError(t, aof.Err())
+			require.NoError(t, aof.Close())
+			require.NoError(t, aof.Close())
+
+			s.Set("e", 6)
+
+			time.Sleep(time.Millisecond * 2)
+
+			loaded := &TypedStore[string, int]{}
+			aof, err = loaded.OpenAOF(config)
+			require.NoError(t, err)
+			defer func() { _ = aof.Close() }()
+
+			assert.ElementsMatch(t, []Entry[string, int]{{"a", 5}, {"c", 3}}, loaded.Entries())
+			assert.WithinDuration(t, s.ttl["c"], loaded.ttl["c"], time.Millisecond)
+		})
+	}
+}
+
+func TestTypedStore_OpenAOF_truncated(t *testing.T) {
+	config := AOFConfig{
+		Path:  filepath.Join(t.TempDir(), "store.aof"),
+		Fsync: FsyncNever,
+	}
+
+	s := &TypedStore[string, int]{}
+	aof, err := s.OpenAOF(config)
+	require.NoError(t, err)
+
+	s.Set("a", 1)
+	s.Set("b", 2)
+	require.NoError(t, aof.Close())
+
+	info, err := os.Stat(config.Path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(config.Path, info.Size()-3))
+
+	loaded := &TypedStore[string, int]{}
+	aof, err = loaded.OpenAOF(config)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Entry[string, int]{{"a", 1}}, loaded.Entries())
+
+	loaded.Set("c", 3)
+	require.NoError(t, aof.Close())
+
+	loaded = &TypedStore[string, int]{}
+	aof, err = loaded.OpenAOF(config)
+	require.NoError(t, err)
+	defer func() { _ = aof.Close() }()
+
+	assert.ElementsMatch(t, []Entry[string, int]{{"a", 1}, {"c", 3}}, loaded.Entries())
+}
+
+func TestAOF_Rewrite(t *testing.T) {
+	config := AOFConfig{
+		Path:  filepath.Join(t.TempDir(), "store.aof"),
+		Fsync: FsyncEverySecond,
+	}
+
+	s := &TypedStore[int, int]{}
+	aof, err := s.OpenAOF(config)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		s.Set(i%10, i)
+	}
+
+	sizeBefore := aof.Size()
+	require.NoError(t, aof.Rewrite())
+	assert.Less(t, aof.Size(), sizeBefore)
+
+	s.Set(10, 10)
+	require.NoError(t, aof.Sync())
+	require.NoError(t, aof.Close())
+	assert.ErrorIs(t, aof.Rewrite(), ErrClosed)
+
+	loaded := &TypedStore[int, int]{}
+	aof, err = loaded.OpenAOF(config)
+	require.NoError(t, err)
+	defer func() { _ = aof.Close() }()
+
+	assert.ElementsMatch(t, s.Entries(), loaded.Entries())
+}
+
+func TestAOF_RewriteSize(t *testing.T) {
+	config := AOFConfig{
+		Path:        filepath.Join(t.TempDir(), "store.aof"),
+		Fsync:       FsyncNever,
+		RewriteSize: 1024,
+	}
+
+	s := &TypedStore[int, int]{}
+	aof, err := s.OpenAOF(config)
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		s.Set(i%10, i)
+	}
+
+	assert.Eventually(t, func() bool {
+		return aof.Size() < 1024*2
+	}, time.Second, time.Millisecond)
+	require.NoError(t, aof.Close())
+
+	loaded := &TypedStore[int, int]{}
+	aof, err = loaded.OpenAOF(config)
+	require.NoError(t, err)
+	defer func() { _ = aof.Close() }()
+
+	assert.ElementsMatch(t, s.Entries(), loaded.Entries())
+}
+
+func TestStore_OpenAOF(t *testing.T) {
+	Register[testRegistered]("test.registered")
+
+	config := AOFConfig{
+		Path:  filepath.Join(t.TempDir(), "store.aof"),
+		Codec: GobCodec{},
+	}
+
+	s := &Store[string]{}
+	aof, err := s.OpenAOF(config)
+	require.NoError(t, err)
+
+	Set(s, "a", 1)
+	Set(s, "b", testRegistered{Name: "b"})
+	SetWithTTL(s, "c", "c", time.Hour)
+	Delete[int](s, "a")
+
+	assert.ErrorIs(t, s.TrySet("d", testNotRegistered{}), ErrNotRegistered)
+	assert.False(t, s.Has("d"))
+
+	Set(s, "e", 2)
+	require.NoError(t, aof.Err())
+	require.NoError(t, aof.Close())
+
+	loaded := &Store[string]{}
+	aof, err = loaded.OpenAOF(config)
+	require.NoError(t, err)
+	defer func() { _ = aof.Close() }()
+
+	assert.ElementsMatch(t, []Entry[string, any]{{"b", testRegistered{Name: "b"}}, {"c", "c"}, {"e", 2}},
+		loaded.Entries())
+}
+
+func TestAOF_baseline(t *testing.T) {
+	config := AOFConfig{
+		Path: filepath.Join(t.TempDir(), "store.aof"),
+	}
+
+	s := &TypedStore[string, int]{}
+	s.Set("a", 1)
+
+	aof, err := s.OpenAOF(config)
+	require.NoError(t, err)
+
+	s.Set("b", 2)
+	require.NoError(t, aof.Close())
+
+	loaded := &TypedStore[string, int]{}
+	aof, err = loaded.OpenAOF(config)
+	require.NoError(t, err)
+	defer func() { _ = aof.Close() }()
+
+	assert.ElementsMatch(t, []Entry[string, int]{{"a", 1}, {"b", 2}}, loaded.Entries())
+}
+
+func TestAOF_WriteError(t *testing.T) {
+	var errs []error
+	config := AOFConfig{
+		Path: filepath.Join(t.TempDir(), "store.aof"),
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	}
+
+	s := &Store[string]{}
+	aof, err := s.OpenAOF(config)
+	require.NoError(t, err)
+
+	// Write fails after the log file is closed underneath
+	require.NoError(t, aof.file.Close())
+	require.NoError(t, s.TrySet("a", 1))
+
+	require.Len(t, errs, 1)
+	require.Error(t, aof.Err())
+	assert.ErrorIs(t, errs[0], aof.Err())
+
+	// Writes that can't be recorded are rejected
+	assert.Equal(t, aof.Err(), s.TrySet("b", 2))
+	assert.False(t, s.Has("b"))
+	assert.Len(t, errs, 1)
+}
+
+func TestAOF_OnError(t *testing.T) {
+	var errs []error
+	config := AOFConfig{
+		Path: filepath.Join(t.TempDir(), "store.aof"),
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	}
+
+	s := NewOrderedStore[int, any]()
+	aof, err := s.OpenAOF(config)
+	require.NoError(t, err)
+
+	s.Set(1, "a")
+	s.Set(1, make(chan int))
+	require.Error(t, s.TrySet(1, make(chan int)))
+	require.Error(t, s.TrySetWithTTL(1, make(chan int), time.Hour))
+	s.SetMany(map[int]any{2: "b", 3: make(chan int)})
+	require.Empty(t, errs)
+
+	value, _ := s.Get(1)
+	assert.Equal(t, "a", value)
+	assert.False(t, s.Has(3))
+
+	aof.record(EventSet, 1, make(chan int), time.Time{})
+	require.Len(t, errs, 1)
+	require.NoError(t, aof.Err())
+	require.NoError(t, aof.Close())
+
+	loaded := NewOrderedStore[int, any]()
+	aof, err = loaded.OpenAOF(config)
+	require.NoError(t, err)
+	defer func() { _ = aof.Close() }()
+
+	assert.Equal(t, []Entry[int, any]{{2, "b"}}, loaded.Entries())
+}
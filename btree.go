@@ -0,0 +1,492 @@
+package memkey
+
+import "sort"
+
+const (
+	// btreeDegree is a minimum degree of B-tree, every node except root has from btreeDegree-1 to 2*btreeDegree-1
+	// items
+	btreeDegree   = 32
+	btreeMaxItems = 2*btreeDegree - 1
+	btreeMinItems = btreeDegree - 1
+)
+
+// btree represents B-tree that keeps entries sorted by key, every node tracks size of its subtree, so rank and
+// select run in logarithmic time. It's not safe for concurrent use
+type btree[K comparable, V any] struct {
+	root    *btreeNode[K, V]
+	compare func(a, b K) int
+}
+
+type btreeNode[K comparable, V any] struct {
+	items    []Entry[K, V]
+	children []*btreeNode[K, V]
+	// size is a number of items in the subtree
+	size int
+}
+
+// btreeRemove defines which item is removed from subtree
+type btreeRemove uint8
+
+const (
+	btreeRemoveKey btreeRemove = iota
+	btreeRemoveMax
+)
+
+func (t *btree[K, V]) len() int {
+	if t.root == nil {
+		return 0
+	}
+	return t.root.size
+}
+
+// get returns value stored with key
+func (t *btree[K, V]) get(key K) (V, bool) {
+	n := t.root
+	for n != nil {
+		i, found := n.find(key, t.compare)
+		if found {
+			return n.items[i].Value, true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return zero[V](), false
+}
+
+// set stores value with key, returns replaced value and true if key was already stored
+func (t *btree[K, V]) set(key K, value V) (V, bool) {
+	item := Entry[K, V]{Key: key, Value: value}
+
+	if t.root == nil {
+		t.root = &btreeNode[K, V]{items: []Entry[K, V]{item}, size: 1}
+		return zero[V](), false
+	}
+
+	if len(t.root.items) >= btreeMaxItems {
+		oldRoot := t.root
+		middle, right := oldRoot.split(btreeMaxItems / 2)
+		t.root = &btreeNode[K, V]{
+			items:    []Entry[K, V]{middle},
+			children: []*btreeNode[K, V]{oldRoot, right},
+			size:     oldRoot.size + 1 + right.size,
+		}
+	}
+
+	return t.root.insert(item, t.compare)
+}
+
+// delete removes key, returns removed value and true if key was stored
+func (t *btree[K, V]) delete(key K) (V, bool) {
+	if t.root == nil {
+		return zero[V](), false
+	}
+
+	item, ok := t.root.remove(key, btreeRemoveKey, t.compare)
+
+	if len(t.root.items) == 0 {
+		if t.root.leaf() {
+			t.root = nil
+		} else {
+			t.root = t.root.children[0]
+		}
+	}
+
+	return item.Value, ok
+}
+
+// min returns entry with the smallest key
+func (t *btree[K, V]) min() (Entry[K, V], bool) {
+	n := t.root
+	if n == nil {
+		return Entry[K, V]{}, false
+	}
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return n.items[0], true
+}
+
+// max returns entry with the largest key
+func (t *btree[K, V]) max() (Entry[K, V], bool) {
+	n := t.root
+	if n == nil {
+		return Entry[K, V]{}, false
+	}
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	return n.items[len(n.items)-1], true
+}
+
+// floor returns entry with the largest key less than or equal to key
+func (t *btree[K, V]) floor(key K) (Entry[K, V], bool) {
+	var (
+		result Entry[K, V]
+		ok     bool
+	)
+
+	for n := t.root; n != nil; {
+		i, found := n.find(key, t.compare)
+		if found {
+			return n.items[i], true
+		}
+		if i > 0 {
+			result, ok = n.items[i-1], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+
+	return result, ok
+}
+
+// ceiling returns entry with the smallest key greater than or equal to key
+func (t *btree[K, V]) ceiling(key K) (Entry[K, V], bool) {
+	var (
+		result Entry[K, V]
+		ok     bool
+	)
+
+	for n := t.root; n != nil; {
+		i, found := n.find(key, t.compare)
+		if found {
+			return n.items[i], true
+		}
+		if i < len(n.items) {
+			result, ok = n.items[i], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+
+	return result, ok
+}
+
+// rank returns number of keys less than key
+func (t *btree[K, V]) rank(key K) int {
+	rank := 0
+
+	for n := t.root; n != nil; {
+		i, found := n.find(key, t.compare)
+		rank += i
+		if n.leaf() {
+			break
+		}
+
+		for _, child := range n.children[:i] {
+			rank += child.size
+		}
+
+		if found {
+			rank += n.children[i].size
+			break
+		}
+		n = n.children[i]
+	}
+
+	return rank
+}
+
+// selectAt returns entry with specified rank, i.e. index in sorted order
+func (t *btree[K, V]) selectAt(index int) (Entry[K, V], bool) {
+	if index < 0 || index >= t.len() {
+		return Entry[K, V]{}, false
+	}
+
+	n := t.root
+	for {
+		if n.leaf() {
+			return n.items[index], true
+		}
+
+		i := 0
+		for ; i < len(n.items); i++ {
+			size := n.children[i].size
+			if index < size {
+				break
+			}
+			if index == size {
+				return n.items[i], true
+			}
+			index -= size + 1
+		}
+		n = n.children[i]
+	}
+}
+
+// ascend calls f for entries with keys in range [from, to) in ascending order until f returns true, nil bound
+// means no bound, returns true if stopped
+func (t *btree[K, V]) ascend(from, to *K, f func(key K, value V) (stop bool)) bool {
+	if t.root == nil {
+		return false
+	}
+	return t.root.ascend(from, to, f, t.compare)
+}
+
+// descend calls f for entries with keys in range (to, from] in descending order until f returns true, nil bound
+// means no bound, returns true if stopped
+func (t *btree[K, V]) descend(from, to *K, f func(key K, value V) (stop bool)) bool {
+	if t.root == nil {
+		return false
+	}
+	return t.root.descend(from, to, f, t.compare)
+}
+
+func (n *btreeNode[K, V]) leaf() bool {
+	return len(n.children) == 0
+}
+
+// find returns index of the first item with key greater than or equal to key and true if it's equal
+func (n *btreeNode[K, V]) find(key K, compare func(a, b K) int) (int, bool) {
+	i := sort.Search(len(n.items), func(i int) bool {
+		return compare(n.items[i].Key, key) >= 0
+	})
+	return i, i < len(n.items) && compare(n.items[i].Key, key) == 0
+}
+
+// split splits node at item i, returns that item and a new node with all items and children after it
+func (n *btreeNode[K, V]) split(i int) (Entry[K, V], *btreeNode[K, V]) {
+	item := n.items[i]
+
+	right := &btreeNode[K, V]{items: append([]Entry[K, V](nil), n.items[i+1:]...)}
+	n.items = n.items[:i:i]
+	right.size = len(right.items)
+
+	if !n.leaf() {
+		right.children = append([]*btreeNode[K, V](nil), n.children[i+1:]...)
+		n.children = n.children[: i+1 : i+1]
+		for _, child := range right.children {
+			right.size += child.size
+		}
+	}
+
+	n.size -= right.size + 1
+	return item, right
+}
+
+// insert inserts item into subtree of a node that is not full
+func (n *btreeNode[K, V]) insert(item Entry[K, V], compare func(a, b K) int) (V, bool) {
+	i, found := n.find(item.Key, compare)
+	if found {
+		old := n.items[i].Value
+		n.items[i] = item
+		return old, true
+	}
+
+	if n.leaf() {
+		n.items = append(n.items, Entry[K, V]{})
+		copy(n.items[i+1:], n.items[i:])
+		n.items[i] = item
+		n.size++
+		return zero[V](), false
+	}
+
+	if len(n.children[i].items) >= btreeMaxItems {
+		middle, right := n.children[i].split(btreeMaxItems / 2)
+
+		n.items = append(n.items, Entry[K, V]{})
+		copy(n.items[i+1:], n.items[i:])
+		n.items[i] = middle
+
+		n.children = append(n.children, nil)
+		copy(n.children[i+2:], n.children[i+1:])
+		n.children[i+1] = right
+
+		switch c := compare(item.Key, middle.Key); {
+		case c > 0:
+			i++
+		case c == 0:
+			old := n.items[i].Value
+			n.items[i] = item
+			return old, true
+		}
+	}
+
+	old, replaced := n.children[i].insert(item, compare)
+	if !replaced {
+		n.size++
+	}
+	return old, replaced
+}
+
+// remove removes item from subtree, every visited child is grown to have more than minimum number of items
+// before descending into it, so removal never leaves a node underflowed
+func (n *btreeNode[K, V]) remove(key K, kind btreeRemove, compare func(a, b K) int) (Entry[K, V], bool) {
+	var (
+		i     int
+		found bool
+	)
+
+	switch kind {
+	case btreeRemoveMax:
+		if n.leaf() {
+			item := n.items[len(n.items)-1]
+			n.items = n.items[:len(n.items)-1]
+			n.size--
+			return item, true
+		}
+		i = len(n.items)
+	default:
+		i, found = n.find(key, compare)
+		if n.leaf() {
+			if !found {
+				return Entry[K, V]{}, false
+			}
+
+			item := n.items[i]
+			n.items = append(n.items[:i], n.items[i+1:]...)
+			n.size--
+			return item, true
+		}
+	}
+
+	if len(n.children[i].items) <= btreeMinItems {
+		n.growChild(i)
+		return n.remove(key, kind, compare)
+	}
+
+	if found {
+		// Item is replaced by the largest item of the left subtree
+		item := n.items[i]
+		n.items[i], _ = n.children[i].remove(key, btreeRemoveMax, compare)
+		n.size--
+		return item, true
+	}
+
+	item, ok := n.children[i].remove(key, kind, compare)
+	if ok {
+		n.size--
+	}
+	return item, ok
+}
+
+// growChild adds item to child i by stealing it from sibling or by merging child with sibling
+func (n *btreeNode[K, V]) growChild(i int) {
+	switch {
+	case i > 0 && len(n.children[i-1].items) > btreeMinItems:
+		child, left := n.children[i], n.children[i-1]
+
+		stolen := left.items[len(left.items)-1]
+		left.items = left.items[:len(left.items)-1]
+
+		child.items = append(child.items, Entry[K, V]{})
+		copy(child.items[1:], child.items)
+		child.items[0] = n.items[i-1]
+		n.items[i-1] = stolen
+
+		moved := 1
+		if !left.leaf() {
+			grandchild := left.children[len(left.children)-1]
+			left.children = left.children[:len(left.children)-1]
+
+			child.children = append(child.children, nil)
+			copy(child.children[1:], child.children)
+			child.children[0] = grandchild
+			moved += grandchild.size
+		}
+
+		left.size -= moved
+		child.size += moved
+	case i < len(n.items) && len(n.children[i+1].items) > btreeMinItems:
+		child, right := n.children[i], n.children[i+1]
+
+		stolen := right.items[0]
+		right.items = append(right.items[:0], right.items[1:]...)
+
+		child.items = append(child.items, n.items[i])
+		n.items[i] = stolen
+
+		moved := 1
+		if !right.leaf() {
+			grandchild := right.children[0]
+			right.children = append(right.children[:0], right.children[1:]...)
+
+			child.children = append(child.children, grandchild)
+			moved += grandchild.size
+		}
+
+		right.size -= moved
+		child.size += moved
+	default:
+		if i >= len(n.items) {
+			i--
+		}
+
+		child, right := n.children[i], n.children[i+1]
+
+		child.items = append(child.items, n.items[i])
+		child.items = append(child.items, right.items...)
+		child.children = append(child.children, right.children...)
+		child.size += 1 + right.size
+
+		n.items = append(n.items[:i], n.items[i+1:]...)
+		n.children = append(n.children[:i+1], n.children[i+2:]...)
+	}
+}
+
+func (n *btreeNode[K, V]) ascend(from, to *K, f func(key K, value V) (stop bool), compare func(a, b K) int) bool {
+	i := 0
+	if from != nil {
+		i, _ = n.find(*from, compare)
+	}
+
+	for ; i < len(n.items); i++ {
+		if !n.leaf() && n.children[i].ascend(from, to, f, compare) {
+			return true
+		}
+
+		item := n.items[i]
+		if to != nil && compare(item.Key, *to) >= 0 {
+			return true
+		}
+
+		if f(item.Key, item.Value) {
+			return true
+		}
+	}
+
+	if !n.leaf() {
+		return n.children[len(n.children)-1].ascend(from, to, f, compare)
+	}
+
+	return false
+}
+
+func (n *btreeNode[K, V]) descend(from, to *K, f func(key K, value V) (stop bool), compare func(a, b K) int) bool {
+	i := len(n.items) - 1
+	if from != nil {
+		index, found := n.find(*from, compare)
+		if found {
+			i = index
+		} else {
+			i = index - 1
+		}
+	}
+
+	if !n.leaf() && n.children[i+1].descend(from, to, f, compare) {
+		return true
+	}
+
+	for ; i >= 0; i-- {
+		item := n.items[i]
+		if to != nil && compare(item.Key, *to) <= 0 {
+			return true
+		}
+
+		if f(item.Key, item.Value) {
+			return true
+		}
+
+		if !n.leaf() && n.children[i].descend(from, to, f, compare) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,171 @@
+package memkey
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkBTree verifies sizes, order and fill of all nodes of the tree
+func checkBTree[K comparable, V any](t *testing.T, tree *btree[K, V]) {
+	t.Helper()
+
+	var check func(n *btreeNode[K, V], root bool) int
+	check = func(n *btreeNode[K, V], root bool) int {
+		require.LessOrEqual(t, len(n.items), btreeMaxItems)
+		if !root {
+			require.GreaterOrEqual(t, len(n.items), btreeMinItems)
+		}
+
+		size := len(n.items)
+		if !n.leaf() {
+			require.Len(t, n.children, len(n.items)+1)
+			for _, child := range n.children {
+				size += check(child, false)
+			}
+		}
+
+		require.Equal(t, size, n.size)
+		return size
+	}
+
+	if tree.root != nil {
+		check(tree.root, true)
+	}
+
+	var prev *K
+	tree.ascend(nil, nil, func(key K, _ V) bool {
+		if prev != nil {
+			require.Negative(t, tree.compare(*prev, key))
+		}
+		prev = &key
+		return false
+	})
+}
+
+func TestBTree(t *testing.T) {
+	tree := &btree[int, int]{compare: compareOrdered[int]}
+	expected := make(map[int]int)
+
+	//nolint:gosec // Deterministic random is fine for tests
+	random := rand.New(rand.NewSource(42))
+
+	for i := 0; i < 20000; i++ {
+		key := random.Intn(5000)
+
+		if random.Intn(3) == 0 {
+			value, ok := tree.delete(key)
+			expectedValue, expectedOK := expected[key]
+			require.Equal(t, expectedOK, ok)
+			require.Equal(t, expectedValue, value)
+			delete(expected, key)
+		} else {
+			old, replaced := tree.set(key, i)
+			expectedOld, expectedReplaced := expected[key]
+			require.Equal(t, expectedReplaced, replaced)
+			require.Equal(t, expectedOld, old)
+			expected[key] = i
+		}
+
+		if i%1000 == 0 {
+			checkBTree(t, tree)
+		}
+	}
+	checkBTree(t, tree)
+
+	keys := make([]int, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	require.Equal(t, len(keys), tree.len())
+	for i, key := range keys {
+		value, ok := tree.get(key)
+		require.True(t, ok)
+		require.Equal(t, expected[key], value)
+
+		require.Equal(t, i, tree.rank(key))
+		entry, ok := tree.selectAt(i)
+		require.True(t, ok)
+		require.Equal(t, key, entry.Key)
+	}
+
+	for key := -1; key <= 5001; key++ {
+		i := sort.SearchInts(keys, key)
+
+		ceiling, ok := tree.ceiling(key)
+		require.Equal(t, i < len(keys), ok)
+		if ok {
+			require.Equal(t, keys[i], ceiling.Key)
+		}
+
+		floorIndex := i - 1
+		if i < len(keys) && keys[i] == key {
+			floorIndex = i
+		}
+		floor, ok := tree.floor(key)
+		require.Equal(t, floorIndex >= 0, ok)
+		if ok {
+			require.Equal(t, keys[floorIndex], floor.Key)
+		}
+
+		require.Equal(t, i, tree.rank(key))
+	}
+
+	for _, key := range keys {
+		_, ok := tree.delete(key)
+		require.True(t, ok)
+	}
+	checkBTree(t, tree)
+	assert.Nil(t, tree.root)
+	assert.Equal(t, 0, tree.len())
+}
+
+func TestBTree_Iterate(t *testing.T) {
+	tree := &btree[int, int]{compare: compareOrdered[int]}
+	for i := 0; i < 1000; i += 2 {
+		tree.set(i, i)
+	}
+
+	collect := func(iterate func(f func(key, _ int) bool) bool, limit int) []int {
+		var keys []int
+		iterate(func(key, _ int) bool {
+			keys = append(keys, key)
+			return len(keys) == limit
+		})
+		return keys
+	}
+
+	from, to := 101, 110
+	assert.Equal(t, []int{102, 104, 106, 108}, collect(func(f func(key, _ int) bool) bool {
+		return tree.ascend(&from, &to, f)
+	}, -1))
+	assert.Equal(t, []int{110, 108, 106, 104, 102}, collect(func(f func(key, _ int) bool) bool {
+		return tree.descend(&to, &from, f)
+	}, -1))
+	assert.Equal(t, []int{0, 2, 4}, collect(func(f func(key, _ int) bool) bool {
+		return tree.ascend(nil, nil, f)
+	}, 3))
+	assert.Equal(t, []int{998, 996}, collect(func(f func(key, _ int) bool) bool {
+		return tree.descend(nil, nil, f)
+	}, 2))
+	assert.Len(t, collect(func(f func(key, _ int) bool) bool {
+		return tree.descend(nil, nil, f)
+	}, -1), 500)
+
+	minEntry, ok := tree.min()
+	assert.True(t, ok)
+	assert.Equal(t, 0, minEntry.Key)
+	maxEntry, ok := tree.max()
+	assert.True(t, ok)
+	assert.Equal(t, 998, maxEntry.Key)
+
+	_, ok = tree.selectAt(500)
+	assert.False(t, ok)
+	_, ok = (&btree[int, int]{compare: compareOrdered[int]}).min()
+	assert.False(t, ok)
+}
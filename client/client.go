@@ -0,0 +1,457 @@
+/*
+Package client provides client of memkey server that shares TypedStore of byte slices between processes.
+
+Client keeps a pool of connections, each connection serves many concurrent requests without waiting for responses
+(pipelining). Broken connections are discarded and new ones are dialed on the next request.
+*/
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mymmrac/memkey"
+	"github.com/mymmrac/memkey/internal/wire"
+)
+
+const (
+	defaultPoolSize = 4
+	defaultTimeout  = time.Second * 5
+)
+
+// Config represents configuration of client
+type Config struct {
+	// Addr is a TCP address of server
+	Addr string
+
+	// PoolSize is a number of connections, 4 is used by default
+	PoolSize int
+
+	// Timeout of methods without context, 5 seconds is used by default
+	Timeout time.Duration
+
+	// Dial used to connect to server, net.Dialer is used by default
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+var _ memkey.KV[string, []byte] = (*Client)(nil)
+
+// Client is a client of memkey server, methods without context mirror methods of TypedStore and implement memkey.KV,
+// errors of them are reported by Err. Client is safe for concurrent use
+type Client struct {
+	config Config
+	slots  []slot
+	next   atomic.Uint32
+
+	lock   sync.Mutex
+	err    error
+	closed bool
+}
+
+// slot holds single connection of the pool
+type slot struct {
+	lock sync.Mutex
+	conn *conn
+}
+
+// New creates client, connections are dialed lazily
+func New(config Config) *Client {
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultPoolSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.Dial == nil {
+		dialer := &net.Dialer{}
+		config.Dial = dialer.DialContext
+	}
+
+	return &Client{
+		config: config,
+		slots:  make([]slot, config.PoolSize),
+	}
+}
+
+// Get returns a value from the server, if not found or request failed returns false
+func (c *Client) Get(key string) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	value, ok, err := c.GetContext(ctx, key)
+	c.report(err)
+	return value, ok
+}
+
+// GetContext returns a value from the server, if not found returns false
+func (c *Client) GetContext(ctx context.Context, key string) ([]byte, bool, error) {
+	resp, err := c.do(ctx, wire.Request{Op: wire.OpGet, Key: key})
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.Status != wire.StatusOK {
+		return nil, false, nil
+	}
+	return resp.Value, true, nil
+}
+
+// Set stores a value on the server
+func (c *Client) Set(key string, value []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	c.report(c.SetContext(ctx, key, value))
+}
+
+// SetContext stores a value on the server
+func (c *Client) SetContext(ctx context.Context, key string, value []byte) error {
+	_, err := c.do(ctx, wire.Request{Op: wire.OpSet, Key: key, Value: value})
+	return err
+}
+
+// SetWithTTL stores a value on the server that expires after TTL
+func (c *Client) SetWithTTL(key string, value []byte, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	c.report(c.SetWithTTLContext(ctx, key, value, ttl))
+}
+
+// SetWithTTLContext stores a value on the server that expires after TTL
+func (c *Client) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return c.SetContext(ctx, key, value)
+	}
+
+	_, err := c.do(ctx, wire.Request{Op: wire.OpSet, Key: key, Value: value, TTL: ttl})
+	return err
+}
+
+// Delete deletes a value from the server and returns true, if not found or request failed returns false
+func (c *Client) Delete(key string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	ok, err := c.DeleteContext(ctx, key)
+	c.report(err)
+	return ok
+}
+
+// DeleteContext deletes a value from the server and returns true, if not found returns false
+func (c *Client) DeleteContext(ctx context.Context, key string) (bool, error) {
+	resp, err := c.do(ctx, wire.Request{Op: wire.OpDelete, Key: key})
+	if err != nil {
+		return false, err
+	}
+	return resp.Status == wire.StatusOK, nil
+}
+
+// Has returns true if value exists on the server, if request failed returns false
+func (c *Client) Has(key string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	ok, err := c.HasContext(ctx, key)
+	c.report(err)
+	return ok
+}
+
+// HasContext returns true if value exists on the server
+func (c *Client) HasContext(ctx context.Context, key string) (bool, error) {
+	resp, err := c.do(ctx, wire.Request{Op: wire.OpHas, Key: key})
+	if err != nil {
+		return false, err
+	}
+	return resp.Status == wire.StatusOK, nil
+}
+
+// Keys returns all keys stored on the server, if request failed returns nil
+func (c *Client) Keys() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	keys, err := c.KeysContext(ctx)
+	c.report(err)
+	return keys
+}
+
+// KeysContext returns all keys stored on the server
+func (c *Client) KeysContext(ctx context.Context) ([]string, error) {
+	resp, err := c.do(ctx, wire.Request{Op: wire.OpKeys})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// Len returns number of values stored on the server, if request failed returns 0
+func (c *Client) Len() int {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	n, err := c.LenContext(ctx)
+	c.report(err)
+	return n
+}
+
+// LenContext returns number of values stored on the server
+func (c *Client) LenContext(ctx context.Context) (int, error) {
+	resp, err := c.do(ctx, wire.Request{Op: wire.OpLen})
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Value) != 8 {
+		return 0, wire.ErrFrame
+	}
+	return int(binary.BigEndian.Uint64(resp.Value)), nil
+}
+
+// Ping checks that server is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, wire.Request{Op: wire.OpPing})
+	return err
+}
+
+// Err returns error of the last failed method without context
+func (c *Client) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.err
+}
+
+// Close closes all connections, pending requests fail with memkey.ErrClosed
+func (c *Client) Close() error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.lock.Unlock()
+
+	for i := range c.slots {
+		s := &c.slots[i]
+
+		s.lock.Lock()
+		if s.conn != nil {
+			s.conn.fail(memkey.ErrClosed)
+			s.conn = nil
+		}
+		s.lock.Unlock()
+	}
+
+	return nil
+}
+
+func (c *Client) report(err error) {
+	if err == nil {
+		return
+	}
+
+	c.lock.Lock()
+	c.err = err
+	c.lock.Unlock()
+}
+
+// do sends request using one of pooled connections and waits for response, request is retried once on a new
+// connection if it wasn't sent because connection was broken
+func (c *Client) do(ctx context.Context, req wire.Request) (wire.Response, error) {
+	var (
+		resp wire.Response
+		err  error
+	)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		var cn *conn
+		cn, err = c.acquire(ctx)
+		if err != nil {
+			return wire.Response{}, err
+		}
+
+		var retry bool
+		resp, retry, err = cn.roundTrip(ctx, req)
+		if !retry {
+			break
+		}
+	}
+
+	if err != nil {
+		return wire.Response{}, err
+	}
+	if resp.Status == wire.StatusError {
+		return wire.Response{}, fmt.Errorf("memkey/client: server error: %s", resp.Value)
+	}
+	return resp, nil
+}
+
+// acquire returns the next connection of the pool, dials a new one if connection is missing or broken
+func (c *Client) acquire(ctx context.Context) (*conn, error) {
+	c.lock.Lock()
+	closed := c.closed
+	c.lock.Unlock()
+	if closed {
+		return nil, memkey.ErrClosed
+	}
+
+	s := &c.slots[int(c.next.Add(1)-1)%len(c.slots)]
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn != nil && !s.conn.broken() {
+		return s.conn, nil
+	}
+
+	netConn, err := c.config.Dial(ctx, "tcp", c.config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("memkey/client: dial: %w", err)
+	}
+
+	// Close may have been called while dialing
+	c.lock.Lock()
+	closed = c.closed
+	c.lock.Unlock()
+	if closed {
+		_ = netConn.Close()
+		return nil, memkey.ErrClosed
+	}
+
+	s.conn = newConn(netConn)
+	return s.conn, nil
+}
+
+// call represents request waiting for response
+type call struct {
+	resp wire.Response
+	err  error
+	done chan struct{}
+}
+
+// conn is a single pipelined connection, responses are matched to requests in order they were sent
+type conn struct {
+	netConn net.Conn
+	w       *bufio.Writer
+
+	writeLock sync.Mutex
+
+	lock    sync.Mutex
+	pending []*call
+	err     error
+}
+
+func newConn(netConn net.Conn) *conn {
+	cn := &conn{
+		netConn: netConn,
+		w:       bufio.NewWriter(netConn),
+	}
+	go cn.readLoop(bufio.NewReader(netConn))
+	return cn
+}
+
+// roundTrip sends request and waits for response, retry reports that request wasn't sent because connection was
+// already broken
+func (cn *conn) roundTrip(ctx context.Context, req wire.Request) (resp wire.Response, retry bool, err error) {
+	cl := &call{done: make(chan struct{})}
+
+	cn.writeLock.Lock()
+
+	cn.lock.Lock()
+	if cn.err != nil {
+		err = cn.err
+		cn.lock.Unlock()
+		cn.writeLock.Unlock()
+		return wire.Response{}, true, err
+	}
+	cn.pending = append(cn.pending, cl)
+	cn.lock.Unlock()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = cn.netConn.SetWriteDeadline(deadline)
+	} else {
+		_ = cn.netConn.SetWriteDeadline(time.Time{})
+	}
+
+	if err = wire.WriteRequest(cn.w, req); err != nil {
+		// Nothing was written, so the connection is still usable, the call is the last pending one since writes are
+		// serialized
+		cn.lock.Lock()
+		cn.pending = cn.pending[:len(cn.pending)-1]
+		cn.lock.Unlock()
+		cn.writeLock.Unlock()
+		return wire.Response{}, false, err
+	}
+
+	err = cn.w.Flush()
+	cn.writeLock.Unlock()
+
+	if err != nil {
+		err = fmt.Errorf("memkey/client: write: %w", err)
+		cn.fail(err)
+		return wire.Response{}, false, err
+	}
+
+	select {
+	case <-cl.done:
+		return cl.resp, false, cl.err
+	case <-ctx.Done():
+		return wire.Response{}, false, ctx.Err()
+	}
+}
+
+func (cn *conn) readLoop(r *bufio.Reader) {
+	for {
+		resp, err := wire.ReadResponse(r)
+		if err != nil {
+			cn.fail(fmt.Errorf("memkey/client: read: %w", err))
+			return
+		}
+
+		cn.lock.Lock()
+		if len(cn.pending) == 0 {
+			cn.lock.Unlock()
+			cn.fail(errors.New("memkey/client: unexpected response"))
+			return
+		}
+		cl := cn.pending[0]
+		cn.pending = cn.pending[1:]
+		cn.lock.Unlock()
+
+		cl.resp = resp
+		close(cl.done)
+	}
+}
+
+// fail marks connection as broken, closes it and fails all pending requests with error
+func (cn *conn) fail(err error) {
+	cn.lock.Lock()
+	if cn.err != nil {
+		cn.lock.Unlock()
+		return
+	}
+	cn.err = err
+	pending := cn.pending
+	cn.pending = nil
+	cn.lock.Unlock()
+
+	_ = cn.netConn.Close()
+
+	for _, cl := range pending {
+		cl.err = err
+		close(cl.done)
+	}
+}
+
+func (cn *conn) broken() bool {
+	cn.lock.Lock()
+	defer cn.lock.Unlock()
+
+	return cn.err != nil
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymmrac/memkey"
+	"github.com/mymmrac/memkey/server"
+)
+
+func startServer(t *testing.T, s *memkey.TypedStore[string, []byte], addr string) *server.Native {
+	t.Helper()
+
+	n := server.NewNative(s)
+
+	listener, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+
+	go func() { _ = n.Serve(listener) }()
+	t.Cleanup(func() { _ = n.Close() })
+
+	return n
+}
+
+func listenAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	return addr
+}
+
+func TestClient(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	addr := listenAddr(t)
+	startServer(t, s, addr)
+
+	c := New(Config{Addr: addr})
+	defer func() { _ = c.Close() }()
+
+	require.NoError(t, c.Ping(context.Background()))
+
+	value, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Nil(t, value)
+
+	c.Set("a", []byte("1"))
+	value, ok = c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	c.SetWithTTL("b", []byte("2"), time.Minute)
+	deadline, ok := s.Deadline("b")
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	assert.True(t, c.Has("b"))
+	assert.ElementsMatch(t, []string{"a", "b"}, c.Keys())
+	assert.Equal(t, 2, c.Len())
+
+	assert.True(t, c.Delete("a"))
+	assert.False(t, c.Delete("a"))
+	assert.False(t, c.Has("a"))
+
+	assert.NoError(t, c.Err())
+}
+
+func TestClient_Pipelining(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	addr := listenAddr(t)
+	startServer(t, s, addr)
+
+	c := New(Config{Addr: addr, PoolSize: 2})
+	defer func() { _ = c.Close() }()
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			key := fmt.Sprintf("key:%d", i)
+			assert.NoError(t, c.SetContext(ctx, key, []byte(key)))
+
+			value, ok, err := c.GetContext(ctx, key)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, key, string(value))
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, s.Len())
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	addr := listenAddr(t)
+	n := startServer(t, s, addr)
+
+	c := New(Config{Addr: addr, PoolSize: 1, Timeout: time.Second})
+	defer func() { _ = c.Close() }()
+
+	c.Set("a", []byte("1"))
+	require.NoError(t, c.Err())
+
+	require.NoError(t, n.Close())
+
+	assert.False(t, c.Has("a"))
+	assert.Error(t, c.Err())
+
+	startServer(t, s, addr)
+
+	assert.Eventually(t, func() bool {
+		return c.Has("a")
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestClient_Context(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	// Server that accepts connections but never replies
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				break
+			}
+			conns = append(conns, conn)
+		}
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+
+	c := New(Config{Addr: listener.Addr().String()})
+	defer func() { _ = c.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	_, _, err = c.GetContext(ctx, "a")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_Close(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	addr := listenAddr(t)
+	startServer(t, s, addr)
+
+	c := New(Config{Addr: addr})
+	require.NoError(t, c.Ping(context.Background()))
+
+	require.NoError(t, c.Close())
+	require.NoError(t, c.Close())
+
+	assert.ErrorIs(t, c.Ping(context.Background()), memkey.ErrClosed)
+}
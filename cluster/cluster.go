@@ -0,0 +1,596 @@
+/*
+Package cluster provides partitioned store that spreads keys across many nodes using consistent hashing.
+
+Any memkey.KV can be a node, for example local memkey.TypedStore or client.Client of a remote server. Each node is
+placed on hash ring many times (virtual nodes), so keys are spread evenly and only a small part of keys move when
+nodes are added or removed.
+*/
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mymmrac/memkey"
+)
+
+const (
+	defaultVirtualNodes = 128
+	defaultReplicas     = 1
+)
+
+var (
+	// ErrNodeExists returned when node with the same name is already added
+	ErrNodeExists = errors.New("memkey/cluster: node already exists")
+	// ErrNodeNotFound returned when node with the specified name doesn't exist
+	ErrNodeNotFound = errors.New("memkey/cluster: node not found")
+	// ErrLastNode returned when the last node is removed, keys of the cluster would have nowhere to move
+	ErrLastNode = errors.New("memkey/cluster: can't remove the last node")
+	// ErrNoNodes returned when value is written to cluster without nodes
+	ErrNoNodes = errors.New("memkey/cluster: no nodes")
+)
+
+// Config represents configuration of cluster
+type Config[K comparable] struct {
+	// VirtualNodes is a number of points on hash ring per node, 128 is used by default
+	VirtualNodes int
+
+	// Replicas is a number of nodes that hold each key (replication factor), 1 is used by default
+	Replicas int
+
+	// ReadRepair stores value found by Get on replicas that miss it, repair waits for other operations like
+	// adding a node, so it doesn't overwrite values stored concurrently
+	ReadRepair bool
+
+	// Hash used to place keys on hash ring, FNV-1a of string keys or their fmt.Sprint representation is used by
+	// default
+	Hash func(key K) uint64
+}
+
+// Cluster is a store that spreads keys across nodes, each key is stored on Replicas nodes that follow key's hash on
+// hash ring. Writes go to all replicas of key, reads return value from the first replica that has it. Adding or
+// removing node moves keys to their new replicas, other operations wait until keys are moved.
+// Nodes that implement SetWithTTL and Deadline (like memkey.ExpiringKV) keep TTL of values, other nodes store values
+// without TTL. Nodes that implement TrySet and TrySetWithTTL (like memkey.TypedStore) report rejected writes
+type Cluster[K comparable, V any] struct {
+	config Config[K]
+
+	lock  sync.RWMutex
+	nodes map[string]memkey.KV[K, V]
+	ring  []point
+}
+
+// point represents virtual node on hash ring
+type point struct {
+	hash uint64
+	name string
+}
+
+// replica represents node that holds a key
+type replica[K comparable, V any] struct {
+	name string
+	node memkey.KV[K, V]
+}
+
+// ttlSetter represents node that can store values with TTL
+type ttlSetter[K comparable, V any] interface {
+	SetWithTTL(key K, value V, ttl time.Duration)
+}
+
+// deadlineGetter represents node that reports TTL deadlines of values
+type deadlineGetter[K comparable] interface {
+	Deadline(key K) (time.Time, bool)
+}
+
+// persister represents node that can remove TTL of values
+type persister[K comparable] interface {
+	Persist(key K) bool
+}
+
+// trySetter represents node that reports rejected writes
+type trySetter[K comparable, V any] interface {
+	TrySet(key K, value V) error
+	TrySetWithTTL(key K, value V, ttl time.Duration) error
+}
+
+var (
+	_ memkey.ExpiringKV[string, int] = (*Cluster[string, int])(nil)
+	_ memkey.IterableKV[string, int] = (*Cluster[string, int])(nil)
+	_ memkey.BatchKV[string, int]    = (*Cluster[string, int])(nil)
+)
+
+// New creates cluster without nodes
+func New[K comparable, V any](config Config[K]) *Cluster[K, V] {
+	if config.VirtualNodes <= 0 {
+		config.VirtualNodes = defaultVirtualNodes
+	}
+	if config.Replicas <= 0 {
+		config.Replicas = defaultReplicas
+	}
+	if config.Hash == nil {
+		config.Hash = hashKey[K]
+	}
+
+	return &Cluster[K, V]{
+		config: config,
+		nodes:  make(map[string]memkey.KV[K, V]),
+	}
+}
+
+// AddNode adds node to the cluster and moves keys that it now owns from other nodes, returns ErrNodeExists if node
+// with the same name already exists
+func (c *Cluster[K, V]) AddNode(name string, node memkey.KV[K, V]) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.nodes[name]; ok {
+		return fmt.Errorf("%w: %q", ErrNodeExists, name)
+	}
+
+	c.nodes[name] = node
+	for i := 0; i < c.config.VirtualNodes; i++ {
+		c.ring = append(c.ring, point{hash: hashString(name + "#" + strconv.Itoa(i)), name: name})
+	}
+	sort.Slice(c.ring, func(i, j int) bool { return c.ring[i].hash < c.ring[j].hash })
+
+	c.rebalance(c.nodes)
+	return nil
+}
+
+// RemoveNode moves all keys of node to remaining nodes and removes it from the cluster, returns ErrNodeNotFound if
+// node doesn't exist and ErrLastNode if it's the only node of the cluster
+func (c *Cluster[K, V]) RemoveNode(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	node, ok := c.nodes[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNodeNotFound, name)
+	}
+
+	if len(c.nodes) == 1 {
+		return fmt.Errorf("%w: %q", ErrLastNode, name)
+	}
+
+	delete(c.nodes, name)
+
+	ring := c.ring[:0]
+	for _, p := range c.ring {
+		if p.name != name {
+			ring = append(ring, p)
+		}
+	}
+	c.ring = ring
+
+	sources := make(map[string]memkey.KV[K, V], len(c.nodes)+1)
+	for n, kv := range c.nodes {
+		sources[n] = kv
+	}
+	sources[name] = node
+
+	c.rebalance(sources)
+	return nil
+}
+
+// Nodes returns sorted names of all nodes
+func (c *Cluster[K, V]) Nodes() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	names := make([]string, 0, len(c.nodes))
+	for name := range c.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Replicas returns names of nodes that hold the key, the first one is primary
+func (c *Cluster[K, V]) Replicas(key K) []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	replicas := c.replicas(key)
+	names := make([]string, len(replicas))
+	for i, r := range replicas {
+		names[i] = r.name
+	}
+
+	return names
+}
+
+// Get returns a value from the first replica that has it, or zero value and false
+func (c *Cluster[K, V]) Get(key K) (V, bool) {
+	c.lock.RLock()
+	value, ok, repair := c.get(key)
+	c.lock.RUnlock()
+
+	if repair {
+		c.repair(key)
+	}
+
+	return value, ok
+}
+
+// get returns a value from the first replica that has it and reports whether replicas before it miss the key,
+// must be called while cluster lock is held
+func (c *Cluster[K, V]) get(key K) (value V, ok bool, missing bool) {
+	for i, r := range c.replicas(key) {
+		if value, ok = r.node.Get(key); ok {
+			return value, true, i > 0
+		}
+	}
+
+	return value, false, false
+}
+
+// repair stores value of the key on replicas that miss it, the cluster lock is held exclusively, so values written
+// concurrently through the cluster are not overwritten by the repaired value
+func (c *Cluster[K, V]) repair(key K) {
+	if !c.config.ReadRepair {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	replicas := c.replicas(key)
+	for i, r := range replicas {
+		value, ok := r.node.Get(key)
+		if !ok {
+			continue
+		}
+
+		ttl, expired := remainingTTL(r.node, key)
+		if expired {
+			return
+		}
+
+		for _, missing := range replicas[:i] {
+			if !missing.node.Has(key) {
+				_ = set(missing.node, key, value, ttl)
+			}
+		}
+		return
+	}
+}
+
+// Set stores value on all replicas of the key, previously set TTL of the key is removed, does nothing if cluster
+// has no nodes
+func (c *Cluster[K, V]) Set(key K, value V) {
+	_ = c.TrySetWithTTL(key, value, 0)
+}
+
+// TrySet stores value on all replicas of the key like Set, returns ErrNoNodes if cluster has no nodes or the first
+// error of replicas that rejected the write, other replicas keep the value
+func (c *Cluster[K, V]) TrySet(key K, value V) error {
+	return c.TrySetWithTTL(key, value, 0)
+}
+
+// SetWithTTL stores value on all replicas of the key with TTL, non-positive TTL means no TTL, does nothing if
+// cluster has no nodes
+func (c *Cluster[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	_ = c.TrySetWithTTL(key, value, ttl)
+}
+
+// TrySetWithTTL stores value on all replicas of the key with TTL like SetWithTTL, returns ErrNoNodes if cluster has
+// no nodes or the first error of replicas that rejected the write, other replicas keep the value
+func (c *Cluster[K, V]) TrySetWithTTL(key K, value V, ttl time.Duration) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	replicas := c.replicas(key)
+	if len(replicas) == 0 {
+		return ErrNoNodes
+	}
+
+	var err error
+	for _, r := range replicas {
+		if setErr := set(r.node, key, value, ttl); setErr != nil && err == nil {
+			err = setErr
+		}
+	}
+
+	return err
+}
+
+// Deadline returns TTL deadline of value from the first replica that has it, zero deadline means no TTL or that
+// node doesn't report TTL, returns false if not found
+func (c *Cluster[K, V]) Deadline(key K) (time.Time, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for _, r := range c.replicas(key) {
+		if getter, ok := r.node.(deadlineGetter[K]); ok {
+			if deadline, found := getter.Deadline(key); found {
+				return deadline, true
+			}
+			continue
+		}
+
+		if r.node.Has(key) {
+			return time.Time{}, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Persist removes TTL of value on all replicas of the key that support TTL and returns true if any replica had TTL
+// of the value
+func (c *Cluster[K, V]) Persist(key K) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	persisted := false
+	for _, r := range c.replicas(key) {
+		if p, ok := r.node.(persister[K]); ok && p.Persist(key) {
+			persisted = true
+		}
+	}
+
+	return persisted
+}
+
+// Has returns true if any replica of the key has it
+func (c *Cluster[K, V]) Has(key K) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for _, r := range c.replicas(key) {
+		if r.node.Has(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Delete deletes value from all replicas of the key and returns true if any replica had it
+func (c *Cluster[K, V]) Delete(key K) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	deleted := false
+	for _, r := range c.replicas(key) {
+		if r.node.Delete(key) {
+			deleted = true
+		}
+	}
+
+	return deleted
+}
+
+// Len returns number of distinct keys stored in the cluster
+func (c *Cluster[K, V]) Len() int {
+	return len(c.Keys())
+}
+
+// Keys returns distinct keys of all nodes, no order is expected
+func (c *Cluster[K, V]) Keys() []K {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.keys()
+}
+
+// Values returns values of all distinct keys, no order is expected
+func (c *Cluster[K, V]) Values() []V {
+	entries := c.Entries()
+
+	values := make([]V, len(entries))
+	for i, entry := range entries {
+		values[i] = entry.Value
+	}
+
+	return values
+}
+
+// Entries returns entries (key-value pairs) of all distinct keys
+func (c *Cluster[K, V]) Entries() []memkey.Entry[K, V] {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	keys := c.keys()
+	entries := make([]memkey.Entry[K, V], 0, len(keys))
+	for _, key := range keys {
+		for _, r := range c.replicas(key) {
+			if value, ok := r.node.Get(key); ok {
+				entries = append(entries, memkey.Entry[K, V]{Key: key, Value: value})
+				break
+			}
+		}
+	}
+
+	return entries
+}
+
+// ForEach goes in loop through all entries and calls f with a key and value
+func (c *Cluster[K, V]) ForEach(f func(key K, value V) (stop bool)) {
+	for _, entry := range c.Entries() {
+		if f(entry.Key, entry.Value) {
+			return
+		}
+	}
+}
+
+// GetMany returns values stored with the specified keys, keys that are not found are omitted
+func (c *Cluster[K, V]) GetMany(keys ...K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, ok := c.Get(key); ok {
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
+// SetMany stores all values on their replicas
+func (c *Cluster[K, V]) SetMany(values map[K]V) {
+	for key, value := range values {
+		c.Set(key, value)
+	}
+}
+
+// DeleteMany deletes values with the specified keys and returns number of deleted values
+func (c *Cluster[K, V]) DeleteMany(keys ...K) int {
+	deleted := 0
+	for _, key := range keys {
+		if c.Delete(key) {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// keys returns distinct keys of all nodes, must be called while cluster lock is held
+func (c *Cluster[K, V]) keys() []K {
+	seen := make(map[K]struct{})
+	var keys []K
+	for _, node := range c.nodes {
+		for _, key := range node.Keys() {
+			if _, ok := seen[key]; ok {
+				continue
+			}
+
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// replicas returns nodes that hold the key, must be called while cluster lock is held
+func (c *Cluster[K, V]) replicas(key K) []replica[K, V] {
+	if len(c.ring) == 0 {
+		return nil
+	}
+
+	count := c.config.Replicas
+	if count > len(c.nodes) {
+		count = len(c.nodes)
+	}
+
+	hash := c.config.Hash(key)
+	start := sort.Search(len(c.ring), func(i int) bool { return c.ring[i].hash >= hash })
+
+	replicas := make([]replica[K, V], 0, count)
+	for i := 0; i < len(c.ring) && len(replicas) < count; i++ {
+		p := c.ring[(start+i)%len(c.ring)]
+
+		duplicate := false
+		for _, r := range replicas {
+			if r.name == p.name {
+				duplicate = true
+				break
+			}
+		}
+
+		if !duplicate {
+			replicas = append(replicas, replica[K, V]{name: p.name, node: c.nodes[p.name]})
+		}
+	}
+
+	return replicas
+}
+
+// rebalance copies keys of sources to their replicas that miss them and deletes keys from sources that are no
+// longer their replicas, must be called while cluster lock is held
+func (c *Cluster[K, V]) rebalance(sources map[string]memkey.KV[K, V]) {
+	for name, node := range sources {
+		for _, key := range node.Keys() {
+			value, ok := node.Get(key)
+			if !ok {
+				continue
+			}
+
+			ttl, expired := remainingTTL(node, key)
+
+			owner := false
+			for _, r := range c.replicas(key) {
+				if r.name == name {
+					owner = true
+					continue
+				}
+
+				if !expired && !r.node.Has(key) {
+					_ = set(r.node, key, value, ttl)
+				}
+			}
+
+			if !owner {
+				node.Delete(key)
+			}
+		}
+	}
+}
+
+// set stores value on node with TTL if node supports it, returns error if node reports that write is rejected
+func set[K comparable, V any](node memkey.KV[K, V], key K, value V, ttl time.Duration) error {
+	if setter, ok := node.(trySetter[K, V]); ok {
+		if ttl > 0 {
+			return setter.TrySetWithTTL(key, value, ttl)
+		}
+		return setter.TrySet(key, value)
+	}
+
+	if ttl > 0 {
+		if setter, ok := node.(ttlSetter[K, V]); ok {
+			setter.SetWithTTL(key, value, ttl)
+			return nil
+		}
+	}
+
+	node.Set(key, value)
+	return nil
+}
+
+// remainingTTL returns TTL left until value expires, or zero if value has no TTL or node doesn't report it
+func remainingTTL[K comparable, V any](node memkey.KV[K, V], key K) (ttl time.Duration, expired bool) {
+	getter, ok := node.(deadlineGetter[K])
+	if !ok {
+		return 0, false
+	}
+
+	deadline, ok := getter.Deadline(key)
+	if !ok || deadline.IsZero() {
+		return 0, false
+	}
+
+	ttl = time.Until(deadline)
+	return ttl, ttl <= 0
+}
+
+// hashKey returns hash of key, string keys are hashed as is, other keys by their fmt.Sprint representation
+func hashKey[K comparable](key K) uint64 {
+	if s, ok := any(key).(string); ok {
+		return hashString(s)
+	}
+	return hashString(fmt.Sprint(key))
+}
+
+// hashString returns FNV-1a hash of string with bits mixed, so similar strings are spread evenly
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return mix(h.Sum64())
+}
+
+// mix is a finalizer of SplitMix64
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,194 @@
+package cluster
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymmrac/memkey"
+)
+
+func newTestCluster(
+	t *testing.T, config Config[string], nodes int,
+) (*Cluster[string, int], []*memkey.TypedStore[string, int]) {
+	t.Helper()
+
+	c := New[string, int](config)
+	stores := make([]*memkey.TypedStore[string, int], nodes)
+	for i := range stores {
+		stores[i] = &memkey.TypedStore[string, int]{}
+		require.NoError(t, c.AddNode("node-"+strconv.Itoa(i), stores[i]))
+	}
+
+	return c, stores
+}
+
+// assertPlacement checks that every key is stored exactly on its replicas
+func assertPlacement(t *testing.T, c *Cluster[string, int], stores map[string]*memkey.TypedStore[string, int]) {
+	t.Helper()
+
+	for _, key := range c.Keys() {
+		replicas := c.Replicas(key)
+		for name, store := range stores {
+			assert.Equal(t, contains(replicas, name), store.Has(key), "key %s on %s", key, name)
+		}
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCluster(t *testing.T) {
+	c, stores := newTestCluster(t, Config[string]{}, 4)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+
+	for i := 0; i < 1000; i++ {
+		c.Set("key:"+strconv.Itoa(i), i)
+	}
+
+	assert.Equal(t, 1000, c.Len())
+	for _, store := range stores {
+		// Keys are spread roughly evenly
+		assert.InDelta(t, 250, store.Len(), 100)
+	}
+
+	value, ok := c.Get("key:42")
+	assert.True(t, ok)
+	assert.Equal(t, 42, value)
+	assert.True(t, c.Has("key:42"))
+
+	assert.True(t, c.Delete("key:42"))
+	assert.False(t, c.Delete("key:42"))
+	assert.False(t, c.Has("key:42"))
+
+	assert.Equal(t, map[string]int{"key:1": 1, "key:2": 2}, c.GetMany("key:1", "key:2", "key:42"))
+	assert.Equal(t, 2, c.DeleteMany("key:1", "key:2", "key:42"))
+	c.SetMany(map[string]int{"x": 1, "y": 2})
+	assert.Len(t, c.Entries(), 999)
+	assert.Len(t, c.Values(), 999)
+}
+
+func TestCluster_Rebalance(t *testing.T) {
+	c, stores := newTestCluster(t, Config[string]{Replicas: 2}, 3)
+
+	byName := make(map[string]*memkey.TypedStore[string, int])
+	for i, store := range stores {
+		byName["node-"+strconv.Itoa(i)] = store
+	}
+
+	for i := 0; i < 500; i++ {
+		c.Set("key:"+strconv.Itoa(i), i)
+	}
+	c.SetWithTTL("ttl", 1, time.Hour)
+
+	assertPlacement(t, c, byName)
+
+	total := 0
+	for _, store := range stores {
+		total += store.Len()
+	}
+	assert.Equal(t, 501*2, total)
+
+	added := &memkey.TypedStore[string, int]{}
+	require.NoError(t, c.AddNode("node-3", added))
+	assert.ErrorIs(t, c.AddNode("node-3", added), ErrNodeExists)
+	byName["node-3"] = added
+
+	assert.Greater(t, added.Len(), 0)
+	assert.Equal(t, 501, c.Len())
+	assertPlacement(t, c, byName)
+
+	require.NoError(t, c.RemoveNode("node-0"))
+	assert.ErrorIs(t, c.RemoveNode("node-0"), ErrNodeNotFound)
+	assert.Equal(t, 0, stores[0].Len())
+	delete(byName, "node-0")
+
+	assert.Equal(t, []string{"node-1", "node-2", "node-3"}, c.Nodes())
+	assert.Equal(t, 501, c.Len())
+	assertPlacement(t, c, byName)
+
+	for i := 0; i < 500; i++ {
+		value, ok := c.Get("key:" + strconv.Itoa(i))
+		require.True(t, ok)
+		require.Equal(t, i, value)
+	}
+
+	for _, name := range c.Replicas("ttl") {
+		deadline, ok := byName[name].Deadline("ttl")
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Second)
+	}
+}
+
+func TestCluster_ReadRepair(t *testing.T) {
+	c, stores := newTestCluster(t, Config[string]{Replicas: 3, ReadRepair: true}, 3)
+
+	c.Set("a", 1)
+	for _, store := range stores {
+		assert.True(t, store.Has("a"))
+	}
+
+	primary := c.Replicas("a")[0]
+	for i, store := range stores {
+		if "node-"+strconv.Itoa(i) == primary {
+			store.Delete("a")
+			value, ok := c.Get("a")
+			assert.True(t, ok)
+			assert.Equal(t, 1, value)
+			assert.True(t, store.Has("a"))
+		}
+	}
+}
+
+func TestCluster_Empty(t *testing.T) {
+	c := New[int, int](Config[int]{})
+
+	c.Set(1, 1)
+	assert.ErrorIs(t, c.TrySet(1, 1), ErrNoNodes)
+	assert.False(t, c.Has(1))
+	assert.Equal(t, 0, c.Len())
+	assert.Empty(t, c.Replicas(1))
+}
+
+func TestCluster_RemoveLastNode(t *testing.T) {
+	c, stores := newTestCluster(t, Config[string]{}, 1)
+
+	c.Set("a", 1)
+	assert.ErrorIs(t, c.RemoveNode("node-0"), ErrLastNode)
+	assert.Equal(t, []string{"node-0"}, c.Nodes())
+	assert.True(t, stores[0].Has("a"))
+}
+
+func TestCluster_TTL(t *testing.T) {
+	c, stores := newTestCluster(t, Config[string]{Replicas: 2}, 3)
+
+	require.NoError(t, c.TrySetWithTTL("a", 1, time.Hour))
+	deadline, ok := c.Deadline("a")
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Second)
+
+	assert.True(t, c.Persist("a"))
+	assert.False(t, c.Persist("a"))
+	deadline, ok = c.Deadline("a")
+	assert.True(t, ok)
+	assert.True(t, deadline.IsZero())
+
+	_, ok = c.Deadline("missing")
+	assert.False(t, ok)
+
+	for _, store := range stores {
+		require.NoError(t, store.Close())
+	}
+	assert.ErrorIs(t, c.TrySet("b", 2), memkey.ErrClosed)
+}
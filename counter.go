@@ -0,0 +1,196 @@
+package memkey
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect" //nolint:depguard // Kind and size of numeric type are required to find its limits
+)
+
+var (
+	// ErrTypeMismatch returned when stored value doesn't have the type expected by the operation
+	ErrTypeMismatch = errors.New("memkey: value has a different type")
+
+	// ErrOverflow returned when result of arithmetic operation doesn't fit into the value type
+	ErrOverflow = errors.New("memkey: numeric overflow")
+)
+
+// Integer is a constraint that permits any integer type
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Float is a constraint that permits any floating-point type
+type Float interface {
+	~float32 | ~float64
+}
+
+// Number is a constraint that permits any integer or floating-point type
+type Number interface {
+	Integer | Float
+}
+
+// CounterMode defines what counter does when result of operation doesn't fit into the value type
+type CounterMode uint8
+
+const (
+	// CounterWrap follows Go arithmetic: integers wrap around, floats become infinite
+	CounterWrap CounterMode = iota
+	// CounterSaturate clamps result to the minimum or maximum value of the type
+	CounterSaturate
+	// CounterError leaves value unchanged and returns ErrOverflow
+	CounterError
+)
+
+// Counter performs atomic arithmetic on numeric values of the store, missing keys are initialized with zero before
+// the operation. Operation holds the store lock, so it can't interleave with concurrent writes of the same key,
+// TTL of the key is kept
+type Counter[K comparable, N Number] struct {
+	mode CounterMode
+	// update replaces value stored with key by result of f while the store lock is held, f gets zero for missing key
+	update func(key K, f func(value N) (N, error)) (N, error)
+}
+
+// NewCounter creates counter of values with type N stored in the store, if stored value has another type
+// operations return ErrTypeMismatch
+func NewCounter[N Number, K comparable](store *Store[K], mode CounterMode) *Counter[K, N] {
+	return &Counter[K, N]{
+		mode: mode,
+		update: func(key K, f func(value N) (N, error)) (N, error) {
+			value, err := store.update(key, func(rawValue any, ok bool) (any, error) {
+				var current N
+				if ok {
+					if current, ok = rawValue.(N); !ok {
+						return nil, fmt.Errorf("%w: %v is %T, not %T", ErrTypeMismatch, key, rawValue, current)
+					}
+				}
+
+				next, err := f(current)
+				if err != nil {
+					return nil, err
+				}
+				return next, nil
+			})
+			if err != nil {
+				return zero[N](), err
+			}
+			return value.(N), nil //nolint:forcetypeassert // f always returns N
+		},
+	}
+}
+
+// NewTypedCounter creates counter of values stored in the store
+func NewTypedCounter[N Number, K comparable](store *TypedStore[K, N], mode CounterMode) *Counter[K, N] {
+	return &Counter[K, N]{
+		mode: mode,
+		update: func(key K, f func(value N) (N, error)) (N, error) {
+			return store.update(key, func(value N, _ bool) (N, error) {
+				return f(value)
+			})
+		},
+	}
+}
+
+// Incr adds delta to value stored with key and returns the new value
+func (c *Counter[K, N]) Incr(key K, delta N) (N, error) {
+	return c.update(key, func(value N) (N, error) {
+		return addNumber(value, delta, false, c.mode)
+	})
+}
+
+// Decr subtracts delta from value stored with key and returns the new value
+func (c *Counter[K, N]) Decr(key K, delta N) (N, error) {
+	return c.update(key, func(value N) (N, error) {
+		return addNumber(value, delta, true, c.mode)
+	})
+}
+
+// Incr atomically adds delta to value with type N stored in the store and returns the new value, missing key is
+// initialized with zero, integers wrap around on overflow. See Counter for other overflow modes
+func Incr[N Number, K comparable](store *Store[K], key K, delta N) (N, error) {
+	return NewCounter[N](store, CounterWrap).Incr(key, delta)
+}
+
+// Decr atomically subtracts delta from value with type N stored in the store and returns the new value, see Incr
+func Decr[N Number, K comparable](store *Store[K], key K, delta N) (N, error) {
+	return NewCounter[N](store, CounterWrap).Decr(key, delta)
+}
+
+// IncrTyped atomically adds delta to value stored in the store and returns the new value, see Incr
+func IncrTyped[N Number, K comparable](store *TypedStore[K, N], key K, delta N) (N, error) {
+	return NewTypedCounter(store, CounterWrap).Incr(key, delta)
+}
+
+// DecrTyped atomically subtracts delta from value stored in the store and returns the new value, see Incr
+func DecrTyped[N Number, K comparable](store *TypedStore[K, N], key K, delta N) (N, error) {
+	return NewTypedCounter(store, CounterWrap).Decr(key, delta)
+}
+
+// addNumber returns value plus delta, or value minus delta if subtract is true, overflow is handled by mode
+func addNumber[N Number](value, delta N, subtract bool, mode CounterMode) (N, error) {
+	result := value + delta
+	if subtract {
+		result = value - delta
+	}
+
+	kind := reflect.TypeOf(value).Kind()
+	isFloat := kind == reflect.Float32 || kind == reflect.Float64
+
+	var overflow, up bool
+	if isFloat {
+		overflow = math.IsInf(float64(result), 0) &&
+			!math.IsInf(float64(value), 0) && !math.IsInf(float64(delta), 0)
+		up = result > 0
+	} else {
+		up = (delta > 0) != subtract
+		overflow = delta != 0 && (up && result < value || !up && result > value)
+	}
+
+	if !overflow {
+		return result, nil
+	}
+
+	switch mode {
+	case CounterSaturate:
+		lowest, highest := numberLimits[N]()
+		if up {
+			return highest, nil
+		}
+		return lowest, nil
+	case CounterError:
+		op := "+"
+		if subtract {
+			op = "-"
+		}
+		return value, fmt.Errorf("%w: %v %s %v", ErrOverflow, value, op, delta)
+	default:
+		return result, nil
+	}
+}
+
+// numberLimits returns the minimum and maximum finite values of type N
+func numberLimits[N Number]() (lowest, highest N) {
+	typ := reflect.TypeOf(lowest)
+
+	switch typ.Kind() {
+	case reflect.Float32, reflect.Float64:
+		limit := math.MaxFloat64
+		if typ.Bits() == 32 {
+			limit = math.MaxFloat32
+		}
+		return -N(limit), N(limit)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Maximum is 2^(bits-1)-1, it's calculated without overflowing the type
+		half := N(1)
+		for i := 2; i < typ.Bits(); i++ {
+			half *= 2
+		}
+		highest = half - 1 + half
+		return -highest - 1, highest
+	default:
+		// Unsigned integer wraps around to its maximum
+		highest--
+		return 0, highest
+	}
+}
@@ -0,0 +1,185 @@
+package memkey
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncr(t *testing.T) {
+	s := &Store[string]{}
+
+	value, err := Incr(s, "hits", 5)
+	require.NoError(t, err)
+	assert.Equal(t, 5, value)
+
+	value, err = Incr(s, "hits", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 7, value)
+
+	value, err = Decr(s, "hits", 10)
+	require.NoError(t, err)
+	assert.Equal(t, -3, value)
+	assert.Equal(t, -3, MustGet[int](s, "hits"))
+
+	ratio, err := Incr(s, "ratio", 0.5)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, ratio, 0)
+
+	s.Set("name", "test")
+	_, err = Incr(s, "name", 1)
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	assert.Equal(t, "test", s.MustGet("name"))
+
+	// Stored value must have exactly the requested type
+	_, err = Incr[int64](s, "hits", 1)
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	assert.Equal(t, -3, s.MustGet("hits"))
+}
+
+func TestIncr_KeepsTTL(t *testing.T) {
+	s := &Store[string]{}
+	s.SetWithTTL("hits", 1, time.Hour)
+
+	deadline, ok := s.Deadline("hits")
+	require.True(t, ok)
+
+	_, err := Incr(s, "hits", 1)
+	require.NoError(t, err)
+
+	newDeadline, ok := s.Deadline("hits")
+	require.True(t, ok)
+	assert.Equal(t, deadline, newDeadline)
+}
+
+func TestIncrTyped(t *testing.T) {
+	s := &TypedStore[string, uint8]{}
+
+	value, err := IncrTyped(s, "a", 200)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(200), value)
+
+	value, err = IncrTyped(s, "a", 100)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(44), value)
+
+	value, err = DecrTyped(s, "a", 45)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(255), value)
+	stored, _ := s.Get("a")
+	assert.Equal(t, uint8(255), stored)
+}
+
+func TestCounter_Modes(t *testing.T) {
+	s := &Store[string]{}
+	s.Set("max", int8(120))
+	s.Set("min", int64(math.MinInt64+1))
+	s.Set("unsigned", uint(1))
+	s.Set("float", float32(math.MaxFloat32))
+
+	saturate := NewCounter[int8](s, CounterSaturate)
+	value, err := saturate.Incr("max", 10)
+	require.NoError(t, err)
+	assert.Equal(t, int8(math.MaxInt8), value)
+
+	value, err = saturate.Decr("max", -1)
+	require.NoError(t, err)
+	assert.Equal(t, int8(math.MaxInt8), value)
+
+	value, err = saturate.Decr("low", 100)
+	require.NoError(t, err)
+	assert.Equal(t, int8(-100), value)
+
+	value, err = saturate.Decr("low", 100)
+	require.NoError(t, err)
+	assert.Equal(t, int8(math.MinInt8), value)
+
+	minValue, err := NewCounter[int64](s, CounterSaturate).Incr("min", -10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(math.MinInt64), minValue)
+
+	unsigned, err := NewCounter[uint](s, CounterSaturate).Decr("unsigned", 2)
+	require.NoError(t, err)
+	assert.Zero(t, unsigned)
+
+	float, err := NewCounter[float32](s, CounterSaturate).Incr("float", math.MaxFloat32)
+	require.NoError(t, err)
+	assert.InDelta(t, float32(math.MaxFloat32), float, 0)
+
+	s.Set("max", int8(120))
+	_, err = NewCounter[int8](s, CounterError).Incr("max", 10)
+	require.ErrorIs(t, err, ErrOverflow)
+	assert.Equal(t, int8(120), s.MustGet("max"))
+
+	_, err = NewCounter[uint](s, CounterError).Decr("unsigned", 1)
+	require.ErrorIs(t, err, ErrOverflow)
+	assert.Equal(t, uint(0), s.MustGet("unsigned"))
+
+	_, err = NewCounter[float32](s, CounterError).Incr("float", math.MaxFloat32)
+	require.ErrorIs(t, err, ErrOverflow)
+
+	wrapped, err := NewCounter[int8](s, CounterWrap).Incr("max", 10)
+	require.NoError(t, err)
+	assert.Equal(t, int8(-126), wrapped)
+}
+
+func TestCounter_UniqueIndex(t *testing.T) {
+	s := &TypedStore[string, int]{}
+	require.NoError(t, s.AddUniqueIndex("value", func(value int) []IndexKey {
+		return []IndexKey{value}
+	}))
+
+	s.Set("a", 1)
+	s.Set("b", 2)
+
+	_, err := IncrTyped(s, "a", 1)
+	require.ErrorIs(t, err, ErrUniqueIndex)
+	stored, _ := s.Get("a")
+	assert.Equal(t, 1, stored)
+}
+
+func TestIncr_Concurrent(t *testing.T) {
+	s := &Store[string]{}
+	typed := &TypedStore[string, int64]{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				_, err := Incr(s, "hits", 1)
+				assert.NoError(t, err)
+				_, err = IncrTyped(typed, "hits", 2)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 8000, s.MustGet("hits"))
+	stored, _ := typed.Get("hits")
+	assert.Equal(t, int64(16000), stored)
+}
+
+func TestIncr_Events(t *testing.T) {
+	s := &Store[string]{}
+
+	var oldValues, newValues []any
+	s.OnSet(func(_ string, oldValue, newValue any) {
+		oldValues = append(oldValues, oldValue)
+		newValues = append(newValues, newValue)
+	}, HookSync)
+
+	_, err := Incr(s, "hits", 1)
+	require.NoError(t, err)
+	_, err = Incr(s, "hits", 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, []any{nil, 1}, oldValues)
+	assert.Equal(t, []any{1, 2}, newValues)
+}
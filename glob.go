@@ -0,0 +1,140 @@
+package memkey
+
+import "strings"
+
+// MatchGlob reports whether s matches glob pattern, pattern supports `*` (any sequence of characters),
+// `?` (any single character), `[abc]`, `[^abc]`, `[a-z]` (character classes) and `\` to escape special characters
+func MatchGlob(pattern, s string) bool {
+	p := []rune(pattern)
+	str := []rune(s)
+
+	// Position to return to on mismatch after the last star
+	starP, starS := -1, 0
+	pi, si := 0, 0
+
+	for si < len(str) {
+		if pi < len(p) {
+			switch p[pi] {
+			case '*':
+				starP, starS = pi, si
+				pi++
+				continue
+			case '?':
+				pi++
+				si++
+				continue
+			case '[':
+				if matched, next, ok := matchClass(p, pi, str[si]); ok {
+					if matched {
+						pi = next
+						si++
+						continue
+					}
+				} else if str[si] == '[' {
+					pi++
+					si++
+					continue
+				}
+			case '\\':
+				if pi+1 < len(p) {
+					if p[pi+1] == str[si] {
+						pi += 2
+						si++
+						continue
+					}
+				} else if str[si] == '\\' {
+					pi++
+					si++
+					continue
+				}
+			default:
+				if p[pi] == str[si] {
+					pi++
+					si++
+					continue
+				}
+			}
+		}
+
+		if starP < 0 {
+			return false
+		}
+
+		starS++
+		pi, si = starP+1, starS
+	}
+
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+
+	return pi == len(p)
+}
+
+// matchClass matches character against class that starts at position i of pattern, returns whether character
+// matched, position after the class, and false if class is not terminated
+func matchClass(p []rune, i int, c rune) (matched bool, next int, ok bool) {
+	i++
+
+	negate := false
+	if i < len(p) && p[i] == '^' {
+		negate = true
+		i++
+	}
+
+	first := true
+	for i < len(p) && (p[i] != ']' || first) {
+		first = false
+
+		lo := p[i]
+		if lo == '\\' && i+1 < len(p) {
+			i++
+			lo = p[i]
+		}
+
+		hi := lo
+		if i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']' {
+			hi = p[i+2]
+			if hi == '\\' && i+3 < len(p) {
+				i++
+				hi = p[i+2]
+			}
+			i += 2
+		}
+
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+
+		if lo <= c && c <= hi {
+			matched = true
+		}
+
+		i++
+	}
+
+	if i >= len(p) {
+		return false, 0, false
+	}
+
+	return matched != negate, i + 1, true
+}
+
+// globPrefix returns literal prefix of glob pattern, all strings that match pattern start with it
+func globPrefix(pattern string) string {
+	var prefix strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*', '?', '[':
+			return prefix.String()
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			prefix.WriteByte(pattern[i])
+		default:
+			prefix.WriteByte(c)
+		}
+	}
+	return prefix.String()
+}
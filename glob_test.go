@@ -0,0 +1,60 @@
+package memkey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", "anything", true},
+		{"user:*", "user:42:profile", true},
+		{"user:*", "users", false},
+		{"user:*:profile", "user:42:profile", true},
+		{"user:*:profile", "user:42:settings", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"*a*b*", "xxaxxbxx", true},
+		{"*a*b*", "xxbxxaxx", false},
+		{"[]]", "]", true},
+		{"[abc", "[abc", true},
+		{"ключ:*", "ключ:значення", true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, MatchGlob(tt.pattern, tt.s), "pattern %q, string %q", tt.pattern, tt.s)
+	}
+}
+
+func TestGlobPrefix(t *testing.T) {
+	tests := map[string]string{
+		"":          "",
+		"user:*":    "user:",
+		"user:?":    "user:",
+		"user:[ab]": "user:",
+		"a\\*b*":    "a*b",
+		"exact":     "exact",
+		"ключ:*":    "ключ:",
+		"trail\\":   "trail\\",
+	}
+
+	for pattern, prefix := range tests {
+		assert.Equal(t, prefix, globPrefix(pattern), "pattern %q", pattern)
+	}
+}
@@ -0,0 +1,76 @@
+package memkey
+
+import "sync"
+
+// Hook represents func that is called on change of a value in the store, old value is zero if there was no value
+// before the change and new value is zero if value was removed. Hooks are called after the store lock is released,
+// so hooks of concurrent writes to the same key may be called in a different order than the writes were applied
+type Hook[K comparable, V any] func(key K, oldValue, newValue V)
+
+// HookMode defines how hook is called
+type HookMode uint8
+
+const (
+	// HookSync calls hook in the goroutine that made the change, after the store lock is released,
+	// so hook can safely call back into the store
+	HookSync HookMode = iota
+	// HookAsync calls hook in a new goroutine
+	HookAsync
+)
+
+// eventClear is a kind of hooks registered by OnClear, such hooks are called with zero key and values and it's never
+// reported to watchers
+const eventClear EventKind = 0
+
+// hookEntry is a unique handle of registered hook
+type hookEntry[K comparable, V any] struct {
+	hook Hook[K, V]
+	mode HookMode
+}
+
+// hooks represents registry of hooks grouped by event kind
+type hooks[K comparable, V any] struct {
+	lock    sync.RWMutex
+	entries map[EventKind][]*hookEntry[K, V]
+}
+
+// add registers hook for specified event kind and returns a func that removes it
+func (h *hooks[K, V]) add(kind EventKind, hook Hook[K, V], mode HookMode) func() {
+	entry := &hookEntry[K, V]{hook: hook, mode: mode}
+
+	h.lock.Lock()
+	if h.entries == nil {
+		h.entries = make(map[EventKind][]*hookEntry[K, V])
+	}
+	h.entries[kind] = append(h.entries[kind], entry)
+	h.lock.Unlock()
+
+	return func() {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+
+		entries := h.entries[kind]
+		for i, e := range entries {
+			if e == entry {
+				h.entries[kind] = append(entries[:i:i], entries[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// run calls all hooks registered for specified event kind, must not be called while store lock is held
+func (h *hooks[K, V]) run(kind EventKind, key K, oldValue, newValue V) {
+	h.lock.RLock()
+	entries := h.entries[kind]
+	h.lock.RUnlock()
+
+	for _, entry := range entries {
+		if entry.mode == HookAsync {
+			go entry.hook(key, oldValue, newValue)
+			continue
+		}
+
+		entry.hook(key, oldValue, newValue)
+	}
+}
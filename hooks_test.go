@@ -0,0 +1,131 @@
+package memkey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedStore_Hooks(t *testing.T) {
+	s := &TypedStore[int, string]{}
+
+	type call struct {
+		key                int
+		oldValue, newValue string
+	}
+
+	t.Run("set", func(t *testing.T) {
+		var calls []call
+		remove := s.OnSet(func(key int, oldValue, newValue string) {
+			calls = append(calls, call{key, oldValue, newValue})
+		}, HookSync)
+
+		k := testKey(t)
+		s.Set(k, "a")
+		s.Set(k, "b")
+		remove()
+		s.Set(k, "c")
+
+		assert.Equal(t, []call{{k, "", "a"}, {k, "a", "b"}}, calls)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		var calls []call
+		remove := s.OnDelete(func(key int, oldValue, newValue string) {
+			calls = append(calls, call{key, oldValue, newValue})
+		}, HookSync)
+		defer remove()
+
+		k := testKey(t)
+		s.Set(k, "a")
+		s.Delete(k)
+		s.Delete(k)
+
+		assert.Equal(t, []call{{k, "a", ""}}, calls)
+	})
+
+	t.Run("async", func(t *testing.T) {
+		done := make(chan call, 1)
+		remove := s.OnSet(func(key int, oldValue, newValue string) {
+			done <- call{key, oldValue, newValue}
+		}, HookAsync)
+		defer remove()
+
+		k := testKey(t)
+		s.Set(k, "a")
+
+		select {
+		case c := <-done:
+			assert.Equal(t, call{k, "", "a"}, c)
+		case <-time.After(time.Second):
+			assert.FailNow(t, "timeout")
+		}
+	})
+
+	t.Run("reentrant", func(t *testing.T) {
+		remove := s.OnDelete(func(key int, oldValue, _ string) {
+			if oldValue == "reentrant" {
+				s.Set(key, "restored")
+			}
+		}, HookSync)
+		defer remove()
+
+		k := testKey(t)
+		s.Set(k, "reentrant")
+		s.Delete(k)
+
+		value, ok := s.Get(k)
+		assert.True(t, ok)
+		assert.Equal(t, "restored", value)
+	})
+}
+
+func TestTypedStore_OnExpire(t *testing.T) {
+	s := &TypedStore[int, string]{}
+
+	done := make(chan struct{})
+	s.OnExpire(func(key int, oldValue, newValue string) {
+		assert.Equal(t, 1, key)
+		assert.Equal(t, "a", oldValue)
+		assert.Equal(t, "", newValue)
+		done <- struct{}{}
+	}, HookSync)
+
+	go s.ExpireTTL(time.Millisecond, func(key int, value string) {
+		// Calling the store from expired func must not deadlock
+		assert.False(t, s.Has(key))
+	})
+
+	s.SetWithTTL(1, "a", time.Millisecond)
+
+	select {
+	case <-done:
+		assert.Equal(t, 0, s.Len())
+	case <-time.After(time.Second):
+		assert.FailNow(t, "timeout")
+	}
+}
+
+func TestStore_Hooks(t *testing.T) {
+	s := &Store[int]{}
+
+	var oldValues, newValues []any
+	s.OnSet(func(_ int, oldValue, newValue any) {
+		oldValues = append(oldValues, oldValue)
+		newValues = append(newValues, newValue)
+	}, HookSync)
+	s.OnDelete(func(_ int, oldValue, newValue any) {
+		oldValues = append(oldValues, oldValue)
+		newValues = append(newValues, newValue)
+	}, HookSync)
+
+	k := testKey(t)
+	Set(s, k, 1)
+	s.Set(k, "a")
+	assert.False(t, Delete[int](s, k))
+	assert.True(t, Delete[string](s, k))
+
+	assert.Equal(t, []any{nil, 1, "a"}, oldValues)
+	assert.Equal(t, []any{1, "a", nil}, newValues)
+}
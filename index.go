@@ -0,0 +1,206 @@
+package memkey
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrIndexExists returned when index with the same name is already added to the store
+	ErrIndexExists = errors.New("memkey: index already exists")
+
+	// ErrUniqueIndex returned when write is rejected because another value has the same key in unique index
+	ErrUniqueIndex = errors.New("memkey: duplicate key in unique index")
+
+	// ErrIndexKey returned when write is rejected because index func returned key that is not comparable
+	ErrIndexKey = errors.New("memkey: index key is not comparable")
+)
+
+// IndexKey represents key of a value in secondary index, it must be comparable (including dynamic values of
+// interface fields), writes of values with keys that are not comparable are rejected with ErrIndexKey
+type IndexKey any
+
+// valueIndex represents secondary index that maps index keys to keys of values in the store, it's updated while
+// the store lock is held
+type valueIndex[K comparable, V any] struct {
+	keys   func(value V) []IndexKey
+	unique bool
+
+	entries map[IndexKey]map[K]struct{}
+	// byKey contains index keys of every indexed value, so value is not needed to remove it from index
+	byKey map[K][]IndexKey
+}
+
+func newValueIndex[K comparable, V any](keys func(value V) []IndexKey, unique bool) *valueIndex[K, V] {
+	return &valueIndex[K, V]{
+		keys:    keys,
+		unique:  unique,
+		entries: make(map[IndexKey]map[K]struct{}),
+		byKey:   make(map[K][]IndexKey),
+	}
+}
+
+// record updates index with change of the store
+func (i *valueIndex[K, V]) record(kind EventKind, key K, value V) {
+	i.remove(key)
+	if kind == EventSet {
+		i.add(key, value)
+	}
+}
+
+func (i *valueIndex[K, V]) add(key K, value V) {
+	// Restored values are not checked, so keys that are not comparable are skipped instead of panicking
+	valueKeys := i.keys(value)
+	indexKeys := make([]IndexKey, 0, len(valueKeys))
+	for _, indexKey := range valueKeys {
+		if comparableKey(indexKey) {
+			indexKeys = append(indexKeys, indexKey)
+		}
+	}
+	if len(indexKeys) == 0 {
+		return
+	}
+
+	i.byKey[key] = indexKeys
+	for _, indexKey := range indexKeys {
+		keys, ok := i.entries[indexKey]
+		if !ok {
+			keys = make(map[K]struct{})
+			i.entries[indexKey] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+func (i *valueIndex[K, V]) remove(key K) {
+	for _, indexKey := range i.byKey[key] {
+		keys := i.entries[indexKey]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(i.entries, indexKey)
+		}
+	}
+	delete(i.byKey, key)
+}
+
+// check returns error if value can't be stored with key: if any of its index keys is not comparable or, if index is
+// unique, is already used by a value with another key
+func (i *valueIndex[K, V]) check(name string, key K, value V) error {
+	indexKeys := i.keys(value)
+	for _, indexKey := range indexKeys {
+		if !comparableKey(indexKey) {
+			return fmt.Errorf("%w: index %q returned %T", ErrIndexKey, name, indexKey)
+		}
+	}
+
+	if !i.unique {
+		return nil
+	}
+
+	for _, indexKey := range indexKeys {
+		for other := range i.entries[indexKey] {
+			if other != key {
+				return fmt.Errorf("%w %q: %v", ErrUniqueIndex, name, indexKey)
+			}
+		}
+	}
+
+	return nil
+}
+
+// comparableKey reports whether index key can be used as a map key
+func comparableKey(key IndexKey) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	// Lookup in nil map still hashes the key, so it panics if dynamic type of the key is not comparable
+	var probe map[IndexKey]struct{}
+	_ = probe[key]
+
+	return true
+}
+
+// AddIndex adds secondary index that maps every value to its index keys, index is kept up to date with all changes
+// of the store and values can be found by their index keys with Lookup. Index keys must be comparable, if keys of
+// stored values are not, index is not added and ErrIndexKey is returned. Keys func is called while the store lock is
+// held, so it must not call back into the store
+func (s *TypedStore[K, V]) AddIndex(name string, keys func(value V) []IndexKey) error {
+	return s.addIndex(name, newValueIndex[K, V](keys, false))
+}
+
+// AddUniqueIndex adds secondary index like AddIndex, but two values with different keys can't have the same index
+// key. Writes that violate it are rejected, TrySet and TrySetWithTTL return ErrUniqueIndex for them. If stored
+// values already violate it, index is not added and ErrUniqueIndex is returned
+func (s *TypedStore[K, V]) AddUniqueIndex(name string, keys func(value V) []IndexKey) error {
+	return s.addIndex(name, newValueIndex[K, V](keys, true))
+}
+
+func (s *TypedStore[K, V]) addIndex(name string, index *valueIndex[K, V]) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.indexes[name]; ok {
+		return fmt.Errorf("%w: %q", ErrIndexExists, name)
+	}
+
+	for key, value := range s.data {
+		if err := index.check(name, key, value); err != nil {
+			return err
+		}
+		index.add(key, value)
+	}
+
+	if s.indexes == nil {
+		s.indexes = make(map[string]*valueIndex[K, V])
+	}
+	s.indexes[name] = index
+
+	return nil
+}
+
+// RemoveIndex removes secondary index and returns true, if index not found returns false
+func (s *TypedStore[K, V]) RemoveIndex(name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.indexes[name]; !ok {
+		return false
+	}
+
+	delete(s.indexes, name)
+	return true
+}
+
+// Lookup returns entries (key-value pairs) that have specified key in secondary index, no order is expected,
+// if index not found or index key is not comparable returns nil
+func (s *TypedStore[K, V]) Lookup(name string, indexKey IndexKey) []Entry[K, V] {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	index, ok := s.indexes[name]
+	if !ok || !comparableKey(indexKey) {
+		return nil
+	}
+
+	keys := index.entries[indexKey]
+	entries := make([]Entry[K, V], 0, len(keys))
+	for key := range keys {
+		entries = append(entries, Entry[K, V]{Key: key, Value: s.data[key]})
+	}
+
+	return entries
+}
+
+// checkIndexes returns error if value can't be stored with key because of its index keys, must be called while the
+// store lock is held
+func (s *TypedStore[K, V]) checkIndexes(key K, value V) error {
+	for name, index := range s.indexes {
+		if err := index.check(name, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package memkey
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSession struct {
+	UserID int
+	Email  string
+	Tags   []string
+}
+
+func TestTypedStore_Index(t *testing.T) {
+	s := &TypedStore[string, testSession]{}
+	s.Set("s1", testSession{UserID: 1, Tags: []string{"web", "admin"}})
+
+	require.NoError(t, s.AddIndex("user", func(session testSession) []IndexKey {
+		return []IndexKey{session.UserID}
+	}))
+	require.NoError(t, s.AddIndex("tag", func(session testSession) []IndexKey {
+		keys := make([]IndexKey, 0, len(session.Tags))
+		for _, tag := range session.Tags {
+			keys = append(keys, tag)
+		}
+		return keys
+	}))
+	assert.ErrorIs(t, s.AddIndex("user", nil), ErrIndexExists)
+
+	s.Set("s2", testSession{UserID: 1, Tags: []string{"web"}})
+	s.SetWithTTL("s3", testSession{UserID: 2, Tags: []string{"mobile"}}, time.Millisecond)
+
+	assert.ElementsMatch(t, []string{"s1", "s2"}, lookupKeys(s.Lookup("user", 1)))
+	assert.ElementsMatch(t, []string{"s1", "s2"}, lookupKeys(s.Lookup("tag", "web")))
+	assert.Equal(t, []string{"s3"}, lookupKeys(s.Lookup("user", 2)))
+	assert.Empty(t, s.Lookup("user", 3))
+	assert.Nil(t, s.Lookup("missing", 1))
+
+	// Index follows updates, deletes and expiration
+	s.Set("s1", testSession{UserID: 3})
+	assert.Equal(t, []string{"s2"}, lookupKeys(s.Lookup("user", 1)))
+	assert.Equal(t, []string{"s1"}, lookupKeys(s.Lookup("user", 3)))
+	assert.Empty(t, s.Lookup("tag", "admin"))
+
+	s.Delete("s2")
+	assert.Empty(t, s.Lookup("user", 1))
+
+	expired := make(chan struct{})
+	go s.ExpireTTL(time.Millisecond, func(string, testSession) { close(expired) })
+	<-expired
+	assert.Empty(t, s.Lookup("user", 2))
+	assert.Empty(t, s.Lookup("tag", "mobile"))
+
+	assert.True(t, s.RemoveIndex("tag"))
+	assert.False(t, s.RemoveIndex("tag"))
+	assert.Nil(t, s.Lookup("tag", "web"))
+}
+
+func TestTypedStore_UniqueIndex(t *testing.T) {
+	s := &TypedStore[int, testSession]{}
+	s.Set(1, testSession{Email: "a@example.com"})
+	s.Set(2, testSession{Email: "a@example.com"})
+
+	byEmail := func(session testSession) []IndexKey {
+		if session.Email == "" {
+			return nil
+		}
+		return []IndexKey{session.Email}
+	}
+
+	assert.ErrorIs(t, s.AddUniqueIndex("email", byEmail), ErrUniqueIndex)
+	assert.Nil(t, s.Lookup("email", "a@example.com"))
+
+	s.Set(2, testSession{Email: "b@example.com"})
+	require.NoError(t, s.AddUniqueIndex("email", byEmail))
+
+	err := s.TrySet(3, testSession{Email: "a@example.com"})
+	assert.ErrorIs(t, err, ErrUniqueIndex)
+	assert.EqualError(t, err, `memkey: duplicate key in unique index "email": a@example.com`)
+	assert.False(t, s.Has(3))
+
+	assert.ErrorIs(t, s.TrySetWithTTL(3, testSession{Email: "b@example.com"}, time.Hour), ErrUniqueIndex)
+	s.Set(3, testSession{Email: "a@example.com"})
+	assert.False(t, s.Has(3))
+
+	// Set silently drops rejected writes and keeps stored value
+	s.Set(2, testSession{Email: "a@example.com"})
+	value, _ := s.Get(2)
+	assert.Equal(t, "b@example.com", value.Email)
+
+	// Value can keep its own index key and values without index keys are not checked
+	require.NoError(t, s.TrySet(1, testSession{Email: "a@example.com", UserID: 1}))
+	require.NoError(t, s.TrySet(3, testSession{}))
+	require.NoError(t, s.TrySet(4, testSession{}))
+
+	s.SetMany(map[int]testSession{5: {Email: "b@example.com"}, 6: {Email: "c@example.com"}})
+	assert.False(t, s.Has(5))
+	assert.True(t, s.Has(6))
+
+	s.Delete(1)
+	require.NoError(t, s.TrySet(7, testSession{Email: "a@example.com"}))
+	assert.Equal(t, []Entry[int, testSession]{{Key: 7, Value: testSession{Email: "a@example.com"}}},
+		s.Lookup("email", "a@example.com"))
+}
+
+func TestTypedStore_IndexKeyNotComparable(t *testing.T) {
+	s := &TypedStore[string, testSession]{}
+	s.Set("s1", testSession{Tags: []string{"web"}})
+
+	byTags := func(session testSession) []IndexKey {
+		return []IndexKey{session.Tags}
+	}
+	assert.ErrorIs(t, s.AddIndex("tags", byTags), ErrIndexKey)
+	assert.Nil(t, s.Lookup("tags", "web"))
+
+	type wrapper struct{ Value any }
+	require.NoError(t, s.AddIndex("user", func(session testSession) []IndexKey {
+		if session.UserID < 0 {
+			return []IndexKey{wrapper{Value: session.Tags}}
+		}
+		return []IndexKey{session.UserID}
+	}))
+
+	err := s.TrySet("s2", testSession{UserID: -1})
+	assert.ErrorIs(t, err, ErrIndexKey)
+	assert.EqualError(t, err, `memkey: index key is not comparable: index "user" returned memkey.wrapper`)
+	assert.False(t, s.Has("s2"))
+
+	s.SetMany(map[string]testSession{"s2": {UserID: -1}, "s3": {UserID: 3}})
+	assert.False(t, s.Has("s2"))
+	assert.Equal(t, []string{"s3"}, lookupKeys(s.Lookup("user", 3)))
+	assert.Nil(t, s.Lookup("user", []int{3}))
+	assert.Nil(t, s.Lookup("user", wrapper{Value: []string{"web"}}))
+}
+
+func TestTypedStore_IndexPanic(t *testing.T) {
+	s := &TypedStore[string, testSession]{}
+	require.NoError(t, s.AddIndex("user", func(session testSession) []IndexKey {
+		if session.UserID < 0 {
+			panic("negative user")
+		}
+		return []IndexKey{session.UserID}
+	}))
+
+	assert.PanicsWithValue(t, "negative user", func() { s.Set("s1", testSession{UserID: -1}) })
+	assert.Panics(t, func() { s.SetMany(map[string]testSession{"s1": {UserID: -1}}) })
+
+	// Store is not left locked
+	s.Set("s1", testSession{UserID: 1})
+	assert.Equal(t, []string{"s1"}, lookupKeys(s.Lookup("user", 1)))
+}
+
+func TestTypedStore_IndexLoad(t *testing.T) {
+	source := &TypedStore[string, testSession]{}
+	source.Set("s1", testSession{UserID: 1})
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, source.Save(buf, JSONCodec{}))
+
+	s := &TypedStore[string, testSession]{}
+	require.NoError(t, s.AddIndex("user", func(session testSession) []IndexKey {
+		return []IndexKey{session.UserID}
+	}))
+	require.NoError(t, s.Load(buf, JSONCodec{}))
+
+	assert.Equal(t, []string{"s1"}, lookupKeys(s.Lookup("user", 1)))
+}
+
+func lookupKeys[V any](entries []Entry[string, V]) []string {
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
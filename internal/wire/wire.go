@@ -0,0 +1,248 @@
+/*
+Package wire implements framed protocol used by memkey server and client.
+
+Every message is a frame of big-endian uint32 payload length followed by payload. Request payload is an operation
+byte, key, value and TTL in nanoseconds (int64), response payload is a status byte, value and list of keys. Keys and
+values are encoded as uint32 length followed by bytes. Responses are sent in order of requests, so client may send
+many requests without waiting for responses.
+*/
+package wire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MaxFrameSize limits size of a single frame payload
+const MaxFrameSize = 256 * 1024 * 1024
+
+// ErrFrame returned when frame can't be decoded
+var ErrFrame = errors.New("memkey/wire: malformed frame")
+
+// Op represents requested operation
+type Op byte
+
+// Operations
+const (
+	OpPing Op = iota + 1
+	OpGet
+	OpSet
+	OpDelete
+	OpHas
+	OpKeys
+	OpLen
+)
+
+// Status represents result of operation
+type Status byte
+
+// Statuses, for operations that return bool StatusOK means true and StatusNotFound means false,
+// value of StatusError response is an error message, value of OpLen response is big-endian uint64
+const (
+	StatusOK Status = iota
+	StatusNotFound
+	StatusError
+)
+
+// Request represents single request
+type Request struct {
+	Op    Op
+	Key   string
+	Value []byte
+	TTL   time.Duration
+}
+
+// Response represents single response
+type Response struct {
+	Status Status
+	Value  []byte
+	Keys   []string
+}
+
+// WriteRequest writes request frame, writer is not flushed
+func WriteRequest(w *bufio.Writer, req Request) error {
+	size := 1 + 4 + len(req.Key) + 4 + len(req.Value) + 8
+
+	if err := writeHeader(w, size); err != nil {
+		return err
+	}
+
+	_ = w.WriteByte(byte(req.Op))
+	writeBytes(w, []byte(req.Key))
+	writeBytes(w, req.Value)
+	writeUint64(w, uint64(req.TTL))
+
+	return nil
+}
+
+// ReadRequest reads request frame
+func ReadRequest(r *bufio.Reader) (Request, error) {
+	payload, err := readFrame(r)
+	if err != nil {
+		return Request{}, err
+	}
+
+	d := decoder{data: payload}
+	req := Request{
+		Op:    Op(d.byte()),
+		Key:   string(d.bytes()),
+		Value: d.bytes(),
+		TTL:   time.Duration(d.uint64()),
+	}
+
+	return req, d.finish()
+}
+
+// WriteResponse writes response frame, writer is not flushed
+func WriteResponse(w *bufio.Writer, resp Response) error {
+	size := 1 + 4 + len(resp.Value) + 4
+	for _, key := range resp.Keys {
+		size += 4 + len(key)
+	}
+
+	if err := writeHeader(w, size); err != nil {
+		return err
+	}
+
+	_ = w.WriteByte(byte(resp.Status))
+	writeBytes(w, resp.Value)
+	writeUint32(w, uint32(len(resp.Keys)))
+	for _, key := range resp.Keys {
+		writeBytes(w, []byte(key))
+	}
+
+	return nil
+}
+
+// ReadResponse reads response frame
+func ReadResponse(r *bufio.Reader) (Response, error) {
+	payload, err := readFrame(r)
+	if err != nil {
+		return Response{}, err
+	}
+
+	d := decoder{data: payload}
+	resp := Response{
+		Status: Status(d.byte()),
+		Value:  d.bytes(),
+	}
+
+	count := d.uint32()
+	if count > 0 && d.err == nil {
+		if uint64(count)*4 > uint64(len(d.data)) {
+			return Response{}, ErrFrame
+		}
+
+		resp.Keys = make([]string, count)
+		for i := range resp.Keys {
+			resp.Keys[i] = string(d.bytes())
+		}
+	}
+
+	return resp, d.finish()
+}
+
+func writeHeader(w *bufio.Writer, size int) error {
+	if size > MaxFrameSize {
+		return fmt.Errorf("memkey/wire: frame too large: %d bytes", size)
+	}
+	writeUint32(w, uint32(size))
+	return nil
+}
+
+func writeBytes(w *bufio.Writer, data []byte) {
+	writeUint32(w, uint32(len(data)))
+	_, _ = w.Write(data)
+}
+
+func writeUint32(w *bufio.Writer, n uint32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], n)
+	_, _ = w.Write(buf[:])
+}
+
+func writeUint64(w *bufio.Writer, n uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], n)
+	_, _ = w.Write(buf[:])
+}
+
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxFrameSize {
+		return nil, ErrFrame
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// decoder reads fields of payload, after the first error all reads return zero values
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) next(n uint64) []byte {
+	if d.err != nil || n > uint64(len(d.data)) {
+		d.err = ErrFrame
+		return nil
+	}
+
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) byte() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uint32()
+	return d.next(uint64(n))
+}
+
+// finish returns decoding error or ErrFrame if not all data was read
+func (d *decoder) finish() error {
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.data) != 0 {
+		return ErrFrame
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package memkey
+
+import "time"
+
+// journal records changes of the store in the order they are applied, it's called while the store lock is held,
+// so it must not call back into the store
+type journal[K comparable, V any] interface {
+	// record is called with EventSet (value and deadline are set, zero deadline means no TTL), EventDelete or
+	// EventExpire, evicted values are recorded as EventDelete
+	record(kind EventKind, key K, value V, deadline time.Time)
+}
+
+// journalSource represents store that can record its changes to journals
+type journalSource[K comparable, V any] interface {
+	// snapshotEntries returns all values with their TTL deadlines, if during func not nil it will be called while
+	// the store lock is still held
+	snapshotEntries(during func()) []snapshotEntry[K, V]
+	// attachJournal restores values and attaches journal that records all following changes, returns a func that
+	// detaches the journal and number of values stored after restoring
+	attachJournal(entries []snapshotEntry[K, V], j journal[K, V]) (detach func(), stored int)
+}
+
+// journalChecker represents journal that can reject a write before it's applied to the store
+type journalChecker[K comparable, V any] interface {
+	// check returns error if value can't be recorded, it's called while the store lock is held
+	check(key K, value V) error
+}
+
+// checkJournals returns the first error of journals that reject a write
+func checkJournals[K comparable, V any](journals []journal[K, V], key K, value V) error {
+	for _, j := range journals {
+		if checker, ok := j.(journalChecker[K, V]); ok {
+			if err := checker.check(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeJournal returns a copy of journals without specified journal
+func removeJournal[K comparable, V any](journals []journal[K, V], j journal[K, V]) []journal[K, V] {
+	result := make([]journal[K, V], 0, len(journals))
+	for _, existing := range journals {
+		if existing != j {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
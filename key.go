@@ -0,0 +1,124 @@
+package memkey
+
+import (
+	"fmt"
+	"reflect" //nolint:depguard // Types of values are compared to find keys declared with different types
+	"sync"
+	"time"
+)
+
+// Key represents handle of a key in Store that holds values of type V, all methods are type-checked by the
+// compiler, so the same key can't be accidentally read or written with another type. Key must be created with NewKey
+type Key[K comparable, V any] struct {
+	key K
+}
+
+// keyRegistry represents registry of keys declared with NewKey
+type keyRegistry struct {
+	lock  sync.Mutex
+	types map[registeredKey]reflect.Type
+}
+
+// registeredKey represents key in the registry, keys of stores with different key types are different keys even if
+// their values are equal (for example, "a" as string and "a" as any)
+type registeredKey struct {
+	keyType reflect.Type
+	key     any
+}
+
+// declaredKeys is a global registry of keys declared by all packages
+var declaredKeys = &keyRegistry{
+	types: make(map[registeredKey]reflect.Type),
+}
+
+// NewKey creates handle of key that holds values of type V. Keys are registered globally by key type K and key,
+// creating the same key with the same type multiple times is allowed. It panics if the key was already created with a
+// different type or if dynamic value of interface key type K is not comparable. Keys of different Go types are
+// different keys (for example, int(1) and int64(1)). Registrations are kept until Release is called, so keys are
+// expected to be created once, for example, as package-level variables
+func NewKey[V any, K comparable](key K) *Key[K, V] {
+	if err := declaredKeys.declare(newRegisteredKey(key), reflect.TypeOf((*V)(nil)).Elem()); err != nil {
+		panic(err)
+	}
+	return &Key[K, V]{key: key}
+}
+
+func newRegisteredKey[K comparable](key K) registeredKey {
+	return registeredKey{
+		keyType: reflect.TypeOf((*K)(nil)).Elem(),
+		key:     key,
+	}
+}
+
+// declare registers type of key, declaring the same key and type twice is not an error
+func (r *keyRegistry) declare(key registeredKey, typ reflect.Type) error {
+	if !comparableKey(key.key) {
+		return fmt.Errorf("memkey: key of type %T is not comparable", key.key)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if existing, ok := r.types[key]; ok && existing != typ {
+		return fmt.Errorf("memkey: key %v already declared with type %s, not %s", key.key, existing, typ)
+	}
+
+	r.types[key] = typ
+	return nil
+}
+
+// release removes registration of key if it's declared with type
+func (r *keyRegistry) release(key registeredKey, typ reflect.Type) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.types[key] == typ {
+		delete(r.types, key)
+	}
+}
+
+// Release removes registration of the key, so it can be created with another type by NewKey. All handles of the key
+// are released, but they can still be used to access values
+func (k *Key[K, V]) Release() {
+	declaredKeys.release(newRegisteredKey(k.key), reflect.TypeOf((*V)(nil)).Elem())
+}
+
+// Key returns key of the handle
+func (k *Key[K, V]) Key() K {
+	return k.key
+}
+
+// Get returns value stored with the key, if it doesn't exist or has another type returns false
+func (k *Key[K, V]) Get(store *Store[K]) (V, bool) {
+	return Get[V](store, k.key)
+}
+
+// MustGet returns value stored with the key, or zero value of V
+func (k *Key[K, V]) MustGet(store *Store[K]) V {
+	return MustGet[V](store, k.key)
+}
+
+// Set stores value with the key, previously set TTL of the key is removed
+func (k *Key[K, V]) Set(store *Store[K], value V) {
+	Set(store, k.key, value)
+}
+
+// SetWithTTL stores value with the key that expires after TTL
+func (k *Key[K, V]) SetWithTTL(store *Store[K], value V, ttl time.Duration) {
+	SetWithTTL(store, k.key, value, ttl)
+}
+
+// Has returns true if value stored with the key has type V
+func (k *Key[K, V]) Has(store *Store[K]) bool {
+	return Has[V](store, k.key)
+}
+
+// Delete deletes value stored with the key if it has type V and returns true, otherwise returns false
+func (k *Key[K, V]) Delete(store *Store[K]) bool {
+	return Delete[V](store, k.key)
+}
+
+// String returns key formatted with its value type
+func (k *Key[K, V]) String() string {
+	return fmt.Sprintf("%v (%s)", k.key, reflect.TypeOf((*V)(nil)).Elem())
+}
@@ -0,0 +1,94 @@
+package memkey
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	s := &Store[string]{}
+	timeout := NewKey[time.Duration]("test-key-timeout")
+	assert.Equal(t, "test-key-timeout", timeout.Key())
+	assert.Equal(t, "test-key-timeout (time.Duration)", timeout.String())
+
+	_, ok := timeout.Get(s)
+	assert.False(t, ok)
+	assert.False(t, timeout.Has(s))
+
+	timeout.Set(s, time.Second)
+	value, ok := timeout.Get(s)
+	require.True(t, ok)
+	assert.Equal(t, time.Second, value)
+	assert.Equal(t, time.Second, timeout.MustGet(s))
+	assert.True(t, timeout.Has(s))
+
+	// Value of another type is not visible through the key
+	s.Set("test-key-timeout", 5)
+	assert.False(t, timeout.Has(s))
+	assert.Zero(t, timeout.MustGet(s))
+	assert.False(t, timeout.Delete(s))
+
+	timeout.SetWithTTL(s, time.Minute, time.Hour)
+	_, ok = s.Deadline("test-key-timeout")
+	assert.True(t, ok)
+
+	assert.True(t, timeout.Delete(s))
+	assert.False(t, s.Has("test-key-timeout"))
+}
+
+func TestNewKey_Registry(t *testing.T) {
+	NewKey[string]("test-key-name")
+
+	assert.NotPanics(t, func() {
+		NewKey[string]("test-key-name")
+	})
+	assert.PanicsWithError(t, "memkey: key test-key-name already declared with type string, not int", func() {
+		NewKey[int]("test-key-name")
+	})
+
+	// Keys of different types are different keys
+	NewKey[string](1)
+	assert.NotPanics(t, func() {
+		NewKey[int](int64(1))
+	})
+
+	NewKey[fmt.Stringer]("test-key-stringer")
+	assert.Panics(t, func() {
+		NewKey[time.Duration]("test-key-stringer")
+	})
+
+	assert.EqualError(t, declaredKeys.declare(registeredKey{key: []int{1}}, nil),
+		"memkey: key of type []int is not comparable")
+}
+
+func TestKey_Release(t *testing.T) {
+	key := NewKey[string]("test-key-release")
+	assert.Panics(t, func() {
+		NewKey[int]("test-key-release")
+	})
+
+	key.Release()
+	assert.NotPanics(t, func() {
+		NewKey[int]("test-key-release")
+	})
+
+	// Key declared with another type is not released
+	key.Release()
+	assert.Panics(t, func() {
+		NewKey[string]("test-key-release")
+	})
+}
+
+func TestKey_Interface(t *testing.T) {
+	s := &Store[string]{}
+	key := NewKey[fmt.Stringer]("test-key-interface")
+
+	s.Set("test-key-interface", time.Second)
+	value, ok := key.Get(s)
+	require.True(t, ok)
+	assert.Equal(t, "1s", value.String())
+}
@@ -0,0 +1,275 @@
+package memkey
+
+import "time"
+
+// KV represents key-value store with values of a single type, it's implemented by TypedStore, Store (with any values)
+// and View, so they can be used interchangeably
+type KV[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, value V)
+	Has(key K) bool
+	Delete(key K) bool
+	Len() int
+	Keys() []K
+}
+
+// ExpiringKV represents KV that supports values with TTL
+type ExpiringKV[K comparable, V any] interface {
+	KV[K, V]
+	SetWithTTL(key K, value V, ttl time.Duration)
+	Deadline(key K) (time.Time, bool)
+	Persist(key K) bool
+}
+
+// IterableKV represents KV that supports iteration over all values
+type IterableKV[K comparable, V any] interface {
+	KV[K, V]
+	Values() []V
+	Entries() []Entry[K, V]
+	ForEach(f func(key K, value V) (stop bool))
+}
+
+// BatchKV represents KV that supports operations on many values at once
+type BatchKV[K comparable, V any] interface {
+	KV[K, V]
+	GetMany(keys ...K) map[K]V
+	SetMany(values map[K]V)
+	DeleteMany(keys ...K) int
+}
+
+// TypedKV represents KV with all features of TypedStore that don't depend on its own storage, it's implemented by
+// TypedStore and View, so a view of Store can be used where a separate store is expected
+type TypedKV[K comparable, V any] interface {
+	ExpiringKV[K, V]
+	IterableKV[K, V]
+	BatchKV[K, V]
+	TrySet(key K, value V) error
+	TrySetWithTTL(key K, value V, ttl time.Duration) error
+	Scan(cursor uint64, count int) ([]K, uint64)
+	Watch(config WatchConfig[K, V]) *Watcher[K, V]
+	WatchFunc(config WatchConfig[K, V], f func(event Event[K, V])) *Watcher[K, V]
+	Query() *Query[K, V]
+}
+
+var (
+	_ TypedKV[int, int] = (*TypedStore[int, int])(nil)
+	_ TypedKV[int, int] = (*View[int, int])(nil)
+
+	_ ExpiringKV[int, int] = (*TypedStore[int, int])(nil)
+	_ IterableKV[int, int] = (*TypedStore[int, int])(nil)
+	_ BatchKV[int, int]    = (*TypedStore[int, int])(nil)
+
+	_ ExpiringKV[int, int] = (*OrderedStore[int, int])(nil)
+	_ IterableKV[int, int] = (*OrderedStore[int, int])(nil)
+	_ BatchKV[int, int]    = (*OrderedStore[int, int])(nil)
+
+	_ ExpiringKV[int, any] = (*Store[int])(nil)
+	_ IterableKV[int, any] = (*Store[int])(nil)
+
+	_ ExpiringKV[int, int] = (*View[int, int])(nil)
+	_ IterableKV[int, int] = (*View[int, int])(nil)
+	_ BatchKV[int, int]    = (*View[int, int])(nil)
+)
+
+// View represents values of a single type stored in Store, all methods see only values of that type, so values of
+// other types are neither returned nor deleted, but Set replaces them unless key type is declared (see Declare).
+// View has no state of its own, changes of the store are visible to the view immediately, and it's safe to use
+// concurrently with the store
+type View[K comparable, V any] struct {
+	store *Store[K]
+}
+
+// NewView creates view of values with a specified type stored in the store
+func NewView[V any, K comparable](store *Store[K]) *View[K, V] {
+	return &View[K, V]{store: store}
+}
+
+// Get returns a value stored in the store if it exists with view's type, or zero value and false
+func (v *View[K, V]) Get(key K) (V, bool) {
+	return Get[V](v.store, key)
+}
+
+// Set stores value in the store, previously set TTL of the key is removed
+func (v *View[K, V]) Set(key K, value V) {
+	v.store.Set(key, value)
+}
+
+// TrySet stores value in the store, previously set TTL of the key is removed, returns error if write is rejected
+func (v *View[K, V]) TrySet(key K, value V) error {
+	return v.store.TrySet(key, value)
+}
+
+// SetWithTTL stores value in the store with TTL, expiration happens only if ExpireTTL of the store was called
+func (v *View[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	v.store.SetWithTTL(key, value, ttl)
+}
+
+// TrySetWithTTL stores value in the store with TTL, returns error if write is rejected
+func (v *View[K, V]) TrySetWithTTL(key K, value V, ttl time.Duration) error {
+	return v.store.TrySetWithTTL(key, value, ttl)
+}
+
+// Deadline returns time when value expires, or zero time if value has no TTL, if not found with view's type returns
+// false
+func (v *View[K, V]) Deadline(key K) (time.Time, bool) {
+	v.store.lock.RLock()
+	defer v.store.lock.RUnlock()
+
+	if _, ok := v.store.data[key].(V); !ok {
+		return time.Time{}, false
+	}
+
+	return v.store.ttl[key], true
+}
+
+// Persist removes TTL of value and returns true, if not found with view's type or value has no TTL returns false
+func (v *View[K, V]) Persist(key K) bool {
+	v.store.lock.Lock()
+	defer v.store.lock.Unlock()
+
+	value, ok := v.store.data[key].(V)
+	if !ok || v.store.lifecycle.closed {
+		return false
+	}
+
+	if _, ok = v.store.ttl[key]; !ok {
+		return false
+	}
+
+	delete(v.store.ttl, key)
+	v.store.record(EventSet, key, value, time.Time{})
+	return true
+}
+
+// Has returns true if value exists in the store with view's type
+func (v *View[K, V]) Has(key K) bool {
+	return Has[V](v.store, key)
+}
+
+// Delete deletes value from the store if it exists with view's type and returns true, if not found returns false
+func (v *View[K, V]) Delete(key K) bool {
+	return Delete[V](v.store, key)
+}
+
+// GetMany returns values with the specified keys that exist in the store with view's type
+func (v *View[K, V]) GetMany(keys ...K) map[K]V {
+	v.store.lock.RLock()
+	defer v.store.lock.RUnlock()
+
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, ok := v.store.data[key].(V); ok {
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
+// SetMany stores all values in the store at once, previously set TTL of the keys is removed. Values which writes
+// are rejected by declared types of keys are not stored
+func (v *View[K, V]) SetMany(values map[K]V) {
+	s := v.store
+	s.lock.Lock()
+
+	s.init.Do(func() {
+		if s.data == nil {
+			s.data = make(map[K]any)
+		}
+	})
+
+	oldValues := make(map[K]any, len(values))
+	for key, value := range values {
+		if err := s.checkWrite(key, value); err != nil {
+			continue
+		}
+
+		oldValues[key] = s.data[key]
+		s.data[key] = value
+		delete(s.ttl, key)
+		s.record(EventSet, key, value, time.Time{})
+	}
+	s.lock.Unlock()
+
+	for key, oldValue := range oldValues {
+		s.emit(EventSet, key, oldValue, values[key])
+	}
+}
+
+// DeleteMany deletes values with the specified keys that exist with view's type from the store at once and returns
+// number of deleted values
+func (v *View[K, V]) DeleteMany(keys ...K) int {
+	s := v.store
+	s.lock.Lock()
+
+	deleted := make([]Entry[K, any], 0, len(keys))
+	for _, key := range keys {
+		value, ok := s.data[key]
+		if _, isV := value.(V); !ok || !isV || s.lifecycle.closed {
+			continue
+		}
+
+		delete(s.data, key)
+		delete(s.ttl, key)
+		s.record(EventDelete, key, nil, time.Time{})
+		deleted = append(deleted, Entry[K, any]{Key: key, Value: value})
+	}
+	s.lock.Unlock()
+
+	for _, entry := range deleted {
+		s.emit(EventDelete, entry.Key, entry.Value, nil)
+	}
+
+	return len(deleted)
+}
+
+// Len returns number of values with view's type
+func (v *View[K, V]) Len() int {
+	return Len[V](v.store)
+}
+
+// Keys returns keys of values with view's type, no order is expected
+func (v *View[K, V]) Keys() []K {
+	return Keys[V](v.store)
+}
+
+// Values returns values with view's type, no order is expected
+func (v *View[K, V]) Values() []V {
+	return Values[V](v.store)
+}
+
+// Entries returns entries (key-value pairs) with view's type
+func (v *View[K, V]) Entries() []Entry[K, V] {
+	return Entries[V](v.store)
+}
+
+// ForEach goes in loop through all values with view's type and calls f with a key and value, values are collected
+// first and f is called without the store lock held, so it can modify the store, changes are not visible to the loop
+func (v *View[K, V]) ForEach(f func(key K, value V) (stop bool)) {
+	for _, entry := range v.Entries() {
+		if f(entry.Key, entry.Value) {
+			return
+		}
+	}
+}
+
+// Scan returns page of keys of values with view's type and cursor of the next page, see Scan
+func (v *View[K, V]) Scan(cursor uint64, count int) ([]K, uint64) {
+	return Scan[V](v.store, cursor, count)
+}
+
+// Watch subscribes to changes of values with view's type, watcher should be closed when it is no longer needed
+func (v *View[K, V]) Watch(config WatchConfig[K, V]) *Watcher[K, V] {
+	return Watch(v.store, config)
+}
+
+// WatchFunc subscribes to changes of values with view's type and calls f with each event in a separate goroutine
+// until watcher is closed
+func (v *View[K, V]) WatchFunc(config WatchConfig[K, V], f func(event Event[K, V])) *Watcher[K, V] {
+	return WatchFunc(v.store, config, f)
+}
+
+// Query creates query of values with view's type
+func (v *View[K, V]) Query() *Query[K, V] {
+	return NewQuery[V](v.store)
+}
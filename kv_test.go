@@ -0,0 +1,158 @@
+package memkey
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKV(t *testing.T, kv KV[string, int]) {
+	t.Helper()
+
+	_, ok := kv.Get("a")
+	assert.False(t, ok)
+
+	kv.Set("a", 1)
+	kv.Set("b", 2)
+
+	value, ok := kv.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.True(t, kv.Has("b"))
+	assert.Equal(t, 2, kv.Len())
+	assert.ElementsMatch(t, []string{"a", "b"}, kv.Keys())
+
+	assert.True(t, kv.Delete("a"))
+	assert.False(t, kv.Delete("a"))
+	assert.Equal(t, 1, kv.Len())
+}
+
+func TestKV(t *testing.T) {
+	testKV(t, &TypedStore[string, int]{})
+	testKV(t, NewView[int](&Store[string]{}))
+}
+
+func TestView(t *testing.T) {
+	s := &Store[string]{}
+	v := NewView[int](s)
+
+	Set(s, "a", 1)
+	Set(s, "b", "text")
+	v.SetWithTTL("c", 3, time.Minute)
+
+	assert.Equal(t, 2, v.Len())
+	assert.ElementsMatch(t, []string{"a", "c"}, v.Keys())
+	assert.ElementsMatch(t, []int{1, 3}, v.Values())
+	assert.ElementsMatch(t, []Entry[string, int]{{Key: "a", Value: 1}, {Key: "c", Value: 3}}, v.Entries())
+
+	count := 0
+	v.ForEach(func(key string, value int) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 2, count)
+
+	assert.False(t, v.Has("b"))
+	assert.False(t, v.Delete("b"))
+	assert.True(t, s.Has("b"))
+
+	_, ok := v.Deadline("b")
+	assert.False(t, ok)
+	deadline, ok := v.Deadline("c")
+	assert.True(t, ok)
+	assert.False(t, deadline.IsZero())
+
+	assert.True(t, v.Persist("c"))
+	assert.False(t, v.Persist("c"))
+
+	// Store can be modified by f
+	v.ForEach(func(key string, value int) bool {
+		v.Set(key, value*10)
+		return false
+	})
+	assert.ElementsMatch(t, []int{10, 30}, v.Values())
+
+	s.Set("a", "replaced")
+	assert.False(t, v.Has("a"))
+}
+
+func testTypedKV(t *testing.T, kv TypedKV[string, int]) {
+	t.Helper()
+
+	kv.SetMany(map[string]int{"a": 1, "b": 2, "c": 3})
+	assert.Equal(t, map[string]int{"a": 1, "c": 3}, kv.GetMany("a", "c", "d"))
+	require.NoError(t, kv.TrySet("d", 4))
+	require.NoError(t, kv.TrySetWithTTL("e", 5, time.Minute))
+
+	keys, cursor := kv.Scan(0, 100)
+	assert.Zero(t, cursor)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e"}, keys)
+	assert.Equal(t, 2, kv.Query().Where(func(_ string, value int) bool { return value > 3 }).Count())
+
+	w := kv.Watch(WatchConfig[string, int]{})
+	kv.Set("f", 6)
+	event := <-w.Events()
+	assert.Equal(t, EventSet, event.Kind)
+	assert.Equal(t, 6, event.Value)
+	w.Close()
+
+	assert.Equal(t, 2, kv.DeleteMany("a", "b", "x"))
+	assert.Equal(t, 4, kv.Len())
+}
+
+func TestTypedKV(t *testing.T) {
+	testTypedKV(t, &TypedStore[string, int]{})
+	testTypedKV(t, NewView[int](&Store[string]{}))
+}
+
+func TestView_Batch(t *testing.T) {
+	s := &Store[string]{}
+	v := NewView[int](s)
+
+	s.Set("a", "text")
+	require.NoError(t, Declare[string](s, "b"))
+
+	v.SetMany(map[string]int{"a": 1, "b": 2})
+	assert.Equal(t, 1, s.MustGet("a"))
+	assert.False(t, s.Has("b"))
+	require.ErrorIs(t, v.TrySet("b", 2), ErrTypeMismatch)
+
+	s.Set("c", "text")
+	assert.Equal(t, 1, v.DeleteMany("a", "c"))
+	assert.True(t, s.Has("c"))
+}
+
+func TestView_Concurrent(t *testing.T) {
+	s := &Store[string]{}
+	v := NewView[int](s)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := strconv.Itoa(i*100 + j)
+				s.Set(key, j)
+				s.Set("text"+key, key)
+				s.Delete(key)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				v.ForEach(func(_ string, _ int) bool { return false })
+				v.Len()
+				v.Entries()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Zero(t, v.Len())
+	assert.Equal(t, 400, Len[string](s))
+}
@@ -0,0 +1,335 @@
+package memkey
+
+import (
+	"sync"
+	"time"
+)
+
+// lifecycle represents state of the store that can be closed, closed is changed and workers are started while the
+// store lock is held
+type lifecycle struct {
+	closed bool
+	// done is closed when the store is closed, it's created by the first worker
+	done    chan struct{}
+	workers sync.WaitGroup
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// startWorker registers background worker and returns channel that is closed when worker should stop, if the store
+// is closed returns false. Must be called while the store lock is held
+func (l *lifecycle) startWorker() (<-chan struct{}, bool) {
+	if l.closed {
+		return nil, false
+	}
+
+	if l.done == nil {
+		l.done = make(chan struct{})
+	}
+	l.workers.Add(1)
+
+	return l.done, true
+}
+
+// stopWorkers stops background workers and waits for them to finish, must be called after closed is set and without
+// the store lock held
+func (l *lifecycle) stopWorkers() {
+	if l.done != nil {
+		close(l.done)
+	}
+	l.workers.Wait()
+}
+
+// journalCloser represents journal with its own resources (for example, AOF or replication leader) that is closed
+// together with the store
+type journalCloser interface {
+	Close() error
+}
+
+// closeJournals closes journals that have their own resources and returns the first error
+func closeJournals[K comparable, V any](journals []journal[K, V]) error {
+	var err error
+	for _, j := range journals {
+		if closer, ok := j.(journalCloser); ok {
+			if closeErr := closer.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	}
+	return err
+}
+
+// checkWrite returns error if value can't be stored with key, must be called while the store lock is held
+func (s *Store[K]) checkWrite(key K, value any) error {
+	if s.lifecycle.closed {
+		return ErrClosed
+	}
+	// Journals are checked first, because in strict mode checkSchema declares type of accepted value
+	if err := checkJournals(s.journals, key, value); err != nil {
+		return err
+	}
+	return s.checkSchema(key, value)
+}
+
+// Clear deletes all values and their TTL from the store at once and returns number of deleted values, changes are
+// recorded by AOF, replication and indexes, but hooks and watchers are not notified, see ClearWithHooks
+func (s *Store[K]) Clear() int {
+	return s.clear(nil, false)
+}
+
+// ClearWithHooks deletes all values like Clear, but also calls evict hooks and notifies watchers with EventEvict about
+// every value
+func (s *Store[K]) ClearWithHooks() int {
+	return s.clear(nil, true)
+}
+
+// Clear deletes all values with a specified type from the store at once and returns number of deleted values, see
+// Store.Clear
+func Clear[V any, K comparable](store *Store[K]) int {
+	return store.clear(keysOfType[V, K], false)
+}
+
+// ClearWithHooks deletes all values with a specified type from the store like Clear, but also calls evict hooks and
+// notifies watchers with EventEvict about every value
+func ClearWithHooks[V any, K comparable](store *Store[K]) int {
+	return store.clear(keysOfType[V, K], true)
+}
+
+// keysOfType returns keys of values with type V, must be called while the store lock is held
+func keysOfType[V any, K comparable](s *Store[K]) []K {
+	var keys []K
+	eachOfType(&s.types, s.data, func(key K, _ V) bool {
+		keys = append(keys, key)
+		return false
+	})
+	return keys
+}
+
+// clear deletes values with keys returned by keys func at once, if keys func is nil all values are deleted,
+// keys func is called while the store lock is held. If the store is closed nothing is deleted
+func (s *Store[K]) clear(keys func(s *Store[K]) []K, hooks bool) int {
+	s.lock.Lock()
+	deleted := s.deleteKeys(keys)
+	s.lock.Unlock()
+
+	switch {
+	case hooks:
+		for _, entry := range deleted {
+			s.emit(EventEvict, entry.Key, entry.Value, nil)
+		}
+	case len(deleted) > 0:
+		s.hooks.run(eventClear, zero[K](), nil, nil)
+	default:
+	}
+
+	return len(deleted)
+}
+
+// OnClear registers func that is called after values are deleted by Clear or Reset without calling hooks, and
+// returns a func that removes it. It's called in the goroutine that cleared the store after the store lock is
+// released, so it can be used to drop state that is kept for values by other hooks
+func (s *Store[K]) OnClear(f func()) (remove func()) {
+	return s.hooks.add(eventClear, func(K, any, any) { f() }, HookSync)
+}
+
+// deleteKeys deletes values like clear and returns deleted entries without notifying hooks and watchers, must be
+// called while the store lock is held
+func (s *Store[K]) deleteKeys(keys func(s *Store[K]) []K) []Entry[K, any] {
+	if s.lifecycle.closed {
+		return nil
+	}
+
+	all := keys == nil
+	var deleteKeys []K
+	if all {
+		deleteKeys = make([]K, 0, len(s.data))
+		for key := range s.data {
+			deleteKeys = append(deleteKeys, key)
+		}
+	} else {
+		deleteKeys = keys(s)
+	}
+
+	deleted := make([]Entry[K, any], 0, len(deleteKeys))
+	for _, key := range deleteKeys {
+		deleted = append(deleted, Entry[K, any]{Key: key, Value: s.data[key]})
+
+		delete(s.data, key)
+		delete(s.ttl, key)
+		s.record(EventDelete, key, nil, time.Time{})
+	}
+
+	// Maps don't shrink, so they are replaced to release memory
+	if all && s.data != nil {
+		s.data = make(map[K]any)
+		s.ttl = make(map[K]time.Time)
+	}
+
+	return deleted
+}
+
+// Reset deletes all values like Clear and removes declared types of keys, strict mode and settings and stats of
+// namespaces, so the store behaves like a new one, registered hooks, watchers and attached journals are kept.
+// Namespace handles created before Reset keep their settings and stats, but they are not shared with new handles.
+// If the store is closed nothing is changed
+func (s *Store[K]) Reset() {
+	s.lock.Lock()
+	if s.lifecycle.closed {
+		s.lock.Unlock()
+		return
+	}
+
+	deleted := s.deleteKeys(nil)
+	s.schema = nil
+	s.strict = false
+	s.namespaces = nil
+	s.lock.Unlock()
+
+	if len(deleted) > 0 {
+		s.hooks.run(eventClear, zero[K](), nil, nil)
+	}
+}
+
+// Close stops background workers started by ExpireTTL and waits for them, closes attached AOF logs (flushing them
+// to disk) and replication leaders. After Close values can still be read, but writes are ignored, TrySet,
+// TrySetWithTTL and counters return ErrClosed. It is safe to call Close multiple times, the same error is returned
+func (s *Store[K]) Close() error {
+	s.lifecycle.closeOnce.Do(func() {
+		s.lock.Lock()
+		s.lifecycle.closed = true
+		journals := append([]journal[K, any](nil), s.journals...)
+		s.lock.Unlock()
+
+		s.lifecycle.stopWorkers()
+		s.lifecycle.closeErr = closeJournals(journals)
+	})
+
+	return s.lifecycle.closeErr
+}
+
+// checkWrite returns error if value can't be stored with key, must be called while the store lock is held
+func (s *TypedStore[K, V]) checkWrite(key K, value V) error {
+	if s.lifecycle.closed {
+		return ErrClosed
+	}
+	if err := checkJournals(s.journals, key, value); err != nil {
+		return err
+	}
+	return s.checkIndexes(key, value)
+}
+
+// Clear deletes all values and their TTL from the store at once and returns number of deleted values, secondary
+// indexes are emptied, but kept. Changes are recorded by AOF, replication and indexes, but hooks and watchers are not
+// notified, see ClearWithHooks
+func (s *TypedStore[K, V]) Clear() int {
+	return s.clear(false)
+}
+
+// ClearWithHooks deletes all values like Clear, but also calls evict hooks and notifies watchers with EventEvict about
+// every value
+func (s *TypedStore[K, V]) ClearWithHooks() int {
+	return s.clear(true)
+}
+
+// clear deletes all values at once, if the store is closed nothing is deleted
+func (s *TypedStore[K, V]) clear(hooks bool) int {
+	s.lock.Lock()
+	deleted := s.deleteAll()
+	s.lock.Unlock()
+
+	switch {
+	case hooks:
+		for _, entry := range deleted {
+			s.emit(EventEvict, entry.Key, entry.Value, zero[V]())
+		}
+	case len(deleted) > 0:
+		s.hooks.run(eventClear, zero[K](), zero[V](), zero[V]())
+	default:
+	}
+
+	return len(deleted)
+}
+
+// OnClear registers func that is called after values are deleted by Clear or Reset without calling hooks, see
+// Store.OnClear
+func (s *TypedStore[K, V]) OnClear(f func()) (remove func()) {
+	return s.hooks.add(eventClear, func(K, V, V) { f() }, HookSync)
+}
+
+// deleteAll deletes all values like clear and returns deleted entries without notifying hooks and watchers, must be
+// called while the store lock is held
+func (s *TypedStore[K, V]) deleteAll() []Entry[K, V] {
+	if s.lifecycle.closed {
+		return nil
+	}
+
+	deleted := make([]Entry[K, V], 0, len(s.data))
+	for key, value := range s.data {
+		deleted = append(deleted, Entry[K, V]{Key: key, Value: value})
+
+		delete(s.data, key)
+		delete(s.ttl, key)
+		s.record(EventDelete, key, zero[V](), time.Time{})
+	}
+
+	// Maps don't shrink, so they are replaced to release memory
+	if s.data != nil {
+		s.data = make(map[K]V)
+		s.ttl = make(map[K]time.Time)
+	}
+
+	return deleted
+}
+
+// Reset deletes all values like Clear and removes secondary indexes, so the store behaves like a new one,
+// registered hooks, watchers and attached journals are kept. If the store is closed nothing is changed
+func (s *TypedStore[K, V]) Reset() {
+	s.lock.Lock()
+	if s.lifecycle.closed {
+		s.lock.Unlock()
+		return
+	}
+
+	deleted := s.deleteAll()
+	s.indexes = nil
+	s.lock.Unlock()
+
+	if len(deleted) > 0 {
+		s.hooks.run(eventClear, zero[K](), zero[V](), zero[V]())
+	}
+}
+
+// Close stops background workers started by ExpireTTL and waits for them, closes attached AOF logs (flushing them
+// to disk) and replication leaders. After Close values can still be read, but writes are ignored, TrySet,
+// TrySetWithTTL and counters return ErrClosed. It is safe to call Close multiple times, the same error is returned
+func (s *TypedStore[K, V]) Close() error {
+	s.lifecycle.closeOnce.Do(func() {
+		s.lock.Lock()
+		s.lifecycle.closed = true
+		journals := append([]journal[K, V](nil), s.journals...)
+		s.lock.Unlock()
+
+		s.lifecycle.stopWorkers()
+		s.lifecycle.closeErr = closeJournals(journals)
+	})
+
+	return s.lifecycle.closeErr
+}
+
+// Close stops background workers started by ExpireTTL and waits for them, closes attached AOF logs (flushing them
+// to disk) and replication leaders. After Close values can still be read, but writes are ignored and Load returns
+// ErrClosed. It is safe to call Close multiple times, the same error is returned
+func (s *OrderedStore[K, V]) Close() error {
+	s.lifecycle.closeOnce.Do(func() {
+		s.lock.Lock()
+		s.lifecycle.closed = true
+		journals := append([]journal[K, V](nil), s.journals...)
+		s.lock.Unlock()
+
+		s.lifecycle.stopWorkers()
+		s.lifecycle.closeErr = closeJournals(journals)
+	})
+
+	return s.lifecycle.closeErr
+}
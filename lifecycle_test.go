@@ -0,0 +1,258 @@
+package memkey
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Clear(t *testing.T) {
+	s := &Store[string]{}
+	s.Set("a", 1)
+	s.SetWithTTL("b", "text", time.Hour)
+	s.Set("c", 2)
+
+	keys, _ := s.Scan(0, 1)
+	require.Len(t, keys, 1)
+
+	deleted, evicted := 0, 0
+	s.OnDelete(func(_ string, _, _ any) {
+		deleted++
+	}, HookSync)
+	s.OnEvict(func(_ string, _, _ any) {
+		evicted++
+	}, HookSync)
+	cleared := 0
+	s.OnClear(func() {
+		cleared++
+	})
+
+	assert.Equal(t, 2, Clear[int](s))
+	assert.Equal(t, []string{"b"}, s.Keys())
+	assert.Zero(t, Len[int](s))
+
+	assert.Equal(t, 1, s.Clear())
+	assert.Zero(t, s.Len())
+	assert.Zero(t, deleted)
+	assert.Empty(t, s.ttl)
+	assert.Empty(t, s.types.types)
+
+	keys, cursor := s.Scan(0, 10)
+	assert.Empty(t, keys)
+	assert.Zero(t, cursor)
+
+	s.Set("a", 1)
+	s.Set("b", "text")
+	assert.Equal(t, 1, ClearWithHooks[string](s))
+	assert.Equal(t, 1, s.ClearWithHooks())
+	assert.Equal(t, 2, evicted)
+	assert.Zero(t, deleted)
+
+	// Clear hooks are called only when values are deleted without hooks
+	assert.Equal(t, 2, cleared)
+	assert.Zero(t, s.Clear())
+	s.Set("a", 1)
+	s.Reset()
+	assert.Equal(t, 3, cleared)
+}
+
+func TestTypedStore_Clear(t *testing.T) {
+	s := &TypedStore[string, testSession]{}
+	require.NoError(t, s.AddUniqueIndex("email", func(session testSession) []IndexKey {
+		return []IndexKey{session.Email}
+	}))
+
+	s.Set("a", testSession{Email: "x"})
+	s.SetWithTTL("b", testSession{Email: "y"}, time.Hour)
+
+	evicted := 0
+	s.OnEvict(func(_ string, _, _ testSession) {
+		evicted++
+	}, HookSync)
+
+	assert.Equal(t, 2, s.Clear())
+	assert.Zero(t, s.Len())
+	assert.Zero(t, evicted)
+	assert.Empty(t, s.Lookup("email", "x"))
+
+	// Index is kept
+	require.NoError(t, s.TrySet("c", testSession{Email: "x"}))
+	require.ErrorIs(t, s.TrySet("d", testSession{Email: "x"}), ErrUniqueIndex)
+
+	w := s.Watch(WatchConfig[string, testSession]{})
+	defer w.Close()
+
+	assert.Equal(t, 1, s.ClearWithHooks())
+	assert.Equal(t, 1, evicted)
+	event := <-w.Events()
+	assert.Equal(t, EventEvict, event.Kind)
+	assert.Equal(t, "c", event.Key)
+
+	s.Set("e", testSession{Email: "z"})
+	s.Reset()
+	assert.Zero(t, s.Len())
+	assert.Nil(t, s.Lookup("email", "z"))
+	require.NoError(t, s.TrySet("f", testSession{Email: "z"}))
+	require.NoError(t, s.TrySet("g", testSession{Email: "z"}))
+
+	// Closed store keeps values and indexes
+	require.NoError(t, s.AddIndex("email", func(session testSession) []IndexKey {
+		return []IndexKey{session.Email}
+	}))
+	require.NoError(t, s.Close())
+	s.Reset()
+	assert.Equal(t, 2, s.Len())
+	assert.Len(t, s.Lookup("email", "z"), 2)
+}
+
+func TestStore_Reset(t *testing.T) {
+	s := &Store[string]{}
+	s.EnableSchema()
+	s.Set("a", 1)
+
+	deleted := 0
+	s.OnDelete(func(string, any, any) {
+		deleted++
+	}, HookSync)
+
+	s.Reset()
+	assert.Zero(t, s.Len())
+	assert.Zero(t, deleted)
+	require.NoError(t, s.TrySet("a", "text"))
+	require.NoError(t, s.TrySet("a", 1.5))
+
+	// Closed store keeps values and their declared types
+	require.NoError(t, Declare[float64](s, "a"))
+	require.NoError(t, s.Close())
+	s.Reset()
+	assert.Equal(t, 1.5, s.MustGet("a"))
+	assert.ErrorIs(t, Declare[string](s, "a"), ErrTypeMismatch)
+}
+
+func TestStore_Close(t *testing.T) {
+	s := &Store[string]{}
+	s.SetWithTTL("a", 1, time.Hour)
+
+	config := AOFConfig{Path: filepath.Join(t.TempDir(), "store.aof"), Fsync: FsyncNever}
+	aof, err := s.OpenAOF(config)
+	require.NoError(t, err)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.ExpireTTL(time.Millisecond, nil)
+		close(stopped)
+	}()
+
+	require.Eventually(t, func() bool {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+		return s.lifecycle.done != nil
+	}, time.Second, time.Millisecond)
+
+	s.Set("b", 2)
+	require.NoError(t, s.Close())
+	require.NoError(t, s.Close())
+	<-stopped
+
+	// AOF is closed together with the store
+	require.ErrorIs(t, aof.Sync(), ErrClosed)
+
+	// Values are readable, but can't be changed
+	assert.Equal(t, 2, s.MustGet("b"))
+	require.ErrorIs(t, s.TrySet("c", 3), ErrClosed)
+	require.ErrorIs(t, s.TrySetWithTTL("c", 3, time.Hour), ErrClosed)
+	_, err = Incr(s, "b", 1)
+	require.ErrorIs(t, err, ErrClosed)
+	s.Set("c", 3)
+	assert.False(t, s.Has("c"))
+	assert.False(t, s.Delete("b"))
+	assert.False(t, Delete[int](s, "b"))
+	assert.False(t, s.Persist("a"))
+	assert.Zero(t, s.Clear())
+	assert.Zero(t, NewView[int](s).DeleteMany("b"))
+	assert.Equal(t, 2, s.Len())
+
+	// Workers started after Close return immediately
+	s.ExpireTTL(time.Millisecond, nil)
+
+	restored := &Store[string]{}
+	restoredAOF, err := restored.OpenAOF(config)
+	require.NoError(t, err)
+	assert.Equal(t, 2, restored.MustGet("b"))
+	require.NoError(t, restoredAOF.Close())
+}
+
+func TestTypedStore_Close(t *testing.T) {
+	s := &TypedStore[string, int]{}
+	s.Set("a", 1)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.ExpireTTL(time.Millisecond, nil)
+		close(stopped)
+	}()
+
+	require.Eventually(t, func() bool {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+		return s.lifecycle.done != nil
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, s.Close())
+	<-stopped
+
+	require.ErrorIs(t, s.TrySet("b", 2), ErrClosed)
+	_, err := IncrTyped(s, "a", 1)
+	require.ErrorIs(t, err, ErrClosed)
+	s.SetMany(map[string]int{"c": 3})
+	assert.False(t, s.Has("c"))
+	assert.False(t, s.Delete("a"))
+	assert.Zero(t, s.DeleteMany("a"))
+
+	value, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+}
+
+func TestOrderedStore_Close(t *testing.T) {
+	s := NewOrderedStore[string, int]()
+	s.SetWithTTL("a", 1, time.Hour)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.ExpireTTL(time.Millisecond, nil)
+		close(stopped)
+	}()
+
+	require.Eventually(t, func() bool {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+		return s.lifecycle.done != nil
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, s.Close())
+	require.NoError(t, s.Close())
+	<-stopped
+
+	s.Set("b", 2)
+	s.SetMany(map[string]int{"c": 3})
+	require.ErrorIs(t, s.TrySet("b", 2), ErrClosed)
+	require.ErrorIs(t, s.TrySetWithTTL("b", 2, time.Hour), ErrClosed)
+	assert.False(t, s.Has("b"))
+	assert.False(t, s.Has("c"))
+	assert.False(t, s.Delete("a"))
+	assert.Zero(t, s.DeleteMany("a"))
+	assert.False(t, s.Persist("a"))
+
+	var buf bytes.Buffer
+	require.NoError(t, NewOrderedStore[string, int]().Save(&buf, JSONCodec{}))
+	assert.ErrorIs(t, s.Load(&buf, JSONCodec{}), ErrClosed)
+
+	value, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+}
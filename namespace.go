@@ -0,0 +1,324 @@
+package memkey
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// NamespaceSeparator separates names of namespaces and keys in keys of the parent store
+const NamespaceSeparator = ":"
+
+// ErrNamespaceKey returned when key of namespace contains NamespaceSeparator
+var ErrNamespaceKey = errors.New("memkey: namespace key contains separator")
+
+// Namespace represents isolated part of Store with string keys, values are stored in the parent store with keys
+// prefixed by the namespace name and NamespaceSeparator, so snapshots, AOF and replication of the store include
+// them. Keys of namespace can't contain NamespaceSeparator, so they never collide with keys of nested namespaces.
+// All handles of the same namespace share default TTL and stats
+type Namespace struct {
+	store  *Store[string]
+	name   string
+	prefix string
+	state  *namespaceState
+}
+
+// namespaceIndex represents index of keys of the store that contain NamespaceSeparator, it's attached to the store
+// as a journal, so it's updated while the store lock is held and must be read while the store lock is held
+type namespaceIndex struct {
+	tree radixTree[struct{}]
+}
+
+// record updates index with change of the store
+func (i *namespaceIndex) record(kind EventKind, key string, _ any, _ time.Time) {
+	if !strings.Contains(key, NamespaceSeparator) {
+		return
+	}
+
+	if kind == EventSet {
+		i.tree.insert(key, struct{}{})
+	} else {
+		i.tree.delete(key)
+	}
+}
+
+// walk calls f with keys of the store that start with prefix, if nested is false keys of nested namespaces are
+// skipped, f is called with keys without prefix
+func (i *namespaceIndex) walk(prefix string, nested bool, f func(key string)) {
+	i.tree.walkPrefix(prefix, func(key string, _ struct{}) bool {
+		key = key[len(prefix):]
+		if nested || !strings.Contains(key, NamespaceSeparator) {
+			f(key)
+		}
+		return false
+	})
+}
+
+// namespaceIndexOf returns index of namespace keys of the store, the index is created and attached to the store if
+// needed. Must be called while the store lock is held exclusively
+func namespaceIndexOf(store *Store[string]) *namespaceIndex {
+	if store.namespaceKeys != nil {
+		return store.namespaceKeys
+	}
+
+	index := &namespaceIndex{}
+	for key := range store.data {
+		index.record(EventSet, key, nil, time.Time{})
+	}
+
+	store.namespaceKeys = index
+	store.journals = append(store.journals, index)
+	return index
+}
+
+// namespaceState represents settings and counters shared by all handles of a namespace
+type namespaceState struct {
+	ttl atomic.Int64
+
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	sets    atomic.Uint64
+	deletes atomic.Uint64
+}
+
+// NamespaceStats represents counters of operations made through namespace handles, operations made directly on the
+// store are not counted
+type NamespaceStats struct {
+	// Hits is a number of Get calls that found value
+	Hits uint64
+	// Misses is a number of Get calls that didn't find value
+	Misses uint64
+	// Sets is a number of stored values
+	Sets uint64
+	// Deletes is a number of deleted values, including values deleted by Clear
+	Deletes uint64
+}
+
+// NewNamespace returns handle of namespace with the specified name in the store. It panics if name is empty or
+// contains NamespaceSeparator. Keys of the store that contain NamespaceSeparator belong to namespaces, so they should
+// be written only through namespace handles
+func NewNamespace(store *Store[string], name string) *Namespace {
+	return newNamespace(store, "", name)
+}
+
+// Namespace returns handle of nested namespace with the specified name, see NewNamespace
+func (n *Namespace) Namespace(name string) *Namespace {
+	return newNamespace(n.store, n.prefix, name)
+}
+
+func newNamespace(store *Store[string], parentPrefix, name string) *Namespace {
+	if name == "" || strings.Contains(name, NamespaceSeparator) {
+		panic(fmt.Sprintf("memkey: invalid namespace name %q", name))
+	}
+
+	fullName := parentPrefix + name
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	namespaceIndexOf(store)
+
+	state, ok := store.namespaces[fullName]
+	if !ok {
+		if store.namespaces == nil {
+			store.namespaces = make(map[string]*namespaceState)
+		}
+
+		state = &namespaceState{}
+		store.namespaces[fullName] = state
+	}
+
+	return &Namespace{
+		store:  store,
+		name:   fullName,
+		prefix: fullName + NamespaceSeparator,
+		state:  state,
+	}
+}
+
+// Name returns full name of the namespace, names of nested namespaces include names of their parents
+func (n *Namespace) Name() string {
+	return n.name
+}
+
+// Key returns key of the parent store that is used for key of the namespace, key must not contain NamespaceSeparator
+func (n *Namespace) Key(key string) string {
+	return n.prefix + key
+}
+
+// checkKey returns error if key can't be used in the namespace
+func checkKey(key string) error {
+	if strings.Contains(key, NamespaceSeparator) {
+		return fmt.Errorf("%w: %q", ErrNamespaceKey, key)
+	}
+	return nil
+}
+
+// SetDefaultTTL sets TTL of values stored by Set and TrySet, if TTL is not positive values don't expire
+func (n *Namespace) SetDefaultTTL(ttl time.Duration) {
+	n.state.ttl.Store(int64(ttl))
+}
+
+// DefaultTTL returns TTL of values stored by Set and TrySet
+func (n *Namespace) DefaultTTL() time.Duration {
+	return time.Duration(n.state.ttl.Load())
+}
+
+// Get returns a value stored in the namespace if it exists, or nil and false, if key contains NamespaceSeparator
+// returns nil and false
+func (n *Namespace) Get(key string) (any, bool) {
+	if checkKey(key) != nil {
+		return nil, false
+	}
+
+	value, ok := n.store.Get(n.prefix + key)
+	if ok {
+		n.state.hits.Add(1)
+	} else {
+		n.state.misses.Add(1)
+	}
+	return value, ok
+}
+
+// Set stores value in the namespace with default TTL of the namespace, value is not stored if key contains
+// NamespaceSeparator or if value is rejected by declared type of the key, use TrySet to get an error
+func (n *Namespace) Set(key string, value any) {
+	_ = n.TrySet(key, value)
+}
+
+// TrySet stores value in the namespace with default TTL of the namespace, returns error if write is rejected,
+// ErrNamespaceKey is returned if key contains NamespaceSeparator
+func (n *Namespace) TrySet(key string, value any) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	if ttl := n.DefaultTTL(); ttl > 0 {
+		return n.TrySetWithTTL(key, value, ttl)
+	}
+
+	if err := n.store.TrySet(n.prefix+key, value); err != nil {
+		return err
+	}
+
+	n.state.sets.Add(1)
+	return nil
+}
+
+// SetWithTTL stores value in the namespace with TTL, expiration happens only if ExpireTTL of the store was called
+func (n *Namespace) SetWithTTL(key string, value any, ttl time.Duration) {
+	_ = n.TrySetWithTTL(key, value, ttl)
+}
+
+// TrySetWithTTL stores value in the namespace with TTL, returns error if write is rejected, see TrySet
+func (n *Namespace) TrySetWithTTL(key string, value any, ttl time.Duration) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	if err := n.store.TrySetWithTTL(n.prefix+key, value, ttl); err != nil {
+		return err
+	}
+
+	n.state.sets.Add(1)
+	return nil
+}
+
+// Has returns true if value exists in the namespace
+func (n *Namespace) Has(key string) bool {
+	return checkKey(key) == nil && n.store.Has(n.prefix+key)
+}
+
+// Delete deletes value from the namespace and returns true, if not found returns false
+func (n *Namespace) Delete(key string) bool {
+	if checkKey(key) != nil || !n.store.Delete(n.prefix+key) {
+		return false
+	}
+
+	n.state.deletes.Add(1)
+	return true
+}
+
+// Len returns number of values stored in the namespace, values of nested namespaces are not counted
+func (n *Namespace) Len() int {
+	n.store.lock.RLock()
+	defer n.store.lock.RUnlock()
+
+	count := 0
+	n.store.namespaceKeys.walk(n.prefix, false, func(string) {
+		count++
+	})
+
+	return count
+}
+
+// Keys returns keys of values stored in the namespace without namespace prefix in lexicographic order, keys of
+// nested namespaces are not included
+func (n *Namespace) Keys() []string {
+	n.store.lock.RLock()
+	defer n.store.lock.RUnlock()
+
+	keys := make([]string, 0)
+	n.store.namespaceKeys.walk(n.prefix, false, func(key string) {
+		keys = append(keys, key)
+	})
+
+	return keys
+}
+
+// Clear deletes all values of the namespace at once and returns number of deleted values, values of nested
+// namespaces are kept. Evict hooks are called and watchers are notified with EventEvict about every value
+func (n *Namespace) Clear() int {
+	deleted := n.store.clear(func(s *Store[string]) []string {
+		var keys []string
+		s.namespaceKeys.walk(n.prefix, false, func(key string) {
+			keys = append(keys, n.prefix+key)
+		})
+		return keys
+	}, true)
+	n.state.deletes.Add(uint64(deleted))
+	return deleted
+}
+
+// Stats returns counters of operations made through handles of the namespace
+func (n *Namespace) Stats() NamespaceStats {
+	return NamespaceStats{
+		Hits:    n.state.hits.Load(),
+		Misses:  n.state.misses.Load(),
+		Sets:    n.state.sets.Load(),
+		Deletes: n.state.deletes.Load(),
+	}
+}
+
+// Namespaces returns sorted names of nested namespaces that contain values
+func (n *Namespace) Namespaces() []string {
+	return namespaces(n.store, n.prefix)
+}
+
+// Namespaces returns sorted names of top-level namespaces that contain values, any key that contains
+// NamespaceSeparator belongs to a namespace
+func Namespaces(store *Store[string]) []string {
+	return namespaces(store, "")
+}
+
+// namespaces returns sorted names of namespaces that contain values with keys that start with prefix
+func namespaces(store *Store[string], prefix string) []string {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	names := make([]string, 0)
+	namespaceIndexOf(store).walk(prefix, true, func(key string) {
+		// Keys are visited in lexicographic order, so keys of the same namespace are next to each other
+		if name, _, ok := strings.Cut(key, NamespaceSeparator); ok && name != "" &&
+			(len(names) == 0 || names[len(names)-1] != name) {
+			names = append(names, name)
+		}
+	})
+	// Names are not sorted yet if they contain bytes that are less than NamespaceSeparator
+	sort.Strings(names)
+
+	return names
+}
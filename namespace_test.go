@@ -0,0 +1,175 @@
+package memkey
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespace(t *testing.T) {
+	s := &Store[string]{}
+	auth := NewNamespace(s, "auth")
+	users := NewNamespace(s, "users")
+
+	auth.Set("id", 1)
+	users.Set("id", 2)
+	s.Set("id", 3)
+
+	value, ok := auth.Get("id")
+	require.True(t, ok)
+	assert.Equal(t, 1, value)
+	value, ok = users.Get("id")
+	require.True(t, ok)
+	assert.Equal(t, 2, value)
+	assert.Equal(t, 3, s.MustGet("id"))
+	assert.Equal(t, 1, s.MustGet("auth:id"))
+	assert.Equal(t, "auth:id", auth.Key("id"))
+
+	assert.Equal(t, 1, auth.Len())
+	assert.Equal(t, []string{"id"}, auth.Keys())
+	assert.True(t, auth.Has("id"))
+	assert.False(t, auth.Has("other"))
+
+	assert.True(t, users.Delete("id"))
+	assert.False(t, users.Delete("id"))
+	assert.Zero(t, users.Len())
+	assert.Empty(t, users.Keys())
+	assert.Equal(t, 2, s.Len())
+}
+
+func TestNamespace_Nested(t *testing.T) {
+	s := &Store[string]{}
+	auth := NewNamespace(s, "auth")
+	sessions := auth.Namespace("sessions")
+	assert.Equal(t, "auth:sessions", sessions.Name())
+
+	auth.Set("secret", "x")
+	sessions.Set("s1", 1)
+	sessions.Set("s2", 2)
+	auth.Namespace("tokens").Set("t1", 1)
+	NewNamespace(s, "billing").Set("plan", "free")
+	s.Set("plain", true)
+
+	assert.Equal(t, []string{"auth", "billing"}, Namespaces(s))
+	assert.Equal(t, []string{"sessions", "tokens"}, auth.Namespaces())
+	assert.Empty(t, sessions.Namespaces())
+
+	// Values of nested namespaces are not part of their parent
+	assert.Equal(t, 2, sessions.Len())
+	assert.Equal(t, []string{"s1", "s2"}, sessions.Keys())
+	assert.Equal(t, 1, auth.Len())
+	assert.Equal(t, []string{"secret"}, auth.Keys())
+
+	assert.Equal(t, 1, auth.Clear())
+	assert.Equal(t, 2, sessions.Len())
+	assert.Equal(t, 2, sessions.Clear())
+	assert.Equal(t, 3, s.Len())
+	assert.Equal(t, []string{"auth", "billing"}, Namespaces(s))
+	assert.Equal(t, []string{"tokens"}, auth.Namespaces())
+
+	assert.Panics(t, func() { NewNamespace(s, "") })
+	assert.Panics(t, func() { auth.Namespace("a:b") })
+}
+
+func TestNamespace_Separator(t *testing.T) {
+	s := &Store[string]{}
+	ns := NewNamespace(s, "a")
+	ns.Namespace("b").Set("c", 1)
+
+	err := ns.TrySet("b:c", 2)
+	assert.ErrorIs(t, err, ErrNamespaceKey)
+	assert.EqualError(t, err, `memkey: namespace key contains separator: "b:c"`)
+	assert.ErrorIs(t, ns.TrySetWithTTL("b:c", 2, time.Hour), ErrNamespaceKey)
+	ns.Set("b:c", 2)
+
+	_, ok := ns.Get("b:c")
+	assert.False(t, ok)
+	assert.False(t, ns.Has("b:c"))
+	assert.False(t, ns.Delete("b:c"))
+	assert.Equal(t, 1, s.MustGet("a:b:c"))
+
+	// Keys of the store written before the namespace was created are indexed too
+	s.Set("auth:x", 1)
+	s.Set("plain", 2)
+	auth := NewNamespace(s, "auth")
+	assert.Equal(t, []string{"x"}, auth.Keys())
+	assert.Equal(t, []string{"a", "auth"}, Namespaces(s))
+}
+
+func TestNamespace_Reset(t *testing.T) {
+	s := &Store[string]{}
+	ns := NewNamespace(s, "cache")
+	ns.SetDefaultTTL(time.Hour)
+	ns.Set("a", 1)
+
+	s.Reset()
+	assert.Zero(t, ns.Len())
+	assert.Empty(t, Namespaces(s))
+
+	ns = NewNamespace(s, "cache")
+	assert.Zero(t, ns.DefaultTTL())
+	assert.Equal(t, NamespaceStats{}, ns.Stats())
+
+	ns.Set("b", 2)
+	assert.Equal(t, []string{"b"}, ns.Keys())
+}
+
+func TestNamespace_DefaultTTL(t *testing.T) {
+	s := &Store[string]{}
+	cache := NewNamespace(s, "cache")
+	cache.SetDefaultTTL(time.Hour)
+
+	// Handles of the same namespace share settings
+	assert.Equal(t, time.Hour, NewNamespace(s, "cache").DefaultTTL())
+
+	cache.Set("a", 1)
+	deadline, ok := s.Deadline("cache:a")
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+
+	cache.SetWithTTL("b", 2, time.Minute)
+	deadline, ok = s.Deadline("cache:b")
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second*10)
+
+	cache.SetDefaultTTL(0)
+	cache.Set("c", 3)
+	deadline, ok = s.Deadline("cache:c")
+	require.True(t, ok)
+	assert.True(t, deadline.IsZero())
+}
+
+func TestNamespace_Stats(t *testing.T) {
+	s := &Store[string]{}
+	ns := NewNamespace(s, "stats")
+
+	ns.Set("a", 1)
+	ns.Set("b", 2)
+	ns.Get("a")
+	ns.Get("c")
+	ns.Delete("a")
+	NewNamespace(s, "stats").Clear()
+
+	require.NoError(t, Declare[int](s, ns.Key("d")))
+	require.ErrorIs(t, ns.TrySet("d", "text"), ErrTypeMismatch)
+
+	assert.Equal(t, NamespaceStats{Hits: 1, Misses: 1, Sets: 2, Deletes: 2}, ns.Stats())
+}
+
+func TestNamespace_Snapshot(t *testing.T) {
+	s := &Store[string]{}
+	NewNamespace(s, "auth").Namespace("sessions").Set("s1", "token")
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, s.Save(buf, JSONCodec{}))
+
+	restored := &Store[string]{}
+	require.NoError(t, restored.Load(buf, JSONCodec{}))
+
+	value, ok := NewNamespace(restored, "auth").Namespace("sessions").Get("s1")
+	require.True(t, ok)
+	assert.Equal(t, "token", value)
+}
@@ -0,0 +1,665 @@
+package memkey
+
+import (
+	"fmt"
+	"io"
+	"reflect" //nolint:depguard // Keys of zero value store are compared by their kinds
+	"sync"
+	"time"
+)
+
+// Ordered is a constraint that permits any ordered type: any type that supports the operators < <= >= >
+type Ordered interface {
+	Integer | Float | ~string
+}
+
+// compareOrdered returns -1 if a is less than b, 1 if a is greater than b and 0 if they are equal
+func compareOrdered[K Ordered](a, b K) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// defaultCompare returns compare func of keys with integer, float or string kinds, or nil for other kinds
+func defaultCompare[K comparable]() func(a, b K) int {
+	typ := reflect.TypeOf((*K)(nil)).Elem()
+
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(a, b K) int {
+			return compareOrdered(reflect.ValueOf(a).Int(), reflect.ValueOf(b).Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(a, b K) int {
+			return compareOrdered(reflect.ValueOf(a).Uint(), reflect.ValueOf(b).Uint())
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(a, b K) int {
+			return compareOrdered(reflect.ValueOf(a).Float(), reflect.ValueOf(b).Float())
+		}
+	case reflect.String:
+		return func(a, b K) int {
+			return compareOrdered(reflect.ValueOf(a).String(), reflect.ValueOf(b).String())
+		}
+	default:
+		return nil
+	}
+}
+
+// OrderedStore represents key-value storage that keeps keys sorted, it's type-safe and thread-safe to use.
+// OrderedStore should be created with NewOrderedStore or NewOrderedStoreFunc, zero value is ready to use only for
+// keys with integer, float or string kinds, it compares keys using reflection and panics on the first write of
+// keys of other kinds
+type OrderedStore[K comparable, V any] struct {
+	tree        btree[K, V]
+	lock        sync.RWMutex
+	initCompare sync.Once
+
+	initTTL sync.Once
+	ttl     map[K]time.Time
+
+	watchers  watchers[K, V]
+	hooks     hooks[K, V]
+	journals  []journal[K, V]
+	lifecycle lifecycle
+}
+
+// NewOrderedStore creates store that keeps keys sorted in their natural order
+func NewOrderedStore[K Ordered, V any]() *OrderedStore[K, V] {
+	return NewOrderedStoreFunc[K, V](compareOrdered[K])
+}
+
+// NewOrderedStoreFunc creates store that keeps keys sorted using compare func, it must return a negative number
+// if a is less than b, a positive number if a is greater than b and zero if they are equal
+func NewOrderedStoreFunc[K comparable, V any](compare func(a, b K) int) *OrderedStore[K, V] {
+	return &OrderedStore[K, V]{
+		tree: btree[K, V]{compare: compare},
+	}
+}
+
+// checkCompare sets default compare func of zero value store, it panics if keys have no default order. It's called
+// by writes before the store lock is acquired, so reads never use compare func of empty tree before it's set
+func (s *OrderedStore[K, V]) checkCompare() {
+	s.initCompare.Do(func() {
+		if s.tree.compare == nil {
+			s.tree.compare = defaultCompare[K]()
+		}
+	})
+
+	if s.tree.compare == nil {
+		panic(fmt.Sprintf("memkey: keys of type %s are not ordered, use NewOrderedStoreFunc",
+			reflect.TypeOf((*K)(nil)).Elem()))
+	}
+}
+
+// Get return value stored in the store if it exists, or zero value and false
+func (s *OrderedStore[K, V]) Get(key K) (V, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tree.get(key)
+}
+
+// Set stores value in the store, previously set TTL of the key is removed. Value is not stored if write is rejected
+// (for example, by AOF), use TrySet to get an error
+func (s *OrderedStore[K, V]) Set(key K, value V) {
+	_ = s.TrySet(key, value)
+}
+
+// TrySet stores value in the store, previously set TTL of the key is removed, returns error if write is rejected
+func (s *OrderedStore[K, V]) TrySet(key K, value V) error {
+	return s.setWithDeadline(key, value, time.Time{})
+}
+
+// SetWithTTL stores value in the store with TTL, expiration happens only if ExpireTTL was called. Value is not
+// stored if write is rejected, use TrySetWithTTL to get an error
+func (s *OrderedStore[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	_ = s.setWithDeadline(key, value, time.Now().Add(ttl))
+}
+
+// TrySetWithTTL stores value in the store with TTL, returns error if write is rejected
+func (s *OrderedStore[K, V]) TrySetWithTTL(key K, value V, ttl time.Duration) error {
+	return s.setWithDeadline(key, value, time.Now().Add(ttl))
+}
+
+// setWithDeadline stores value in the store that expires at deadline, if deadline is zero TTL of the key is removed
+func (s *OrderedStore[K, V]) setWithDeadline(key K, value V, deadline time.Time) error {
+	s.checkCompare()
+	s.lock.Lock()
+
+	s.initTTL.Do(func() {
+		if s.ttl == nil {
+			s.ttl = make(map[K]time.Time)
+		}
+	})
+
+	if err := s.checkWrite(key, value); err != nil {
+		s.lock.Unlock()
+		return err
+	}
+
+	oldValue, _ := s.tree.set(key, value)
+	if deadline.IsZero() {
+		delete(s.ttl, key)
+	} else {
+		s.ttl[key] = deadline
+	}
+	s.record(EventSet, key, value, deadline)
+	s.lock.Unlock()
+
+	s.emit(EventSet, key, oldValue, value)
+	return nil
+}
+
+// checkWrite returns error if value can't be stored with key, must be called while the store lock is held
+func (s *OrderedStore[K, V]) checkWrite(key K, value V) error {
+	if s.lifecycle.closed {
+		return ErrClosed
+	}
+	return checkJournals(s.journals, key, value)
+}
+
+// ExpireTTL run check for TTL in specified time, and if expired func not nil it will be called with removed item,
+// expired func is called after the store lock is released, so it can safely call back into the store, except Close.
+// ExpireTTL returns when the store is closed
+func (s *OrderedStore[K, V]) ExpireTTL(check time.Duration, expired func(key K, value V)) {
+	s.initTTL.Do(func() {
+		if s.ttl == nil {
+			s.ttl = make(map[K]time.Time)
+		}
+	})
+
+	s.lock.Lock()
+	done, ok := s.lifecycle.startWorker()
+	s.lock.Unlock()
+	if !ok {
+		return
+	}
+	defer s.lifecycle.workers.Done()
+
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case <-done:
+			return
+		case now = <-ticker.C:
+		}
+
+		s.lock.Lock()
+
+		var entries []Entry[K, V]
+		for k, v := range s.ttl {
+			if v.Before(now) {
+				value, _ := s.tree.delete(k)
+				entries = append(entries, Entry[K, V]{Key: k, Value: value})
+
+				delete(s.ttl, k)
+				s.record(EventExpire, k, zero[V](), time.Time{})
+			}
+		}
+
+		s.lock.Unlock()
+
+		for _, entry := range entries {
+			if expired != nil {
+				expired(entry.Key, entry.Value)
+			}
+
+			s.emit(EventExpire, entry.Key, entry.Value, zero[V]())
+		}
+	}
+}
+
+// Deadline returns time when value expires, or zero time if value has no TTL, if not found returns false
+func (s *OrderedStore[K, V]) Deadline(key K) (time.Time, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if _, ok := s.tree.get(key); !ok {
+		return time.Time{}, false
+	}
+
+	return s.ttl[key], true
+}
+
+// Persist removes TTL of value and returns true, if not found or value has no TTL returns false
+func (s *OrderedStore[K, V]) Persist(key K) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.ttl[key]; !ok || s.lifecycle.closed {
+		return false
+	}
+
+	delete(s.ttl, key)
+	value, _ := s.tree.get(key)
+	s.record(EventSet, key, value, time.Time{})
+	return true
+}
+
+// Has returns a true if value with the specified key exists in the store
+func (s *OrderedStore[K, V]) Has(key K) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	_, ok := s.tree.get(key)
+	return ok
+}
+
+// Delete deletes value from the store and returns true or if not found reruns false
+func (s *OrderedStore[K, V]) Delete(key K) bool {
+	s.lock.Lock()
+
+	if s.lifecycle.closed {
+		s.lock.Unlock()
+		return false
+	}
+
+	value, ok := s.tree.delete(key)
+	if !ok {
+		s.lock.Unlock()
+		return false
+	}
+
+	delete(s.ttl, key)
+	s.record(EventDelete, key, zero[V](), time.Time{})
+	s.lock.Unlock()
+
+	s.emit(EventDelete, key, value, zero[V]())
+	return true
+}
+
+// GetMany returns values stored with the specified keys, keys that are not found are omitted
+func (s *OrderedStore[K, V]) GetMany(keys ...K) map[K]V {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, ok := s.tree.get(key); ok {
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
+// SetMany stores all values in the store at once, previously set TTL of the keys is removed
+func (s *OrderedStore[K, V]) SetMany(values map[K]V) {
+	s.checkCompare()
+	s.lock.Lock()
+
+	if s.lifecycle.closed {
+		s.lock.Unlock()
+		return
+	}
+
+	oldValues := make(map[K]V, len(values))
+	for key, value := range values {
+		if err := checkJournals(s.journals, key, value); err != nil {
+			continue
+		}
+
+		oldValues[key], _ = s.tree.set(key, value)
+		delete(s.ttl, key)
+		s.record(EventSet, key, value, time.Time{})
+	}
+	s.lock.Unlock()
+
+	for key, oldValue := range oldValues {
+		s.emit(EventSet, key, oldValue, values[key])
+	}
+}
+
+// DeleteMany deletes values with the specified keys from the store at once and returns number of deleted values
+func (s *OrderedStore[K, V]) DeleteMany(keys ...K) int {
+	s.lock.Lock()
+
+	if s.lifecycle.closed {
+		s.lock.Unlock()
+		return 0
+	}
+
+	deleted := make([]Entry[K, V], 0, len(keys))
+	for _, key := range keys {
+		value, ok := s.tree.delete(key)
+		if !ok {
+			continue
+		}
+
+		delete(s.ttl, key)
+		s.record(EventDelete, key, zero[V](), time.Time{})
+		deleted = append(deleted, Entry[K, V]{Key: key, Value: value})
+	}
+	s.lock.Unlock()
+
+	for _, entry := range deleted {
+		s.emit(EventDelete, entry.Key, entry.Value, zero[V]())
+	}
+
+	return len(deleted)
+}
+
+// Len returns number of values that are stored
+func (s *OrderedStore[K, V]) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tree.len()
+}
+
+// Keys returns keys of all values that are stored in ascending order
+func (s *OrderedStore[K, V]) Keys() []K {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	keys := make([]K, 0, s.tree.len())
+	s.tree.ascend(nil, nil, func(key K, _ V) bool {
+		keys = append(keys, key)
+		return false
+	})
+
+	return keys
+}
+
+// Values returns all values that are stored in ascending order of their keys
+func (s *OrderedStore[K, V]) Values() []V {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	values := make([]V, 0, s.tree.len())
+	s.tree.ascend(nil, nil, func(_ K, value V) bool {
+		values = append(values, value)
+		return false
+	})
+
+	return values
+}
+
+// Entries returns entries (key-value pairs) that are stored in ascending order of their keys
+func (s *OrderedStore[K, V]) Entries() []Entry[K, V] {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	entries := make([]Entry[K, V], 0, s.tree.len())
+	s.tree.ascend(nil, nil, func(key K, value V) bool {
+		entries = append(entries, Entry[K, V]{Key: key, Value: value})
+		return false
+	})
+
+	return entries
+}
+
+// Range returns entries with keys greater than or equal to from and less than to in ascending order
+func (s *OrderedStore[K, V]) Range(from, to K) []Entry[K, V] {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var entries []Entry[K, V]
+	s.tree.ascend(&from, &to, func(key K, value V) bool {
+		entries = append(entries, Entry[K, V]{Key: key, Value: value})
+		return false
+	})
+
+	return entries
+}
+
+// ForEach goes in loop through all values in ascending order of their keys and calls f with a key and value,
+// f is called while the store read lock is held, so it must not modify the store
+func (s *OrderedStore[K, V]) ForEach(f func(key K, value V) (stop bool)) {
+	s.Ascend(f)
+}
+
+// Ascend calls f with all values in ascending order of their keys until f returns true, f is called while the
+// store read lock is held, so it must not modify the store
+func (s *OrderedStore[K, V]) Ascend(f func(key K, value V) (stop bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.tree.ascend(nil, nil, f)
+}
+
+// AscendFrom calls f with values with keys greater than or equal to from in ascending order until f returns true,
+// f is called while the store read lock is held, so it must not modify the store
+func (s *OrderedStore[K, V]) AscendFrom(from K, f func(key K, value V) (stop bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.tree.ascend(&from, nil, f)
+}
+
+// Descend calls f with all values in descending order of their keys until f returns true, f is called while the
+// store read lock is held, so it must not modify the store
+func (s *OrderedStore[K, V]) Descend(f func(key K, value V) (stop bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.tree.descend(nil, nil, f)
+}
+
+// DescendFrom calls f with values with keys less than or equal to from in descending order until f returns true,
+// f is called while the store read lock is held, so it must not modify the store
+func (s *OrderedStore[K, V]) DescendFrom(from K, f func(key K, value V) (stop bool)) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.tree.descend(&from, nil, f)
+}
+
+// Min returns entry with the smallest key, if store is empty returns false
+func (s *OrderedStore[K, V]) Min() (Entry[K, V], bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tree.min()
+}
+
+// Max returns entry with the largest key, if store is empty returns false
+func (s *OrderedStore[K, V]) Max() (Entry[K, V], bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tree.max()
+}
+
+// Floor returns entry with the largest key less than or equal to key, if there is no such key returns false
+func (s *OrderedStore[K, V]) Floor(key K) (Entry[K, V], bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tree.floor(key)
+}
+
+// Ceiling returns entry with the smallest key greater than or equal to key, if there is no such key returns false
+func (s *OrderedStore[K, V]) Ceiling(key K) (Entry[K, V], bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tree.ceiling(key)
+}
+
+// Rank returns number of keys less than key, i.e. index of key in sorted order if it is stored
+func (s *OrderedStore[K, V]) Rank(key K) int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tree.rank(key)
+}
+
+// Select returns entry at index in sorted order, if index is out of range returns false
+func (s *OrderedStore[K, V]) Select(index int) (Entry[K, V], bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.tree.selectAt(index)
+}
+
+// Watch subscribes to changes in the store, watcher should be closed when it is no longer needed
+func (s *OrderedStore[K, V]) Watch(config WatchConfig[K, V]) *Watcher[K, V] {
+	watcher := newWatcher(config)
+	watcher.unsubscribe = s.watchers.subscribe(watcher)
+	return watcher
+}
+
+// WatchFunc subscribes to changes in the store and calls f with each event in a separate goroutine until
+// watcher is closed
+func (s *OrderedStore[K, V]) WatchFunc(config WatchConfig[K, V], f func(event Event[K, V])) *Watcher[K, V] {
+	watcher := s.Watch(config)
+	go watcher.handle(f)
+	return watcher
+}
+
+// Save writes snapshot of all values and their TTL deadlines to w using specified codec, snapshot has the same
+// format as snapshot of TypedStore
+func (s *OrderedStore[K, V]) Save(w io.Writer, codec Codec) error {
+	return writeSnapshot(w, codec, snapshotFormatTyped, s.snapshotEntries(nil))
+}
+
+// Load reads snapshot from r using specified codec and stores all values that are not expired yet,
+// existing values with the same keys are replaced
+func (s *OrderedStore[K, V]) Load(r io.Reader, codec Codec) error {
+	entries, err := readSnapshot[K, V](r, codec, snapshotFormatTyped)
+	if err != nil {
+		return err
+	}
+
+	s.checkCompare()
+	s.lock.Lock()
+	if s.lifecycle.closed {
+		s.lock.Unlock()
+		return ErrClosed
+	}
+	oldValues := s.restoreEntries(entries)
+	s.lock.Unlock()
+
+	for i, entry := range entries {
+		s.emit(EventSet, entry.Key, oldValues[i], entry.Value)
+	}
+
+	return nil
+}
+
+// OpenAOF replays append-only log into the store and records all following changes of the store to it,
+// values are replayed without calling hooks and watchers, a truncated or corrupted tail of the log is discarded.
+// If the store has values that are not in the log, the log is rewritten with all values of the store.
+// Writes of values that can't be encoded are rejected, TrySet and TrySetWithTTL return the error
+func (s *OrderedStore[K, V]) OpenAOF(config AOFConfig) (*AOF[K, V], error) {
+	return openAOF[K, V](s, typedValues[V]{}, config)
+}
+
+// snapshotEntries returns all values with their TTL deadlines, if during func not nil it will be called while
+// the store lock is still held
+func (s *OrderedStore[K, V]) snapshotEntries(during func()) []snapshotEntry[K, V] {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	entries := make([]snapshotEntry[K, V], 0, s.tree.len())
+	s.tree.ascend(nil, nil, func(key K, value V) bool {
+		entry := snapshotEntry[K, V]{
+			Key:   key,
+			Value: value,
+		}
+
+		if deadline, ok := s.ttl[key]; ok {
+			entry.Deadline = &deadline
+		}
+
+		entries = append(entries, entry)
+		return false
+	})
+
+	if during != nil {
+		during()
+	}
+
+	return entries
+}
+
+// restoreEntries stores values with their TTL deadlines and returns replaced values, must be called while the store
+// lock is held
+func (s *OrderedStore[K, V]) restoreEntries(entries []snapshotEntry[K, V]) []V {
+	s.initTTL.Do(func() {
+		if s.ttl == nil {
+			s.ttl = make(map[K]time.Time)
+		}
+	})
+
+	oldValues := make([]V, len(entries))
+	for i, entry := range entries {
+		oldValues[i], _ = s.tree.set(entry.Key, entry.Value)
+
+		deadline := time.Time{}
+		if entry.Deadline != nil {
+			deadline = *entry.Deadline
+			s.ttl[entry.Key] = deadline
+		} else {
+			delete(s.ttl, entry.Key)
+		}
+
+		s.record(EventSet, entry.Key, entry.Value, deadline)
+	}
+
+	return oldValues
+}
+
+// attachJournal restores values and attaches journal that records all following changes, returns a func that
+// detaches the journal and number of values stored after restoring
+func (s *OrderedStore[K, V]) attachJournal(
+	entries []snapshotEntry[K, V], j journal[K, V],
+) (detach func(), stored int) {
+	s.checkCompare()
+	s.lock.Lock()
+	s.restoreEntries(entries)
+	s.journals = append(s.journals, j)
+	stored = s.tree.len()
+	s.lock.Unlock()
+
+	return func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.journals = removeJournal(s.journals, j)
+	}, stored
+}
+
+// record writes change to all attached journals, must be called while the store lock is held
+func (s *OrderedStore[K, V]) record(kind EventKind, key K, value V, deadline time.Time) {
+	for _, j := range s.journals {
+		j.record(kind, key, value, deadline)
+	}
+}
+
+// OnSet registers hook that is called when value is stored and returns a func that removes the hook
+func (s *OrderedStore[K, V]) OnSet(hook Hook[K, V], mode HookMode) (remove func()) {
+	return s.hooks.add(EventSet, hook, mode)
+}
+
+// OnDelete registers hook that is called when value is deleted and returns a func that removes the hook
+func (s *OrderedStore[K, V]) OnDelete(hook Hook[K, V], mode HookMode) (remove func()) {
+	return s.hooks.add(EventDelete, hook, mode)
+}
+
+// OnExpire registers hook that is called when value is removed because of TTL and returns a func that removes the hook
+func (s *OrderedStore[K, V]) OnExpire(hook Hook[K, V], mode HookMode) (remove func()) {
+	return s.hooks.add(EventExpire, hook, mode)
+}
+
+// emit calls hooks and notifies watchers about the change, must not be called while store lock is held
+func (s *OrderedStore[K, V]) emit(kind EventKind, key K, oldValue, newValue V) {
+	s.hooks.run(kind, key, oldValue, newValue)
+
+	value := newValue
+	if kind != EventSet {
+		value = oldValue
+	}
+
+	s.watchers.notify(Event[K, V]{Kind: kind, Key: key, Value: value})
+}
@@ -0,0 +1,200 @@
+package memkey
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedStore(t *testing.T) {
+	s := NewOrderedStore[string, int]()
+
+	_, ok := s.Min()
+	assert.False(t, ok)
+
+	for i, key := range []string{"d", "b", "a", "e", "c"} {
+		s.Set(key, i)
+	}
+	s.Set("a", 10)
+
+	value, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 10, value)
+	assert.Equal(t, 5, s.Len())
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, s.Keys())
+	assert.Equal(t, []int{10, 1, 4, 0, 3}, s.Values())
+
+	assert.Equal(t, []Entry[string, int]{{Key: "b", Value: 1}, {Key: "c", Value: 4}}, s.Range("aa", "d"))
+	assert.Empty(t, s.Range("x", "z"))
+
+	var keys []string
+	s.AscendFrom("bb", func(key string, _ int) bool {
+		keys = append(keys, key)
+		return key == "d"
+	})
+	assert.Equal(t, []string{"c", "d"}, keys)
+
+	keys = nil
+	s.DescendFrom("cc", func(key string, _ int) bool {
+		keys = append(keys, key)
+		return false
+	})
+	assert.Equal(t, []string{"c", "b", "a"}, keys)
+
+	keys = nil
+	s.Descend(func(key string, _ int) bool {
+		keys = append(keys, key)
+		return len(keys) == 2
+	})
+	assert.Equal(t, []string{"e", "d"}, keys)
+
+	minEntry, ok := s.Min()
+	assert.True(t, ok)
+	assert.Equal(t, Entry[string, int]{Key: "a", Value: 10}, minEntry)
+	maxEntry, ok := s.Max()
+	assert.True(t, ok)
+	assert.Equal(t, "e", maxEntry.Key)
+
+	floor, ok := s.Floor("cc")
+	assert.True(t, ok)
+	assert.Equal(t, "c", floor.Key)
+	_, ok = s.Floor("0")
+	assert.False(t, ok)
+
+	ceiling, ok := s.Ceiling("cc")
+	assert.True(t, ok)
+	assert.Equal(t, "d", ceiling.Key)
+	_, ok = s.Ceiling("f")
+	assert.False(t, ok)
+
+	assert.Equal(t, 2, s.Rank("c"))
+	assert.Equal(t, 3, s.Rank("cc"))
+	selected, ok := s.Select(2)
+	assert.True(t, ok)
+	assert.Equal(t, "c", selected.Key)
+	_, ok = s.Select(5)
+	assert.False(t, ok)
+
+	assert.True(t, s.Delete("c"))
+	assert.False(t, s.Delete("c"))
+	assert.False(t, s.Has("c"))
+	assert.Equal(t, map[string]int{"a": 10}, s.GetMany("a", "c"))
+	s.SetMany(map[string]int{"f": 5, "g": 6})
+	assert.Equal(t, 2, s.DeleteMany("a", "f", "z"))
+	assert.Equal(t, []string{"b", "d", "e", "g"}, s.Keys())
+}
+
+func TestOrderedStore_Comparator(t *testing.T) {
+	s := NewOrderedStoreFunc[string, int](func(a, b string) int {
+		return strings.Compare(strings.ToLower(b), strings.ToLower(a))
+	})
+
+	s.Set("a", 1)
+	s.Set("C", 2)
+	s.Set("b", 3)
+
+	assert.Equal(t, []string{"C", "b", "a"}, s.Keys())
+
+	var entries []Entry[string, int]
+	s.ForEach(func(key string, value int) bool {
+		entries = append(entries, Entry[string, int]{Key: key, Value: value})
+		return false
+	})
+	assert.Equal(t, s.Entries(), entries)
+}
+
+func TestOrderedStore_ZeroValue(t *testing.T) {
+	type name string
+
+	names := &OrderedStore[name, int]{}
+	names.Set("b", 2)
+	names.Set("a", 1)
+	names.Set("c", 3)
+	assert.Equal(t, []name{"a", "b", "c"}, names.Keys())
+
+	numbers := &OrderedStore[int8, int]{}
+	numbers.SetMany(map[int8]int{3: 3, -1: -1, 2: 2})
+	assert.Equal(t, []int8{-1, 2, 3}, numbers.Keys())
+
+	unordered := &OrderedStore[struct{}, int]{}
+	assert.Empty(t, unordered.Keys())
+	assert.Panics(t, func() { unordered.Set(struct{}{}, 1) })
+	// Store isn't left locked after panic
+	assert.Zero(t, unordered.Len())
+}
+
+func TestOrderedStore_TTL(t *testing.T) {
+	s := NewOrderedStore[int, string]()
+
+	expired := make(chan int, 1)
+	go s.ExpireTTL(time.Millisecond, func(key int, _ string) {
+		expired <- key
+	})
+
+	s.Set(1, "a")
+	s.SetWithTTL(2, "b", time.Millisecond*2)
+	s.SetWithTTL(3, "c", time.Hour)
+
+	select {
+	case key := <-expired:
+		assert.Equal(t, 2, key)
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "timeout")
+	}
+
+	assert.Equal(t, []int{1, 3}, s.Keys())
+
+	deadline, ok := s.Deadline(3)
+	assert.True(t, ok)
+	assert.False(t, deadline.IsZero())
+	assert.True(t, s.Persist(3))
+	assert.False(t, s.Persist(3))
+	deadline, ok = s.Deadline(3)
+	assert.True(t, ok)
+	assert.True(t, deadline.IsZero())
+}
+
+func TestOrderedStore_Persistence(t *testing.T) {
+	s := NewOrderedStore[int, string]()
+
+	var deleted []int
+	s.OnDelete(func(key int, _, _ string) {
+		deleted = append(deleted, key)
+	}, HookSync)
+
+	s.Set(2, "b")
+	s.SetWithTTL(1, "a", time.Hour)
+	s.Delete(2)
+	assert.Equal(t, []int{2}, deleted)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, s.Save(buf, JSONCodec{}))
+
+	// Snapshot of ordered store can be loaded into typed store
+	typed := &TypedStore[int, string]{}
+	require.NoError(t, typed.Load(bytes.NewReader(buf.Bytes()), JSONCodec{}))
+	assert.Equal(t, []Entry[int, string]{{Key: 1, Value: "a"}}, typed.Entries())
+
+	loaded := NewOrderedStore[int, string]()
+	require.NoError(t, loaded.Load(buf, JSONCodec{}))
+	assert.Equal(t, s.Entries(), loaded.Entries())
+
+	path := filepath.Join(t.TempDir(), "ordered.aof")
+
+	aof, err := loaded.OpenAOF(AOFConfig{Path: path})
+	require.NoError(t, err)
+	loaded.Set(3, "c")
+	loaded.Delete(1)
+	require.NoError(t, aof.Close())
+
+	replayed := NewOrderedStore[int, string]()
+	aof, err = replayed.OpenAOF(AOFConfig{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, aof.Close())
+	assert.Equal(t, []Entry[int, string]{{Key: 3, Value: "c"}}, replayed.Entries())
+}
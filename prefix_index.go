@@ -0,0 +1,174 @@
+package memkey
+
+import (
+	"sync"
+	"time"
+)
+
+// PrefixIndex represents index of string keys of the store that makes prefix and glob scans proportional to the
+// number of matching keys instead of all keys. Index is kept up to date with all changes of the store until it's
+// closed, keys are returned in lexicographic order
+type PrefixIndex[V any] struct {
+	lock sync.RWMutex
+	tree radixTree[V]
+	// touched contains keys changed while index is being built, it's nil after that
+	touched map[string]struct{}
+
+	remove func(key string) bool
+	detach func()
+}
+
+// NewPrefixIndex creates index of keys of the store, index should be closed when it is no longer needed
+func NewPrefixIndex(store *Store[string]) *PrefixIndex[any] {
+	return newPrefixIndex[any](store, store.Delete)
+}
+
+// NewTypedPrefixIndex creates index of keys of the store, index should be closed when it is no longer needed
+func NewTypedPrefixIndex[V any](store *TypedStore[string, V]) *PrefixIndex[V] {
+	return newPrefixIndex[V](store, store.Delete)
+}
+
+func newPrefixIndex[V any](source journalSource[string, V], remove func(key string) bool) *PrefixIndex[V] {
+	index := &PrefixIndex[V]{remove: remove}
+	index.detach, _ = source.attachJournal(nil, index)
+
+	// Changes recorded before snapshot are already in it, so they are discarded, changes recorded after snapshot
+	// take precedence over it
+	entries := source.snapshotEntries(func() {
+		index.lock.Lock()
+		index.tree = radixTree[V]{}
+		index.touched = make(map[string]struct{})
+		index.lock.Unlock()
+	})
+
+	index.lock.Lock()
+	for _, entry := range entries {
+		if _, ok := index.touched[entry.Key]; !ok {
+			index.tree.insert(entry.Key, entry.Value)
+		}
+	}
+	index.touched = nil
+	index.lock.Unlock()
+
+	return index
+}
+
+// record updates index with change of the store
+func (p *PrefixIndex[V]) record(kind EventKind, key string, value V, _ time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if kind == EventSet {
+		p.tree.insert(key, value)
+	} else {
+		p.tree.delete(key)
+	}
+
+	if p.touched != nil {
+		p.touched[key] = struct{}{}
+	}
+}
+
+// Len returns number of indexed keys
+func (p *PrefixIndex[V]) Len() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.tree.len()
+}
+
+// ScanPrefix returns all keys that start with prefix
+func (p *PrefixIndex[V]) ScanPrefix(prefix string) []string {
+	var keys []string
+	p.scan(prefix, nil, func(key string, _ V) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// ScanPrefixEntries returns entries (key-value pairs) with keys that start with prefix
+func (p *PrefixIndex[V]) ScanPrefixEntries(prefix string) []Entry[string, V] {
+	var entries []Entry[string, V]
+	p.scan(prefix, nil, func(key string, value V) {
+		entries = append(entries, Entry[string, V]{Key: key, Value: value})
+	})
+	return entries
+}
+
+// Match returns all keys that match glob pattern, see MatchGlob for pattern syntax. Only keys that start with
+// literal prefix of pattern are checked, so patterns that start with a wildcard scan all keys
+func (p *PrefixIndex[V]) Match(pattern string) []string {
+	var keys []string
+	p.scan(globPrefix(pattern), matchGlob(pattern), func(key string, _ V) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// MatchEntries returns entries (key-value pairs) with keys that match glob pattern, see Match
+func (p *PrefixIndex[V]) MatchEntries(pattern string) []Entry[string, V] {
+	var entries []Entry[string, V]
+	p.scan(globPrefix(pattern), matchGlob(pattern), func(key string, value V) {
+		entries = append(entries, Entry[string, V]{Key: key, Value: value})
+	})
+	return entries
+}
+
+// DeletePrefix deletes all values with keys that start with prefix from the store and returns number of deleted
+// values, values are deleted one by one, so values stored concurrently with the call may survive it
+func (p *PrefixIndex[V]) DeletePrefix(prefix string) int {
+	deleted := 0
+	for _, key := range p.ScanPrefix(prefix) {
+		if p.remove(key) {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Close stops updating index, it is safe to call Close multiple times
+func (p *PrefixIndex[V]) Close() {
+	p.detach()
+}
+
+// scan calls f with keys that start with prefix and are accepted by match func, if match func is not nil
+func (p *PrefixIndex[V]) scan(prefix string, match func(key string) bool, f func(key string, value V)) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	p.tree.walkPrefix(prefix, func(key string, value V) bool {
+		if match == nil || match(key) {
+			f(key, value)
+		}
+		return false
+	})
+}
+
+// matchGlob returns a match func that accepts keys matching glob pattern
+func matchGlob(pattern string) func(key string) bool {
+	return func(key string) bool {
+		return MatchGlob(pattern, key)
+	}
+}
+
+// ScanPrefix returns entries (key-value pairs) with keys that start with prefix and values of the specified type
+func ScanPrefix[V any](index *PrefixIndex[any], prefix string) []Entry[string, V] {
+	var entries []Entry[string, V]
+	index.scan(prefix, nil, func(key string, rawValue any) {
+		if value, ok := rawValue.(V); ok {
+			entries = append(entries, Entry[string, V]{Key: key, Value: value})
+		}
+	})
+	return entries
+}
+
+// Match returns entries (key-value pairs) with keys that match glob pattern and values of the specified type
+func Match[V any](index *PrefixIndex[any], pattern string) []Entry[string, V] {
+	var entries []Entry[string, V]
+	index.scan(globPrefix(pattern), matchGlob(pattern), func(key string, rawValue any) {
+		if value, ok := rawValue.(V); ok {
+			entries = append(entries, Entry[string, V]{Key: key, Value: value})
+		}
+	})
+	return entries
+}
@@ -0,0 +1,93 @@
+package memkey
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixIndex(t *testing.T) {
+	s := &TypedStore[string, int]{}
+	s.Set("user:1:name", 1)
+	s.Set("user:1:email", 2)
+
+	index := NewTypedPrefixIndex(s)
+	defer index.Close()
+
+	s.Set("user:2:name", 3)
+	s.Set("user:10:name", 4)
+	s.Set("order:1", 5)
+	s.Delete("user:1:email")
+
+	assert.Equal(t, 4, index.Len())
+	assert.Equal(t, []string{"user:10:name", "user:1:name", "user:2:name"}, index.ScanPrefix("user:"))
+	assert.Equal(t, []string{"user:1:name"}, index.ScanPrefix("user:1:"))
+	assert.Empty(t, index.ScanPrefix("session:"))
+	assert.Equal(t, []Entry[string, int]{{Key: "order:1", Value: 5}}, index.ScanPrefixEntries("order"))
+
+	assert.Equal(t, []string{"user:10:name", "user:1:name", "user:2:name"}, index.Match("user:*:name"))
+	assert.Equal(t, []string{"user:1:name", "user:2:name"}, index.Match("user:?:name"))
+	assert.Equal(t, []Entry[string, int]{{Key: "order:1", Value: 5}}, index.MatchEntries("*:1"))
+	assert.Empty(t, index.Match(""))
+
+	assert.Equal(t, 3, index.DeletePrefix("user:"))
+	assert.Equal(t, []string{"order:1"}, s.Keys())
+	assert.Equal(t, 1, index.Len())
+
+	index.Close()
+	index.Close()
+	s.Set("user:3:name", 6)
+	assert.Empty(t, index.ScanPrefix("user:"))
+}
+
+func TestPrefixIndex_Store(t *testing.T) {
+	s := &Store[string]{}
+	Set(s, "config:name", "memkey")
+	Set(s, "config:size", 42)
+
+	index := NewPrefixIndex(s)
+	defer index.Close()
+
+	SetWithTTL(s, "config:version", "1", time.Millisecond)
+
+	assert.Equal(t, []Entry[string, string]{
+		{Key: "config:name", Value: "memkey"},
+		{Key: "config:version", Value: "1"},
+	}, ScanPrefix[string](index, "config:"))
+	assert.Equal(t, []Entry[string, int]{{Key: "config:size", Value: 42}}, Match[int](index, "config:*"))
+
+	expired := make(chan struct{})
+	go s.ExpireTTL(time.Millisecond, func(string, any) { close(expired) })
+	<-expired
+
+	assert.Equal(t, []string{"config:name", "config:size"}, index.ScanPrefix("config:"))
+}
+
+func TestPrefixIndex_Concurrent(t *testing.T) {
+	s := &TypedStore[string, int]{}
+	for i := 0; i < 1000; i++ {
+		s.Set("key:"+strconv.Itoa(i), i)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			if i%2 == 0 {
+				s.Delete("key:" + strconv.Itoa(i))
+			} else {
+				s.Set("new:"+strconv.Itoa(i), i)
+			}
+		}
+	}()
+
+	index := NewTypedPrefixIndex(s)
+	defer index.Close()
+	wg.Wait()
+
+	assert.ElementsMatch(t, s.Keys(), index.ScanPrefix(""))
+}
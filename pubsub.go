@@ -0,0 +1,460 @@
+package memkey
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// MaxRemoteBuffer is a maximal number of messages buffered by the server for a remote subscriber, larger buffers
+// requested by subscribers are reduced to it
+const MaxRemoteBuffer = 4096
+
+// Message represents message published to a channel
+type Message[T any] struct {
+	Channel string
+	// Pattern is a subscription pattern that matched the channel
+	Pattern string
+	Payload T
+}
+
+// SubscribeConfig represents configuration of a subscription
+type SubscribeConfig struct {
+	// Buffer is a number of messages that can be buffered, if zero DefaultWatchBuffer is used
+	Buffer int
+	// Overflow is a policy to apply when buffer is full
+	Overflow OverflowPolicy
+}
+
+// PubSub represents publish/subscribe broker, messages are delivered to all subscriptions with a pattern matching
+// the channel, patterns are globs in MatchGlob syntax. PubSub is safe for concurrent use and zero value is ready to
+// use
+type PubSub[T any] struct {
+	lock          sync.RWMutex
+	subscriptions map[*Subscription[T]]struct{}
+	closed        bool
+
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+	done      chan struct{}
+
+	initOnce  sync.Once
+	closeOnce sync.Once
+}
+
+func (p *PubSub[T]) init() {
+	p.initOnce.Do(func() {
+		p.subscriptions = make(map[*Subscription[T]]struct{})
+		p.listeners = make(map[net.Listener]struct{})
+		p.conns = make(map[net.Conn]struct{})
+		p.done = make(chan struct{})
+	})
+}
+
+// Publish sends payload to all subscriptions matching the channel, returns number of subscriptions message was
+// delivered to, messages dropped because of buffer overflow are counted as delivered
+func (p *PubSub[T]) Publish(channel string, payload T) int {
+	p.init()
+
+	type delivery struct {
+		subscription *Subscription[T]
+		pattern      string
+	}
+
+	p.lock.RLock()
+	deliveries := make([]delivery, 0, len(p.subscriptions))
+	for subscription := range p.subscriptions {
+		if pattern, ok := subscription.match(channel); ok {
+			deliveries = append(deliveries, delivery{subscription: subscription, pattern: pattern})
+		}
+	}
+	p.lock.RUnlock()
+
+	// Messages are pushed without lock held, so blocked subscriptions don't block subscribing or closing
+	for _, d := range deliveries {
+		d.subscription.queue.push(Message[T]{Channel: channel, Pattern: d.pattern, Payload: payload})
+	}
+
+	return len(deliveries)
+}
+
+// Subscribe creates subscription to all channels matching any of patterns with default configuration
+func (p *PubSub[T]) Subscribe(patterns ...string) *Subscription[T] {
+	return p.SubscribeWithConfig(SubscribeConfig{}, patterns...)
+}
+
+// SubscribeWithConfig creates subscription to all channels matching any of patterns, if PubSub is closed returned
+// subscription is already closed
+func (p *PubSub[T]) SubscribeWithConfig(config SubscribeConfig, patterns ...string) *Subscription[T] {
+	p.init()
+
+	subscription := newSubscription[T](config, patterns)
+	subscription.unsubscribe = func() {
+		p.lock.Lock()
+		delete(p.subscriptions, subscription)
+		p.lock.Unlock()
+	}
+
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		subscription.Close()
+		return subscription
+	}
+	p.subscriptions[subscription] = struct{}{}
+	p.lock.Unlock()
+
+	return subscription
+}
+
+// Close closes all subscriptions, listeners and remote subscriber connections and waits for them to return, it is
+// safe to call Close multiple times
+func (p *PubSub[T]) Close() error {
+	p.init()
+
+	p.closeOnce.Do(func() {
+		p.lock.Lock()
+		p.closed = true
+		close(p.done)
+
+		subscriptions := make([]*Subscription[T], 0, len(p.subscriptions))
+		for subscription := range p.subscriptions {
+			subscriptions = append(subscriptions, subscription)
+		}
+		for listener := range p.listeners {
+			_ = listener.Close()
+		}
+		for conn := range p.conns {
+			_ = conn.Close()
+		}
+		p.lock.Unlock()
+
+		for _, subscription := range subscriptions {
+			subscription.Close()
+		}
+
+		p.wg.Wait()
+	})
+
+	return nil
+}
+
+// Subscription represents subscription to channels of PubSub
+type Subscription[T any] struct {
+	queue    *queue[Message[T]]
+	patterns []string
+
+	lock        sync.Mutex
+	err         error
+	closeOnce   sync.Once
+	unsubscribe func()
+}
+
+func newSubscription[T any](config SubscribeConfig, patterns []string) *Subscription[T] {
+	return &Subscription[T]{
+		queue:    newQueue[Message[T]](config.Buffer, config.Overflow),
+		patterns: append([]string(nil), patterns...),
+	}
+}
+
+// match returns the first pattern that matches channel
+func (s *Subscription[T]) match(channel string) (string, bool) {
+	for _, pattern := range s.patterns {
+		if MatchGlob(pattern, channel) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// Patterns returns patterns of subscription
+func (s *Subscription[T]) Patterns() []string {
+	return append([]string(nil), s.patterns...)
+}
+
+// Messages returns channel of messages, channel is closed when subscription is closed
+func (s *Subscription[T]) Messages() <-chan Message[T] {
+	return s.queue.items
+}
+
+// Dropped returns number of messages that were dropped because of buffer overflow
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.queue.dropped.Load()
+}
+
+// Err returns error that closed remote subscription, it's nil for local subscriptions and for subscriptions closed
+// by Close
+func (s *Subscription[T]) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.err
+}
+
+// Close stops delivery of messages and closes messages channel, it is safe to call Close multiple times
+func (s *Subscription[T]) Close() {
+	s.closeOnce.Do(func() {
+		// Queue is closed first to unblock publishers waiting for room in the buffer
+		s.queue.close()
+		if s.unsubscribe != nil {
+			s.unsubscribe()
+		}
+	})
+}
+
+// pubsubHello is the first message remote subscriber sends
+type pubsubHello struct {
+	Patterns []string       `json:"patterns"`
+	Buffer   int            `json:"buffer"`
+	Overflow OverflowPolicy `json:"overflow"`
+}
+
+// pubsubReady is sent in reply to hello once subscription is registered
+type pubsubReady struct{}
+
+// pubsubMessage is a message sent to remote subscriber
+type pubsubMessage[T any] struct {
+	Channel string `json:"channel"`
+	Pattern string `json:"pattern"`
+	Payload T      `json:"payload"`
+}
+
+// Serve accepts remote subscribers on listener until listener fails or PubSub is closed, listener is closed on
+// return. Subscribers must use the same codec, if codec is nil JSONCodec is used
+func (p *PubSub[T]) Serve(listener net.Listener, codec Codec) error {
+	p.init()
+
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		_ = listener.Close()
+		return ErrClosed
+	}
+	p.listeners[listener] = struct{}{}
+	p.lock.Unlock()
+
+	defer func() {
+		p.lock.Lock()
+		delete(p.listeners, listener)
+		p.lock.Unlock()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-p.done:
+				return ErrClosed
+			default:
+				return err
+			}
+		}
+
+		go func() { _ = p.ServeConn(conn, codec) }()
+	}
+}
+
+// ServeConn delivers messages to a single remote subscriber until connection fails or PubSub is closed, connection
+// is closed on return, returns nil if subscriber disconnected. Buffer requested by subscriber is limited by
+// MaxRemoteBuffer and OverflowBlock is replaced by OverflowDropOldest, so remote subscribers can't block publishers
+// or make the server buffer unlimited number of messages
+func (p *PubSub[T]) ServeConn(conn net.Conn, codec Codec) error {
+	p.init()
+
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		_ = conn.Close()
+		return ErrClosed
+	}
+	p.conns[conn] = struct{}{}
+	p.wg.Add(1)
+	p.lock.Unlock()
+
+	defer func() {
+		p.lock.Lock()
+		delete(p.conns, conn)
+		p.lock.Unlock()
+
+		_ = conn.Close()
+		p.wg.Done()
+	}()
+
+	r := bufio.NewReader(conn)
+
+	payload, err := readFrame(r)
+	if err != nil {
+		return fmt.Errorf("memkey: read subscriber hello: %w", err)
+	}
+
+	var hello pubsubHello
+	if err = codec.NewDecoder(bytes.NewReader(payload)).Decode(&hello); err != nil {
+		return fmt.Errorf("memkey: decode subscriber hello: %w", err)
+	}
+
+	config, err := remoteSubscribeConfig(hello)
+	if err != nil {
+		return err
+	}
+
+	subscription := p.SubscribeWithConfig(config, hello.Patterns...)
+	defer subscription.Close()
+
+	// Subscribers send nothing after hello, so reading fails only when subscriber disconnects
+	gone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, r)
+		close(gone)
+	}()
+
+	w := bufio.NewWriter(conn)
+	if err = writePubSubMessage(w, codec, &pubsubReady{}); err != nil {
+		return err
+	}
+
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("memkey: write subscriber message: %w", err)
+	}
+
+	for {
+		select {
+		case message, ok := <-subscription.Messages():
+			if !ok {
+				return ErrClosed
+			}
+
+			err = writePubSubMessage(w, codec, &pubsubMessage[T]{
+				Channel: message.Channel,
+				Pattern: message.Pattern,
+				Payload: message.Payload,
+			})
+			if err != nil {
+				return err
+			}
+
+			// Buffered messages are written in one batch before flushing
+			if len(subscription.Messages()) > 0 {
+				continue
+			}
+
+			if err = w.Flush(); err != nil {
+				return fmt.Errorf("memkey: write subscriber message: %w", err)
+			}
+		case <-gone:
+			return nil
+		case <-p.done:
+			return ErrClosed
+		}
+	}
+}
+
+func writePubSubMessage(w io.Writer, codec Codec, message any) error {
+	frame, err := encodeFrame(codec, message)
+	if err != nil {
+		return fmt.Errorf("memkey: encode subscriber message: %w", err)
+	}
+
+	if _, err = w.Write(frame); err != nil {
+		return fmt.Errorf("memkey: write subscriber message: %w", err)
+	}
+
+	return nil
+}
+
+// remoteSubscribeConfig returns configuration of subscription requested by remote subscriber, buffer is limited by
+// MaxRemoteBuffer and OverflowBlock is replaced by OverflowDropOldest
+func remoteSubscribeConfig(hello pubsubHello) (SubscribeConfig, error) {
+	if hello.Buffer < 0 {
+		return SubscribeConfig{}, fmt.Errorf("memkey: invalid subscriber buffer: %d", hello.Buffer)
+	}
+
+	config := SubscribeConfig{Buffer: hello.Buffer, Overflow: hello.Overflow}
+	if config.Buffer > MaxRemoteBuffer {
+		config.Buffer = MaxRemoteBuffer
+	}
+
+	switch config.Overflow {
+	case OverflowDropNewest, OverflowDropOldest:
+	case OverflowBlock:
+		config.Overflow = OverflowDropOldest
+	default:
+		return SubscribeConfig{}, fmt.Errorf("memkey: invalid subscriber overflow policy: %d", hello.Overflow)
+	}
+
+	return config, nil
+}
+
+// RemoteSubscribe subscribes to channels of PubSub served on the other end of connection, subscription owns
+// connection and closes it when subscription is closed. Config applies both to the local buffer and to the buffer
+// kept by the server, but the server limits its buffer by MaxRemoteBuffer and drops the oldest messages instead of
+// blocking publishers. If connection fails subscription is closed and Subscription.Err returns the error.
+// Codec must be the same as used by the server, if nil JSONCodec is used
+func RemoteSubscribe[T any](
+	conn net.Conn, codec Codec, config SubscribeConfig, patterns ...string,
+) (*Subscription[T], error) {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	hello := &pubsubHello{Patterns: patterns, Buffer: config.Buffer, Overflow: config.Overflow}
+	if err := writePubSubMessage(conn, codec, hello); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+
+	payload, err := readFrame(r)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("memkey: read subscription reply: %w", err)
+	}
+
+	if err = codec.NewDecoder(bytes.NewReader(payload)).Decode(&pubsubReady{}); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("memkey: decode subscription reply: %w", err)
+	}
+
+	subscription := newSubscription[T](config, patterns)
+	subscription.unsubscribe = func() { _ = conn.Close() }
+
+	go subscription.receive(r, codec)
+
+	return subscription, nil
+}
+
+// receive reads messages of remote subscription until connection fails
+func (s *Subscription[T]) receive(r io.Reader, codec Codec) {
+	for {
+		payload, err := readFrame(r)
+		if err == nil {
+			var message pubsubMessage[T]
+			if err = codec.NewDecoder(bytes.NewReader(payload)).Decode(&message); err == nil {
+				s.queue.push(Message[T]{Channel: message.Channel, Pattern: message.Pattern, Payload: message.Payload})
+				continue
+			}
+			err = fmt.Errorf("memkey: decode subscriber message: %w", err)
+		}
+
+		s.lock.Lock()
+		select {
+		case <-s.queue.done:
+			// Closed by Close, connection error is expected
+		default:
+			s.err = err
+		}
+		s.lock.Unlock()
+
+		s.Close()
+		return
+	}
+}
@@ -0,0 +1,206 @@
+package memkey
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveMessage[T any](t *testing.T, subscription *Subscription[T]) Message[T] {
+	t.Helper()
+
+	select {
+	case message, ok := <-subscription.Messages():
+		require.True(t, ok, "subscription closed")
+		return message
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "no message received")
+		return Message[T]{}
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	p := &PubSub[string]{}
+
+	users := p.Subscribe("user.*")
+	all := p.Subscribe("*", "user.*")
+
+	assert.Equal(t, 2, p.Publish("user.created", "a"))
+	assert.Equal(t, 1, p.Publish("order.created", "b"))
+
+	assert.Equal(t, Message[string]{Channel: "user.created", Pattern: "user.*", Payload: "a"},
+		receiveMessage(t, users))
+	assert.Equal(t, Message[string]{Channel: "user.created", Pattern: "*", Payload: "a"}, receiveMessage(t, all))
+	assert.Equal(t, Message[string]{Channel: "order.created", Pattern: "*", Payload: "b"}, receiveMessage(t, all))
+	assert.Equal(t, []string{"*", "user.*"}, all.Patterns())
+
+	users.Close()
+	users.Close()
+	_, ok := <-users.Messages()
+	assert.False(t, ok)
+	assert.Equal(t, 1, p.Publish("user.deleted", "c"))
+
+	require.NoError(t, p.Close())
+	_, ok = <-all.Messages()
+	assert.True(t, ok)
+	_, ok = <-all.Messages()
+	assert.False(t, ok)
+
+	assert.Equal(t, 0, p.Publish("user.created", "d"))
+	_, ok = <-p.Subscribe("*").Messages()
+	assert.False(t, ok)
+}
+
+func TestPubSub_Overflow(t *testing.T) {
+	t.Run("drop_newest", func(t *testing.T) {
+		p := &PubSub[int]{}
+		s := p.SubscribeWithConfig(SubscribeConfig{Buffer: 2}, "c")
+
+		for i := 0; i < 5; i++ {
+			p.Publish("c", i)
+		}
+
+		assert.Equal(t, 0, receiveMessage(t, s).Payload)
+		assert.Equal(t, 1, receiveMessage(t, s).Payload)
+		assert.Equal(t, uint64(3), s.Dropped())
+	})
+
+	t.Run("drop_oldest", func(t *testing.T) {
+		p := &PubSub[int]{}
+		s := p.SubscribeWithConfig(SubscribeConfig{Buffer: 2, Overflow: OverflowDropOldest}, "c")
+
+		for i := 0; i < 5; i++ {
+			p.Publish("c", i)
+		}
+
+		assert.Equal(t, 3, receiveMessage(t, s).Payload)
+		assert.Equal(t, 4, receiveMessage(t, s).Payload)
+		assert.Equal(t, uint64(3), s.Dropped())
+	})
+
+	t.Run("block", func(t *testing.T) {
+		p := &PubSub[int]{}
+		s := p.SubscribeWithConfig(SubscribeConfig{Buffer: 1, Overflow: OverflowBlock}, "c")
+
+		p.Publish("c", 0)
+
+		published := make(chan struct{})
+		go func() {
+			p.Publish("c", 1)
+			close(published)
+		}()
+
+		select {
+		case <-published:
+			t.Fatal("publish is not blocked")
+		case <-time.After(time.Millisecond * 50):
+		}
+
+		assert.Equal(t, 0, receiveMessage(t, s).Payload)
+		<-published
+		assert.Equal(t, 1, receiveMessage(t, s).Payload)
+
+		// Close unblocks publishers
+		p.Publish("c", 2)
+		unblocked := make(chan struct{})
+		go func() {
+			p.Publish("c", 3)
+			close(unblocked)
+		}()
+		s.Close()
+		<-unblocked
+		assert.Zero(t, s.Dropped())
+	})
+}
+
+func TestPubSub_Remote(t *testing.T) {
+	p := &PubSub[string]{}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- p.Serve(listener, nil) }()
+
+	subscribe := func(patterns ...string) *Subscription[string] {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+
+		s, err := RemoteSubscribe[string](conn, nil, SubscribeConfig{}, patterns...)
+		require.NoError(t, err)
+		return s
+	}
+
+	users := subscribe("user.*")
+	orders := subscribe("order.*")
+
+	assert.Equal(t, 1, p.Publish("user.created", "a"))
+	assert.Equal(t, 1, p.Publish("order.created", "b"))
+	assert.Equal(t, 1, p.Publish("user.deleted", "c"))
+
+	assert.Equal(t, Message[string]{Channel: "user.created", Pattern: "user.*", Payload: "a"},
+		receiveMessage(t, users))
+	assert.Equal(t, Message[string]{Channel: "user.deleted", Pattern: "user.*", Payload: "c"},
+		receiveMessage(t, users))
+	assert.Equal(t, Message[string]{Channel: "order.created", Pattern: "order.*", Payload: "b"},
+		receiveMessage(t, orders))
+
+	// Server drops subscription once subscriber disconnects
+	orders.Close()
+	assert.NoError(t, orders.Err())
+	assert.Eventually(t, func() bool { return p.Publish("order.created", "d") == 0 }, time.Second*5, time.Millisecond)
+
+	require.NoError(t, p.Close())
+	assert.ErrorIs(t, <-served, ErrClosed)
+
+	_, ok := <-users.Messages()
+	assert.False(t, ok)
+	assert.Error(t, users.Err())
+}
+
+func TestPubSub_RemoteCodec(t *testing.T) {
+	type payload struct {
+		ID   int
+		Name string
+	}
+
+	p := &PubSub[payload]{}
+	defer func() { _ = p.Close() }()
+
+	serverConn, clientConn := net.Pipe()
+	go func() { _ = p.ServeConn(serverConn, GobCodec{}) }()
+
+	s, err := RemoteSubscribe[payload](clientConn, GobCodec{}, SubscribeConfig{}, "*")
+	require.NoError(t, err)
+	defer s.Close()
+
+	p.Publish("a", payload{ID: 1, Name: "one"})
+	assert.Equal(t, payload{ID: 1, Name: "one"}, receiveMessage(t, s).Payload)
+}
+
+func TestPubSub_RemoteConfig(t *testing.T) {
+	config, err := remoteSubscribeConfig(pubsubHello{Buffer: MaxRemoteBuffer * 2, Overflow: OverflowBlock})
+	require.NoError(t, err)
+	assert.Equal(t, SubscribeConfig{Buffer: MaxRemoteBuffer, Overflow: OverflowDropOldest}, config)
+
+	config, err = remoteSubscribeConfig(pubsubHello{Buffer: 8, Overflow: OverflowDropNewest})
+	require.NoError(t, err)
+	assert.Equal(t, SubscribeConfig{Buffer: 8, Overflow: OverflowDropNewest}, config)
+
+	_, err = remoteSubscribeConfig(pubsubHello{Overflow: OverflowBlock + 1})
+	assert.Error(t, err)
+
+	p := &PubSub[string]{}
+	defer func() { _ = p.Close() }()
+
+	serverConn, clientConn := net.Pipe()
+	served := make(chan error, 1)
+	go func() { served <- p.ServeConn(serverConn, nil) }()
+
+	require.NoError(t, writePubSubMessage(clientConn, JSONCodec{}, &pubsubHello{Patterns: []string{"*"}, Buffer: -5}))
+	assert.ErrorContains(t, <-served, "invalid subscriber buffer")
+	assert.Equal(t, 0, p.Publish("a", "a"))
+}
@@ -0,0 +1,206 @@
+package memkey
+
+import "sort"
+
+// Query represents query of entries stored in the store, it's built by chaining Where, OrderBy, Offset and Limit and
+// executed by Entries, Keys, Values, Count, First or Select. All matching entries are collected under a single read
+// lock of the store. Every method that modifies query returns a new query, so query can be reused and extended
+type Query[K comparable, V any] struct {
+	// iterate calls f with values of the store until f returns true while the store read lock is held
+	iterate func(f func(key K, value V) (stop bool))
+
+	where  []func(key K, value V) bool
+	less   func(a, b Entry[K, V]) bool
+	offset int
+	limit  int
+}
+
+func newQuery[K comparable, V any](iterate func(f func(key K, value V) (stop bool))) *Query[K, V] {
+	return &Query[K, V]{
+		iterate: iterate,
+		limit:   -1,
+	}
+}
+
+// Query creates query of all values stored in the store
+func (s *TypedStore[K, V]) Query() *Query[K, V] {
+	return newQuery(func(f func(key K, value V) bool) {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+
+		for key, value := range s.data {
+			if f(key, value) {
+				return
+			}
+		}
+	})
+}
+
+// Query creates query of all values stored in the store
+func (s *Store[K]) Query() *Query[K, any] {
+	return NewQuery[any](s)
+}
+
+// NewQuery creates query of values with a specified type stored in the store
+func NewQuery[V any, K comparable](store *Store[K]) *Query[K, V] {
+	return newQuery(func(f func(key K, value V) bool) {
+		store.lock.RLock()
+		defer store.lock.RUnlock()
+
+		eachOfType(&store.types, store.data, f)
+	})
+}
+
+// Where returns query that selects only entries accepted by predicate in addition to already selected ones,
+// predicate is called while the store read lock is held, so it must not call back into the store
+func (q *Query[K, V]) Where(predicate func(key K, value V) bool) *Query[K, V] {
+	c := *q
+	c.where = append(append(make([]func(key K, value V) bool, 0, len(q.where)+1), q.where...), predicate)
+	return &c
+}
+
+// OrderBy returns query that sorts entries using less func, sorting is stable, without it no order is expected
+func (q *Query[K, V]) OrderBy(less func(a, b Entry[K, V]) bool) *Query[K, V] {
+	c := *q
+	c.less = less
+	return &c
+}
+
+// Offset returns query that skips first n entries, negative n is treated as zero
+func (q *Query[K, V]) Offset(n int) *Query[K, V] {
+	if n < 0 {
+		n = 0
+	}
+
+	c := *q
+	c.offset = n
+	return &c
+}
+
+// Limit returns query that returns at most n entries, if n is negative number of entries is not limited
+func (q *Query[K, V]) Limit(n int) *Query[K, V] {
+	c := *q
+	c.limit = n
+	return &c
+}
+
+// Entries returns entries (key-value pairs) selected by query
+func (q *Query[K, V]) Entries() []Entry[K, V] {
+	return q.run()
+}
+
+// Keys returns keys of entries selected by query
+func (q *Query[K, V]) Keys() []K {
+	return Select(q, func(key K, _ V) K {
+		return key
+	})
+}
+
+// Values returns values of entries selected by query
+func (q *Query[K, V]) Values() []V {
+	return Select(q, func(_ K, value V) V {
+		return value
+	})
+}
+
+// Count returns number of entries selected by query, offset and limit are applied as well
+func (q *Query[K, V]) Count() int {
+	if q.less != nil {
+		return len(q.run())
+	}
+
+	count := 0
+	q.iterate(func(key K, value V) bool {
+		if q.match(key, value) {
+			count++
+		}
+		return false
+	})
+
+	count -= q.offset
+	if count < 0 {
+		count = 0
+	}
+	if q.limit >= 0 && count > q.limit {
+		count = q.limit
+	}
+
+	return count
+}
+
+// First returns the first entry selected by query, if there are no entries returns false
+func (q *Query[K, V]) First() (Entry[K, V], bool) {
+	entries := q.Limit(1).run()
+	if len(entries) == 0 {
+		return Entry[K, V]{}, false
+	}
+	return entries[0], true
+}
+
+// Select returns entries selected by query mapped by f
+func Select[T any, K comparable, V any](query *Query[K, V], f func(key K, value V) T) []T {
+	entries := query.run()
+
+	result := make([]T, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, f(entry.Key, entry.Value))
+	}
+
+	return result
+}
+
+// match reports whether entry is accepted by all predicates
+func (q *Query[K, V]) match(key K, value V) bool {
+	for _, predicate := range q.where {
+		if !predicate(key, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// run collects entries selected by query
+func (q *Query[K, V]) run() []Entry[K, V] {
+	if q.limit == 0 {
+		return []Entry[K, V]{}
+	}
+
+	var entries []Entry[K, V]
+
+	// Without ordering iteration stops as soon as limit is reached
+	skip := q.offset
+	q.iterate(func(key K, value V) bool {
+		if !q.match(key, value) {
+			return false
+		}
+		if q.less == nil && skip > 0 {
+			skip--
+			return false
+		}
+
+		entries = append(entries, Entry[K, V]{Key: key, Value: value})
+		return q.less == nil && len(entries) == q.limit
+	})
+
+	if q.less == nil {
+		if entries == nil {
+			entries = []Entry[K, V]{}
+		}
+		return entries
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return q.less(entries[i], entries[j])
+	})
+
+	if q.offset >= len(entries) {
+		return []Entry[K, V]{}
+	}
+	entries = entries[q.offset:]
+
+	if q.limit >= 0 && len(entries) > q.limit {
+		entries = entries[:q.limit]
+	}
+
+	return entries
+}
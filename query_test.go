@@ -0,0 +1,79 @@
+package memkey
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUser struct {
+	Name string
+	Age  int
+}
+
+func TestTypedStore_Query(t *testing.T) {
+	s := &TypedStore[int, testUser]{}
+	for i := 1; i <= 10; i++ {
+		s.Set(i, testUser{Name: "user" + strconv.Itoa(i), Age: 20 + i%4})
+	}
+
+	adults := s.Query().Where(func(_ int, user testUser) bool { return user.Age >= 22 })
+	byAge := adults.OrderBy(func(a, b Entry[int, testUser]) bool {
+		if a.Value.Age != b.Value.Age {
+			return a.Value.Age > b.Value.Age
+		}
+		return a.Key < b.Key
+	})
+
+	assert.Equal(t, 5, adults.Count())
+	assert.Equal(t, []int{3, 7, 2, 6, 10}, byAge.Keys())
+	assert.Equal(t, []int{7, 2}, byAge.Offset(1).Limit(2).Keys())
+	assert.Equal(t, 2, byAge.Offset(1).Limit(2).Count())
+	assert.Equal(t, 2, adults.Offset(3).Count())
+	assert.Equal(t, 0, adults.Offset(10).Count())
+	assert.Empty(t, byAge.Offset(10).Keys())
+	assert.Empty(t, byAge.Limit(0).Keys())
+	assert.Len(t, adults.Limit(3).Entries(), 3)
+	assert.Len(t, adults.Offset(2).Entries(), 3)
+	assert.Len(t, adults.Offset(-1).Entries(), 5)
+
+	first, ok := byAge.First()
+	assert.True(t, ok)
+	assert.Equal(t, Entry[int, testUser]{Key: 3, Value: testUser{Name: "user3", Age: 23}}, first)
+
+	oldest := byAge.Where(func(key int, _ testUser) bool { return key > 3 })
+	first, ok = oldest.First()
+	assert.True(t, ok)
+	assert.Equal(t, 7, first.Key)
+	assert.Equal(t, 5, adults.Count(), "original query is not changed")
+
+	_, ok = adults.Where(func(int, testUser) bool { return false }).First()
+	assert.False(t, ok)
+
+	names := Select(byAge.Limit(2), func(_ int, user testUser) string { return user.Name })
+	assert.Equal(t, []string{"user3", "user7"}, names)
+
+	assert.Len(t, s.Query().Values(), 10)
+}
+
+func TestStore_Query(t *testing.T) {
+	s := &Store[string]{}
+	Set(s, "a", 1)
+	Set(s, "b", 2)
+	Set(s, "c", "3")
+	Set(s, "d", 4)
+
+	even := NewQuery[int](s).
+		Where(func(_ string, value int) bool { return value%2 == 0 }).
+		OrderBy(func(a, b Entry[string, int]) bool { return a.Key < b.Key })
+
+	assert.Equal(t, []int{2, 4}, even.Values())
+	assert.Equal(t, 3, NewQuery[int](s).Count())
+	assert.Equal(t, 4, s.Query().Count())
+
+	keys := s.Query().
+		Where(func(_ string, value any) bool { _, ok := value.(string); return ok }).
+		Keys()
+	assert.Equal(t, []string{"c"}, keys)
+}
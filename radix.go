@@ -0,0 +1,196 @@
+package memkey
+
+import (
+	"sort"
+	"strings"
+)
+
+// radixTree represents compressed prefix tree of string keys, keys are visited in lexicographic order of their
+// bytes. It's not safe for concurrent use
+type radixTree[V any] struct {
+	root radixNode[V]
+	size int
+}
+
+type radixNode[V any] struct {
+	// prefix is a part of the key that leads from parent to this node, it's empty only for root
+	prefix string
+	// children are sorted by the first byte of their prefix, no two children share the first byte
+	children []*radixNode[V]
+	leaf     bool
+	value    V
+}
+
+func (t *radixTree[V]) len() int {
+	return t.size
+}
+
+// get returns value stored with key
+func (t *radixTree[V]) get(key string) (V, bool) {
+	n := &t.root
+	for key != "" {
+		_, child := n.child(key[0])
+		if child == nil || !strings.HasPrefix(key, child.prefix) {
+			return zero[V](), false
+		}
+
+		key = key[len(child.prefix):]
+		n = child
+	}
+
+	return n.value, n.leaf
+}
+
+// insert stores value with key, returns true if key was already stored
+func (t *radixTree[V]) insert(key string, value V) bool {
+	n := &t.root
+	for key != "" {
+		i, child := n.child(key[0])
+		if child == nil {
+			n.children = append(n.children, nil)
+			copy(n.children[i+1:], n.children[i:])
+			n.children[i] = &radixNode[V]{prefix: key, leaf: true, value: value}
+			t.size++
+			return false
+		}
+
+		common := commonPrefixLen(key, child.prefix)
+		if common < len(child.prefix) {
+			// Child is split, so that the common part of prefixes becomes a separate node
+			split := &radixNode[V]{prefix: child.prefix[:common], children: []*radixNode[V]{child}}
+			child.prefix = child.prefix[common:]
+			n.children[i] = split
+			child = split
+		}
+
+		key = key[common:]
+		n = child
+	}
+
+	replaced := n.leaf
+	n.leaf = true
+	n.value = value
+	if !replaced {
+		t.size++
+	}
+
+	return replaced
+}
+
+// delete removes key, returns removed value and true if key was stored
+func (t *radixTree[V]) delete(key string) (V, bool) {
+	var (
+		parent *radixNode[V]
+		index  int
+	)
+
+	n := &t.root
+	for key != "" {
+		i, child := n.child(key[0])
+		if child == nil || !strings.HasPrefix(key, child.prefix) {
+			return zero[V](), false
+		}
+
+		parent, index = n, i
+		key = key[len(child.prefix):]
+		n = child
+	}
+
+	if !n.leaf {
+		return zero[V](), false
+	}
+
+	value := n.value
+	n.leaf = false
+	n.value = zero[V]()
+	t.size--
+
+	if parent == nil {
+		return value, true
+	}
+
+	switch len(n.children) {
+	case 0:
+		parent.children = append(parent.children[:index], parent.children[index+1:]...)
+		if parent != &t.root && !parent.leaf && len(parent.children) == 1 {
+			parent.merge()
+		}
+	case 1:
+		n.merge()
+	}
+
+	return value, true
+}
+
+// walkPrefix calls f with all keys that start with prefix until f returns true
+func (t *radixTree[V]) walkPrefix(prefix string, f func(key string, value V) (stop bool)) {
+	n := &t.root
+	key := ""
+
+	for prefix != "" {
+		_, child := n.child(prefix[0])
+		if child == nil {
+			return
+		}
+
+		switch {
+		case strings.HasPrefix(prefix, child.prefix):
+			prefix = prefix[len(child.prefix):]
+		case strings.HasPrefix(child.prefix, prefix):
+			prefix = ""
+		default:
+			return
+		}
+
+		key += child.prefix
+		n = child
+	}
+
+	n.walk(key, f)
+}
+
+// child returns index of child which prefix starts with b and the child, if there is no such child returns index
+// where it should be inserted and nil
+func (n *radixNode[V]) child(b byte) (int, *radixNode[V]) {
+	i := sort.Search(len(n.children), func(i int) bool {
+		return n.children[i].prefix[0] >= b
+	})
+
+	if i < len(n.children) && n.children[i].prefix[0] == b {
+		return i, n.children[i]
+	}
+	return i, nil
+}
+
+// merge joins node that is not a leaf with its only child
+func (n *radixNode[V]) merge() {
+	child := n.children[0]
+	n.prefix += child.prefix
+	n.children = child.children
+	n.leaf = child.leaf
+	n.value = child.value
+}
+
+// walk calls f with all keys in subtree, key is a full key of the node, returns true if stopped
+func (n *radixNode[V]) walk(key string, f func(key string, value V) (stop bool)) bool {
+	if n.leaf && f(key, n.value) {
+		return true
+	}
+
+	for _, child := range n.children {
+		if child.walk(key+child.prefix, f) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// commonPrefixLen returns length of the longest common prefix of a and b
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,127 @@
+package memkey
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkRadixTree verifies that tree is compressed and children are sorted
+func checkRadixTree[V any](t *testing.T, tree *radixTree[V]) {
+	t.Helper()
+
+	var check func(n *radixNode[V], root bool) int
+	check = func(n *radixNode[V], root bool) int {
+		size := 0
+		if n.leaf {
+			size++
+		}
+
+		if !root {
+			require.NotEmpty(t, n.prefix)
+			require.True(t, n.leaf || len(n.children) > 1, "node %q must be merged", n.prefix)
+		}
+
+		for i, child := range n.children {
+			if i > 0 {
+				require.Less(t, n.children[i-1].prefix[0], child.prefix[0])
+			}
+			size += check(child, false)
+		}
+
+		return size
+	}
+
+	require.Equal(t, tree.len(), check(&tree.root, true))
+}
+
+func TestRadixTree(t *testing.T) {
+	tree := &radixTree[int]{}
+	expected := make(map[string]int)
+
+	//nolint:gosec // Deterministic random is fine for tests
+	random := rand.New(rand.NewSource(42))
+	parts := []string{"user", "order", "u", ":", "1", "12", "profile", ""}
+
+	for i := 0; i < 20000; i++ {
+		var key strings.Builder
+		for j := random.Intn(4); j >= 0; j-- {
+			key.WriteString(parts[random.Intn(len(parts))])
+		}
+
+		if random.Intn(3) == 0 {
+			value, ok := tree.delete(key.String())
+			expectedValue, expectedOK := expected[key.String()]
+			require.Equal(t, expectedOK, ok)
+			require.Equal(t, expectedValue, value)
+			delete(expected, key.String())
+		} else {
+			_, replaced := expected[key.String()]
+			require.Equal(t, replaced, tree.insert(key.String(), i))
+			expected[key.String()] = i
+		}
+
+		if i%1000 == 0 {
+			checkRadixTree(t, tree)
+		}
+	}
+	checkRadixTree(t, tree)
+
+	keys := make([]string, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, prefix := range append(parts, "us", "user:1", "x") {
+		var expectedKeys []string
+		for _, key := range keys {
+			if strings.HasPrefix(key, prefix) {
+				expectedKeys = append(expectedKeys, key)
+			}
+		}
+
+		var scanned []string
+		tree.walkPrefix(prefix, func(key string, value int) bool {
+			assert.Equal(t, expected[key], value)
+			scanned = append(scanned, key)
+			return false
+		})
+		require.Equal(t, expectedKeys, scanned, "prefix %q", prefix)
+	}
+
+	for _, key := range keys {
+		value, ok := tree.get(key)
+		require.True(t, ok)
+		require.Equal(t, expected[key], value)
+
+		_, ok = tree.delete(key)
+		require.True(t, ok)
+	}
+	checkRadixTree(t, tree)
+	assert.Empty(t, tree.root.children)
+}
+
+func TestRadixTree_Stop(t *testing.T) {
+	tree := &radixTree[int]{}
+	for i := 0; i < 100; i++ {
+		tree.insert("key:"+strconv.Itoa(i), i)
+	}
+
+	var keys []string
+	tree.walkPrefix("key:", func(key string, _ int) bool {
+		keys = append(keys, key)
+		return len(keys) == 3
+	})
+	assert.Equal(t, []string{"key:0", "key:1", "key:10"}, keys)
+
+	_, ok := tree.get("key:")
+	assert.False(t, ok)
+	_, ok = tree.get("key:100")
+	assert.False(t, ok)
+}
@@ -0,0 +1,150 @@
+package memkey
+
+import (
+	"fmt"
+	"reflect" //nolint:depguard // Exact dynamic types are required to match values with registered types
+	"sync"
+	"time"
+)
+
+// registeredType represents type registered with Register
+type registeredType struct {
+	name   string
+	typ    reflect.Type
+	decode func(decoder Decoder) (any, error)
+}
+
+// typeRegistry represents registry of types that can be serialized by Store
+type typeRegistry struct {
+	lock   sync.RWMutex
+	byName map[string]*registeredType
+	byType map[reflect.Type]*registeredType
+}
+
+// registry is a global registry of types used by all stores
+var registry = newTypeRegistry()
+
+func newTypeRegistry() *typeRegistry {
+	r := &typeRegistry{
+		byName: make(map[string]*registeredType),
+		byType: make(map[reflect.Type]*registeredType),
+	}
+
+	registerBuiltin[bool](r)
+	registerBuiltin[string](r)
+	registerBuiltin[int](r)
+	registerBuiltin[int8](r)
+	registerBuiltin[int16](r)
+	registerBuiltin[int32](r)
+	registerBuiltin[int64](r)
+	registerBuiltin[uint](r)
+	registerBuiltin[uint8](r)
+	registerBuiltin[uint16](r)
+	registerBuiltin[uint32](r)
+	registerBuiltin[uint64](r)
+	registerBuiltin[float32](r)
+	registerBuiltin[float64](r)
+	registerBuiltin[[]byte](r)
+	registerBuiltin[[]string](r)
+	registerBuiltin[time.Time](r)
+	registerBuiltin[time.Duration](r)
+
+	return r
+}
+
+// registerBuiltin registers type under its Go name
+func registerBuiltin[V any](r *typeRegistry) {
+	if err := registerType[V](r, fmt.Sprintf("%T", zero[V]())); err != nil {
+		panic(err)
+	}
+}
+
+// Register registers type of values under specified name, so values of this type can be saved and loaded by Store,
+// registered name is also returned by Type. Basic types (bool, string, numbers, []byte, []string, time.Time and
+// time.Duration) are registered by default under their Go names. It panics if name or type is already registered
+// with a different pair or if V is an interface type
+func Register[V any](name string) {
+	if err := registerType[V](registry, name); err != nil {
+		panic(err)
+	}
+}
+
+// registerType registers type in the registry, registering the same name and type twice is not an error
+func registerType[V any](r *typeRegistry, name string) error {
+	typ := reflect.TypeOf((*V)(nil)).Elem()
+	if typ.Kind() == reflect.Interface {
+		return fmt.Errorf("memkey: can't register interface type %s", typ)
+	}
+
+	if name == "" {
+		return fmt.Errorf("memkey: can't register type %s with empty name", typ)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if existing, ok := r.byName[name]; ok {
+		if existing.typ == typ {
+			return nil
+		}
+		return fmt.Errorf("memkey: name %q already registered for type %s", name, existing.typ)
+	}
+
+	if existing, ok := r.byType[typ]; ok {
+		return fmt.Errorf("memkey: type %s already registered with name %q", typ, existing.name)
+	}
+
+	registered := &registeredType{
+		name: name,
+		typ:  typ,
+		decode: func(decoder Decoder) (any, error) {
+			var value V
+			if err := decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+			return value, nil
+		},
+	}
+
+	r.byName[name] = registered
+	r.byType[typ] = registered
+
+	return nil
+}
+
+// lookupValue returns registered type of value, or false if type is not registered
+func (r *typeRegistry) lookupValue(value any) (*registeredType, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	registered, ok := r.byType[reflect.TypeOf(value)]
+	return registered, ok
+}
+
+// lookupName returns registered type with specified name, or false if name is not registered
+func (r *typeRegistry) lookupName(name string) (*registeredType, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	registered, ok := r.byName[name]
+	return registered, ok
+}
+
+// typeName returns registered name of value type, or its Go name if type is not registered
+func (r *typeRegistry) typeName(value any) string {
+	if registered, ok := r.lookupValue(value); ok {
+		return registered.name
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// typeNameOf returns registered name of type, or its Go name if type is not registered
+func (r *typeRegistry) typeNameOf(typ reflect.Type) string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if registered, ok := r.byType[typ]; ok {
+		return registered.name
+	}
+	return fmt.Sprint(typ)
+}
@@ -0,0 +1,108 @@
+package memkey
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRegistered struct {
+	Name  string
+	Count int
+}
+
+type testNotRegistered struct{}
+
+func TestRegister(t *testing.T) {
+	Register[testRegistered]("test.registered")
+
+	t.Run("same_pair", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			Register[testRegistered]("test.registered")
+		})
+	})
+
+	t.Run("name_conflict", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Register[testNotRegistered]("test.registered")
+		})
+	})
+
+	t.Run("type_conflict", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Register[testRegistered]("test.other")
+		})
+	})
+
+	t.Run("interface", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Register[testInterface]("test.interface")
+		})
+	})
+
+	t.Run("empty_name", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Register[struct{ A int }]("")
+		})
+	})
+
+	t.Run("type_name", func(t *testing.T) {
+		s := &Store[int]{}
+		k := testKey(t)
+		Set(s, k, testRegistered{})
+		assert.Equal(t, "test.registered", MustType(s, k))
+	})
+}
+
+func TestStore_SaveAndLoad(t *testing.T) {
+	Register[testRegistered]("test.registered")
+
+	codecs := map[string]Codec{
+		"gob":  GobCodec{},
+		"json": JSONCodec{},
+	}
+
+	for name, codec := range codecs {
+		codec := codec
+		t.Run(name, func(t *testing.T) {
+			s := &Store[string]{}
+			Set(s, "int", 1)
+			Set(s, "string", "a")
+			Set(s, "duration", time.Second)
+			Set(s, "struct", testRegistered{Name: "a", Count: 2})
+
+			buf := &bytes.Buffer{}
+			require.NoError(t, s.Save(buf, codec))
+
+			loaded := &Store[string]{}
+			require.NoError(t, loaded.Load(buf, codec))
+
+			assert.ElementsMatch(t, s.Entries(), loaded.Entries())
+			assert.Equal(t, time.Second, MustGet[time.Duration](loaded, "duration"))
+		})
+	}
+
+	t.Run("not_registered_save", func(t *testing.T) {
+		s := &Store[string]{}
+		Set(s, "a", testNotRegistered{})
+
+		buf := &bytes.Buffer{}
+		assert.ErrorIs(t, s.Save(buf, JSONCodec{}), ErrNotRegistered)
+		assert.Equal(t, 0, buf.Len())
+	})
+
+	t.Run("not_registered_load", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		encoder := JSONCodec{}.NewEncoder(buf)
+		require.NoError(t, encodeSnapshotHeader(encoder, snapshotFormatStore, 1))
+		require.NoError(t, encoder.Encode(storeSnapshotEntry[string]{Key: "a", Type: "test.unknown"}))
+		require.NoError(t, encoder.Encode(struct{}{}))
+
+		s := &Store[string]{}
+		assert.ErrorIs(t, s.Load(buf, JSONCodec{}), ErrNotRegistered)
+		assert.Equal(t, 0, s.Len())
+	})
+}
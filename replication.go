@@ -0,0 +1,548 @@
+package memkey
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	replicationDefaultBacklog = 1024
+	replicationIDSize         = 16
+)
+
+var (
+	// ErrFollowerLagging returned by Leader.ServeConn when follower is behind the leader by more changes than backlog
+	// holds, follower will receive full snapshot after reconnecting
+	ErrFollowerLagging = errors.New("memkey: follower is too far behind")
+
+	// ErrReplicating returned by Follower.Replicate when follower already replicates over another connection
+	ErrReplicating = errors.New("memkey: follower is already replicating")
+)
+
+// ReplicationConfig represents configuration of replication leader and follower
+type ReplicationConfig struct {
+	// Codec is used to encode changes, leader and followers must use the same codec, if nil JSONCodec is used
+	Codec Codec
+	// Backlog is a number of the latest changes leader keeps for followers that reconnect, followers that are further
+	// behind receive full snapshot, if zero 1024 is used, it's ignored by followers
+	Backlog int
+}
+
+// replicationHello is the first message follower sends, ID and offset of the last applied change identify
+// position of follower in the leader's log
+type replicationHello struct {
+	ID     string `json:"id"`
+	Offset uint64 `json:"offset"`
+}
+
+// replicationHeader is the first message leader sends, full resync header is followed by Entries set records,
+// after that records of all following changes are sent
+type replicationHeader struct {
+	ID      string `json:"id"`
+	Offset  uint64 `json:"offset"`
+	Full    bool   `json:"full"`
+	Entries int    `json:"entries"`
+}
+
+// Leader streams changes of the store to followers, each change is numbered by its offset in the leader's log
+type Leader[K comparable, V any] struct {
+	id      string
+	codec   Codec
+	coder   recordCoder[K, V]
+	source  journalSource[K, V]
+	backlog int
+	detach  func()
+
+	lock      sync.Mutex
+	offset    uint64
+	first     uint64
+	changes   [][]byte
+	changed   chan struct{}
+	err       error
+	closed    bool
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewLeader creates replication leader that records all following changes of the store, followers are served by
+// Serve or ServeConn
+func (s *TypedStore[K, V]) NewLeader(config ReplicationConfig) *Leader[K, V] {
+	return newLeader[K, V](s, typedValues[V]{}, config)
+}
+
+func newLeader[K comparable, V any](
+	source journalSource[K, V], values valueCoder[V], config ReplicationConfig,
+) *Leader[K, V] {
+	if config.Codec == nil {
+		config.Codec = JSONCodec{}
+	}
+	if config.Backlog <= 0 {
+		config.Backlog = replicationDefaultBacklog
+	}
+
+	l := &Leader[K, V]{
+		id:        newReplicationID(),
+		codec:     config.Codec,
+		coder:     recordCoder[K, V]{codec: config.Codec, values: values},
+		source:    source,
+		backlog:   config.Backlog,
+		first:     1,
+		changes:   make([][]byte, config.Backlog),
+		changed:   make(chan struct{}),
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+		done:      make(chan struct{}),
+	}
+
+	l.detach, _ = source.attachJournal(nil, l)
+	return l
+}
+
+// newReplicationID returns random ID of leader's log
+func newReplicationID() string {
+	id := make([]byte, replicationIDSize)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// record appends change to the backlog and wakes up followers, it's called while the store lock is held
+func (l *Leader[K, V]) record(kind EventKind, key K, value V, deadline time.Time) {
+	var deadlinePtr *time.Time
+	if !deadline.IsZero() {
+		deadlinePtr = &deadline
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return
+	}
+
+	l.offset++
+
+	frame, err := l.coder.encodeRecord(kind, key, value, deadlinePtr)
+	if err != nil {
+		// Change can't be replicated, so all followers have to resync
+		if l.err == nil {
+			l.err = err
+		}
+		l.first = l.offset + 1
+	} else {
+		l.changes[l.offset%uint64(l.backlog)] = frame
+		if l.offset-l.first >= uint64(l.backlog) {
+			l.first = l.offset - uint64(l.backlog) + 1
+		}
+	}
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Offset returns offset of the last recorded change
+func (l *Leader[K, V]) Offset() uint64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.offset
+}
+
+// Err returns the first error that happened while encoding changes, changes that can't be encoded force followers to
+// resync
+func (l *Leader[K, V]) Err() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.err
+}
+
+// Serve accepts followers on listener until listener fails or leader is closed, listener is closed on return
+func (l *Leader[K, V]) Serve(listener net.Listener) error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		_ = listener.Close()
+		return ErrClosed
+	}
+	l.listeners[listener] = struct{}{}
+	l.lock.Unlock()
+
+	defer func() {
+		l.lock.Lock()
+		delete(l.listeners, listener)
+		l.lock.Unlock()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-l.done:
+				return ErrClosed
+			default:
+				return err
+			}
+		}
+
+		go func() { _ = l.ServeConn(conn) }()
+	}
+}
+
+// ServeConn streams changes to a single follower until connection fails or leader is closed, connection is closed
+// on return, returns nil if follower disconnected
+func (l *Leader[K, V]) ServeConn(conn net.Conn) error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		_ = conn.Close()
+		return ErrClosed
+	}
+	l.conns[conn] = struct{}{}
+	l.wg.Add(1)
+	l.lock.Unlock()
+
+	defer func() {
+		l.lock.Lock()
+		delete(l.conns, conn)
+		l.lock.Unlock()
+
+		_ = conn.Close()
+		l.wg.Done()
+	}()
+
+	r := bufio.NewReader(conn)
+
+	payload, err := readFrame(r)
+	if err != nil {
+		return fmt.Errorf("memkey: read replication hello: %w", err)
+	}
+
+	var hello replicationHello
+	if err = l.codec.NewDecoder(bytes.NewReader(payload)).Decode(&hello); err != nil {
+		return fmt.Errorf("memkey: decode replication hello: %w", err)
+	}
+
+	// Followers send nothing after hello, so reading fails only when follower disconnects
+	gone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, r)
+		close(gone)
+	}()
+
+	w := bufio.NewWriter(conn)
+
+	offset, err := l.handshake(w, hello)
+	if err != nil {
+		return err
+	}
+
+	return l.stream(w, offset, gone)
+}
+
+// handshake sends header and snapshot if follower can't continue from its offset, returns offset follower is at
+func (l *Leader[K, V]) handshake(w *bufio.Writer, hello replicationHello) (uint64, error) {
+	l.lock.Lock()
+	partial := hello.ID == l.id && hello.Offset+1 >= l.first && hello.Offset <= l.offset
+	l.lock.Unlock()
+
+	if partial {
+		if err := l.writeMessage(w, &replicationHeader{ID: l.id, Offset: hello.Offset}); err != nil {
+			return 0, err
+		}
+		return hello.Offset, nil
+	}
+
+	var offset uint64
+	entries := l.source.snapshotEntries(func() {
+		l.lock.Lock()
+		offset = l.offset
+		l.lock.Unlock()
+	})
+
+	header := &replicationHeader{ID: l.id, Offset: offset, Full: true, Entries: len(entries)}
+	if err := l.writeMessage(w, header); err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		frame, err := l.coder.encodeRecord(EventSet, entry.Key, entry.Value, entry.Deadline)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err = w.Write(frame); err != nil {
+			return 0, fmt.Errorf("memkey: write replication snapshot: %w", err)
+		}
+	}
+
+	return offset, nil
+}
+
+// stream sends changes that follow offset until follower disconnects or leader is closed
+func (l *Leader[K, V]) stream(w *bufio.Writer, offset uint64, gone <-chan struct{}) error {
+	for {
+		l.lock.Lock()
+		if l.closed {
+			l.lock.Unlock()
+			return ErrClosed
+		}
+
+		if offset+1 < l.first {
+			l.lock.Unlock()
+			return ErrFollowerLagging
+		}
+
+		frames := make([][]byte, 0, l.offset-offset)
+		for next := offset + 1; next <= l.offset; next++ {
+			frames = append(frames, l.changes[next%uint64(l.backlog)])
+		}
+		offset = l.offset
+		changed := l.changed
+		l.lock.Unlock()
+
+		for _, frame := range frames {
+			if _, err := w.Write(frame); err != nil {
+				return fmt.Errorf("memkey: write replication log: %w", err)
+			}
+		}
+
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("memkey: write replication log: %w", err)
+		}
+
+		select {
+		case <-changed:
+		case <-gone:
+			return nil
+		case <-l.done:
+			return ErrClosed
+		}
+	}
+}
+
+func (l *Leader[K, V]) writeMessage(w *bufio.Writer, message any) error {
+	frame, err := encodeFrame(l.codec, message)
+	if err != nil {
+		return fmt.Errorf("memkey: encode replication message: %w", err)
+	}
+
+	if _, err = w.Write(frame); err != nil {
+		return fmt.Errorf("memkey: write replication message: %w", err)
+	}
+
+	return nil
+}
+
+// Close stops recording changes, closes all listeners and follower connections and waits for them to return
+func (l *Leader[K, V]) Close() error {
+	l.closeOnce.Do(func() {
+		l.detach()
+
+		l.lock.Lock()
+		l.closed = true
+		close(l.done)
+
+		for listener := range l.listeners {
+			_ = listener.Close()
+		}
+		for conn := range l.conns {
+			_ = conn.Close()
+		}
+		l.lock.Unlock()
+
+		l.wg.Wait()
+	})
+
+	return nil
+}
+
+// Follower applies changes streamed by leader to the store, it remembers its position in the leader's log, so after
+// reconnecting it receives only missed changes if leader still has them. Changes are applied with hooks and
+// watchers of the store being called, followers should not run ExpireTTL since expiration is replicated from leader
+type Follower[K comparable, V any] struct {
+	store *TypedStore[K, V]
+	codec Codec
+	coder recordCoder[K, V]
+
+	lock   sync.Mutex
+	id     string
+	offset uint64
+	conn   net.Conn
+	closed bool
+}
+
+// NewFollower creates replication follower that applies changes to the store, replication is started by Replicate
+func (s *TypedStore[K, V]) NewFollower(config ReplicationConfig) *Follower[K, V] {
+	if config.Codec == nil {
+		config.Codec = JSONCodec{}
+	}
+
+	return &Follower[K, V]{
+		store: s,
+		codec: config.Codec,
+		coder: recordCoder[K, V]{codec: config.Codec, values: typedValues[V]{}},
+	}
+}
+
+// Replicate receives changes from leader over connection and applies them to the store until connection fails or
+// follower is closed, connection is closed on return. On the first call or if leader can't continue from the
+// follower's position all values of the store are replaced by the leader's snapshot. Replicate can be called again
+// with a new connection after it returns
+func (f *Follower[K, V]) Replicate(conn net.Conn) error {
+	f.lock.Lock()
+	if f.closed {
+		f.lock.Unlock()
+		_ = conn.Close()
+		return ErrClosed
+	}
+	if f.conn != nil {
+		f.lock.Unlock()
+		_ = conn.Close()
+		return ErrReplicating
+	}
+	f.conn = conn
+	hello := replicationHello{ID: f.id, Offset: f.offset}
+	f.lock.Unlock()
+
+	defer func() {
+		f.lock.Lock()
+		f.conn = nil
+		f.lock.Unlock()
+
+		_ = conn.Close()
+	}()
+
+	err := f.replicate(conn, hello)
+
+	f.lock.Lock()
+	closed := f.closed
+	f.lock.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	return err
+}
+
+func (f *Follower[K, V]) replicate(conn net.Conn, hello replicationHello) error {
+	frame, err := encodeFrame(f.codec, &hello)
+	if err != nil {
+		return fmt.Errorf("memkey: encode replication hello: %w", err)
+	}
+
+	if _, err = conn.Write(frame); err != nil {
+		return fmt.Errorf("memkey: write replication hello: %w", err)
+	}
+
+	r := bufio.NewReader(conn)
+
+	payload, err := readFrame(r)
+	if err != nil {
+		return fmt.Errorf("memkey: read replication header: %w", err)
+	}
+
+	var header replicationHeader
+	if err = f.codec.NewDecoder(bytes.NewReader(payload)).Decode(&header); err != nil {
+		return fmt.Errorf("memkey: decode replication header: %w", err)
+	}
+
+	if header.Full {
+		if err = f.resync(r, header.Entries); err != nil {
+			return err
+		}
+	} else if header.ID != hello.ID || header.Offset != hello.Offset {
+		return fmt.Errorf("memkey: unexpected replication position %s:%d", header.ID, header.Offset)
+	}
+
+	f.lock.Lock()
+	f.id, f.offset = header.ID, header.Offset
+	f.lock.Unlock()
+
+	for {
+		payload, err = readFrame(r)
+		if err != nil {
+			return fmt.Errorf("memkey: read replication log: %w", err)
+		}
+
+		if err = f.apply(payload); err != nil {
+			return err
+		}
+	}
+}
+
+// apply decodes and applies single change from the leader's log
+func (f *Follower[K, V]) apply(payload []byte) error {
+	kind, key, value, deadline, err := f.coder.decodeRecord(payload)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case kind != EventSet:
+		f.store.remove(kind, key)
+	case deadline != nil:
+		_ = f.store.setWithDeadline(key, value, *deadline)
+	default:
+		f.store.Set(key, value)
+	}
+
+	f.lock.Lock()
+	f.offset++
+	f.lock.Unlock()
+
+	return nil
+}
+
+// resync reads snapshot and replaces all values of the store with it
+func (f *Follower[K, V]) resync(r io.Reader, count int) error {
+	entries := make([]snapshotEntry[K, V], 0, count)
+	for i := 0; i < count; i++ {
+		payload, err := readFrame(r)
+		if err != nil {
+			return fmt.Errorf("memkey: read replication snapshot: %w", err)
+		}
+
+		_, key, value, deadline, err := f.coder.decodeRecord(payload)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, snapshotEntry[K, V]{Key: key, Value: value, Deadline: deadline})
+	}
+
+	f.store.replaceEntries(entries)
+	return nil
+}
+
+// Offset returns offset of the last change applied from the leader's log
+func (f *Follower[K, V]) Offset() uint64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.offset
+}
+
+// Close stops replication, Replicate returns ErrClosed after follower is closed
+func (f *Follower[K, V]) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.closed = true
+	if f.conn != nil {
+		_ = f.conn.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package memkey
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connectFollower connects follower to leader over in-memory connection, returns follower side of connection and
+// channel with result of Replicate
+func connectFollower[K comparable, V any](leader *Leader[K, V], follower *Follower[K, V]) (net.Conn, <-chan error) {
+	leaderConn, followerConn := net.Pipe()
+
+	go func() { _ = leader.ServeConn(leaderConn) }()
+
+	done := make(chan error, 1)
+	go func() { done <- follower.Replicate(followerConn) }()
+
+	return followerConn, done
+}
+
+func waitOffset[K comparable, V any](t *testing.T, leader *Leader[K, V], follower *Follower[K, V]) {
+	t.Helper()
+
+	assert.Eventually(t, func() bool {
+		return follower.Offset() == leader.Offset()
+	}, time.Second*5, time.Millisecond)
+}
+
+func TestReplication(t *testing.T) {
+	leaderStore := &TypedStore[string, int]{}
+	leaderStore.Set("before", 1)
+
+	leader := leaderStore.NewLeader(ReplicationConfig{})
+	defer func() { _ = leader.Close() }()
+
+	leaderStore.SetWithTTL("ttl", 2, time.Hour)
+
+	followerStore := &TypedStore[string, int]{}
+	followerStore.Set("stale", 0)
+
+	follower := followerStore.NewFollower(ReplicationConfig{})
+	_, done := connectFollower(leader, follower)
+
+	waitOffset(t, leader, follower)
+	assert.Eventually(t, func() bool { return followerStore.Len() == 2 }, time.Second*5, time.Millisecond)
+	assert.False(t, followerStore.Has("stale"))
+
+	leaderDeadline, _ := leaderStore.Deadline("ttl")
+	followerDeadline, _ := followerStore.Deadline("ttl")
+	assert.True(t, leaderDeadline.Equal(followerDeadline))
+
+	leaderStore.Set("a", 3)
+	leaderStore.Delete("before")
+	leaderStore.Persist("ttl")
+	waitOffset(t, leader, follower)
+
+	assert.ElementsMatch(t, leaderStore.Entries(), followerStore.Entries())
+	followerDeadline, _ = followerStore.Deadline("ttl")
+	assert.True(t, followerDeadline.IsZero())
+
+	require.NoError(t, follower.Close())
+	assert.ErrorIs(t, <-done, ErrClosed)
+	conn, _ := net.Pipe()
+	assert.ErrorIs(t, follower.Replicate(conn), ErrClosed)
+}
+
+func TestReplication_Resync(t *testing.T) {
+	leaderStore := &TypedStore[string, int]{}
+	leader := leaderStore.NewLeader(ReplicationConfig{Codec: GobCodec{}, Backlog: 2})
+	defer func() { _ = leader.Close() }()
+
+	leaderStore.Set("a", 1)
+
+	followerStore := &TypedStore[string, int]{}
+	follower := followerStore.NewFollower(ReplicationConfig{Codec: GobCodec{}})
+
+	conn, done := connectFollower(leader, follower)
+	waitOffset(t, leader, follower)
+
+	// Local value survives partial resync, but not full one
+	followerStore.Set("local", 0)
+
+	require.NoError(t, conn.Close())
+	assert.Error(t, <-done)
+
+	leaderStore.Set("b", 2)
+	leaderStore.Set("c", 3)
+
+	conn, done = connectFollower(leader, follower)
+	waitOffset(t, leader, follower)
+	assert.Equal(t, 4, followerStore.Len())
+	assert.True(t, followerStore.Has("local"))
+
+	require.NoError(t, conn.Close())
+	assert.Error(t, <-done)
+
+	for i := 0; i < 3; i++ {
+		leaderStore.Set("d", i)
+	}
+
+	_, done = connectFollower(leader, follower)
+	waitOffset(t, leader, follower)
+	assert.Eventually(t, func() bool { return !followerStore.Has("local") }, time.Second*5, time.Millisecond)
+	assert.ElementsMatch(t, leaderStore.Entries(), followerStore.Entries())
+
+	require.NoError(t, follower.Close())
+	assert.ErrorIs(t, <-done, ErrClosed)
+}
+
+func TestReplication_Serve(t *testing.T) {
+	leaderStore := &TypedStore[string, int]{}
+	leader := leaderStore.NewLeader(ReplicationConfig{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- leader.Serve(listener) }()
+
+	followerStore := &TypedStore[string, int]{}
+	follower := followerStore.NewFollower(ReplicationConfig{})
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- follower.Replicate(conn) }()
+
+	leaderStore.Set("a", 1)
+	assert.Eventually(t, func() bool { return followerStore.Has("a") }, time.Second*5, time.Millisecond)
+
+	other, _ := net.Pipe()
+	assert.ErrorIs(t, follower.Replicate(other), ErrReplicating)
+
+	require.NoError(t, leader.Close())
+	assert.ErrorIs(t, <-served, ErrClosed)
+	assert.Error(t, <-done)
+}
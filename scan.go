@@ -0,0 +1,100 @@
+package memkey
+
+// DefaultScanCount is a number of keys examined by scan if no count specified
+const DefaultScanCount = 10
+
+// scanIndex assigns every key a slot that doesn't change while key is stored, cursor of scan is an index of slot,
+// so keys that are stored during the whole scan are returned exactly once. Slots of deleted keys are reused by new
+// keys, slots are released only from the end
+type scanIndex[K comparable] struct {
+	slots []scanSlot[K]
+	index map[K]int
+	free  []int
+}
+
+type scanSlot[K comparable] struct {
+	key  K
+	used bool
+}
+
+func newScanIndex[K comparable, V any](data map[K]V) *scanIndex[K] {
+	s := &scanIndex[K]{
+		slots: make([]scanSlot[K], 0, len(data)),
+		index: make(map[K]int, len(data)),
+	}
+
+	for key := range data {
+		s.add(key)
+	}
+
+	return s
+}
+
+// record updates slots with change of the store
+func (s *scanIndex[K]) record(kind EventKind, key K) {
+	if kind == EventSet {
+		s.add(key)
+	} else {
+		s.remove(key)
+	}
+}
+
+func (s *scanIndex[K]) add(key K) {
+	if _, ok := s.index[key]; ok {
+		return
+	}
+
+	i := len(s.slots)
+	for len(s.free) > 0 {
+		slot := s.free[len(s.free)-1]
+		s.free = s.free[:len(s.free)-1]
+
+		// Free list may contain slots that were released from the end
+		if slot < len(s.slots) {
+			i = slot
+			break
+		}
+	}
+
+	if i == len(s.slots) {
+		s.slots = append(s.slots, scanSlot[K]{})
+	}
+
+	s.slots[i] = scanSlot[K]{key: key, used: true}
+	s.index[key] = i
+}
+
+func (s *scanIndex[K]) remove(key K) {
+	i, ok := s.index[key]
+	if !ok {
+		return
+	}
+
+	delete(s.index, key)
+	s.slots[i] = scanSlot[K]{}
+	s.free = append(s.free, i)
+
+	for len(s.slots) > 0 && !s.slots[len(s.slots)-1].used {
+		s.slots = s.slots[:len(s.slots)-1]
+	}
+	if len(s.slots) == 0 {
+		s.free = s.free[:0]
+	}
+}
+
+// scan calls f with keys of count used slots starting from cursor, returns cursor of the next page or zero if
+// there are no more slots
+func (s *scanIndex[K]) scan(cursor uint64, count int, f func(key K)) uint64 {
+	i := cursor
+	for ; i < uint64(len(s.slots)) && count > 0; i++ {
+		if slot := s.slots[i]; slot.used {
+			f(slot.key)
+			count--
+		}
+	}
+
+	if i >= uint64(len(s.slots)) {
+		return 0
+	}
+	return i
+}
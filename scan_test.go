@@ -0,0 +1,118 @@
+package memkey
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scanAll scans the store until cursor is zero and returns number of times each key was returned
+func scanAll[K comparable](scan func(cursor uint64, count int) ([]K, uint64), count int) map[K]int {
+	seen := make(map[K]int)
+
+	var (
+		keys   []K
+		cursor uint64
+	)
+	for {
+		keys, cursor = scan(cursor, count)
+		for _, key := range keys {
+			seen[key]++
+		}
+
+		if cursor == 0 {
+			return seen
+		}
+	}
+}
+
+func TestStore_Scan(t *testing.T) {
+	s := &Store[int]{}
+
+	keys, cursor := s.Scan(0, 0)
+	assert.Empty(t, keys)
+	assert.Zero(t, cursor)
+
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			Set(s, i, strconv.Itoa(i))
+		} else {
+			Set(s, i, i)
+		}
+	}
+
+	keys, cursor = s.Scan(0, 0)
+	assert.Len(t, keys, DefaultScanCount)
+	assert.NotZero(t, cursor)
+
+	seen := scanAll(s.Scan, 7)
+	assert.Len(t, seen, 100)
+	for key, times := range seen {
+		assert.Equal(t, 1, times, "key %d", key)
+	}
+
+	strings := scanAll(func(cursor uint64, count int) ([]int, uint64) {
+		return Scan[string](s, cursor, count)
+	}, 7)
+	assert.Len(t, strings, 50)
+	for key := range strings {
+		assert.Zero(t, key%2)
+	}
+
+	keys, cursor = s.Scan(1000, 10)
+	assert.Empty(t, keys)
+	assert.Zero(t, cursor)
+}
+
+func TestTypedStore_Scan(t *testing.T) {
+	s := &TypedStore[string, int]{}
+	for i := 0; i < 1000; i++ {
+		s.Set("stable:"+strconv.Itoa(i), i)
+		s.Set("deleted:"+strconv.Itoa(i), i)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			s.Delete("deleted:" + strconv.Itoa(i%1000))
+			s.Set("new:"+strconv.Itoa(i), i)
+			s.Set("stable:"+strconv.Itoa(i%1000), i)
+		}
+	}()
+
+	seen := scanAll(s.Scan, 50)
+	close(done)
+	wg.Wait()
+
+	for i := 0; i < 1000; i++ {
+		require.Equal(t, 1, seen["stable:"+strconv.Itoa(i)], "key stable:%d", i)
+	}
+
+	// Slots are released from the end
+	for _, key := range s.Keys() {
+		s.Delete(key)
+	}
+	assert.Empty(t, s.cursors.slots)
+	assert.Empty(t, s.cursors.index)
+	assert.Empty(t, s.cursors.free)
+
+	s.Set("a", 1)
+	assert.Len(t, s.cursors.slots, 1)
+	keys, cursor := s.Scan(0, 10)
+	assert.Equal(t, []string{"a"}, keys)
+	assert.Zero(t, cursor)
+}
@@ -0,0 +1,115 @@
+package memkey
+
+import (
+	"fmt"
+	"reflect" //nolint:depguard // Types of values are compared with declared types of keys
+)
+
+// TypeError represents error of write rejected because value doesn't have the type declared for its key,
+// it matches ErrTypeMismatch with errors.Is
+type TypeError struct {
+	// Key is a key of the rejected write
+	Key any
+	// Declared is a type declared for the key
+	Declared reflect.Type
+	// Actual is a type of the rejected value, it's nil for nil value
+	Actual reflect.Type
+}
+
+// Error returns description of the error
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("memkey: key %v is declared with type %s, not %s", e.Key, e.Declared, e.Actual)
+}
+
+// Unwrap returns ErrTypeMismatch
+func (e *TypeError) Unwrap() error {
+	return ErrTypeMismatch
+}
+
+// EnableSchema enables strict mode of the store: the first accepted write of a key fixes its type as if it was
+// declared by Declare. Keys that are already stored keep types of their values. Values restored by Load, AOF or
+// replication are not checked, but fix types of keys that are not declared yet
+func (s *Store[K]) EnableSchema() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.strict = true
+}
+
+// Declare fixes type of values stored with key, even if the store is not in strict mode, writes of values with
+// another type are rejected with TypeError, TrySet and TrySetWithTTL return it. For interface type V values that
+// implement it are accepted. Returns TypeError if key is already declared with another type or if stored value
+// doesn't have type V
+func Declare[V any, K comparable](store *Store[K], key K) error {
+	typ := reflect.TypeOf((*V)(nil)).Elem()
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if declared, ok := store.schema[key]; ok {
+		if declared != typ {
+			return &TypeError{Key: key, Declared: declared, Actual: typ}
+		}
+		return nil
+	}
+
+	if value, ok := store.data[key]; ok && !matchesType(typ, value) {
+		return &TypeError{Key: key, Declared: typ, Actual: reflect.TypeOf(value)}
+	}
+
+	if store.schema == nil {
+		store.schema = make(map[K]reflect.Type)
+	}
+	store.schema[key] = typ
+
+	return nil
+}
+
+// checkSchema returns error if value can't be stored with key because of declared type, in strict mode it also
+// declares type of key, must be called while the store lock is held
+func (s *Store[K]) checkSchema(key K, value any) error {
+	declared, ok := s.schema[key]
+	if !ok && s.strict {
+		if oldValue, stored := s.data[key]; stored {
+			declared, ok = reflect.TypeOf(oldValue), true
+		}
+	}
+
+	if ok && !matchesType(declared, value) {
+		return &TypeError{Key: key, Declared: declared, Actual: reflect.TypeOf(value)}
+	}
+
+	s.declareValue(key, value)
+	return nil
+}
+
+// declareValue declares type of value for key if the store is in strict mode and key is not declared yet, must be
+// called while the store lock is held
+func (s *Store[K]) declareValue(key K, value any) {
+	if !s.strict {
+		return
+	}
+
+	if _, ok := s.schema[key]; ok {
+		return
+	}
+
+	if s.schema == nil {
+		s.schema = make(map[K]reflect.Type)
+	}
+	s.schema[key] = reflect.TypeOf(value)
+}
+
+// matchesType reports whether value can be stored with key declared with type, nil value matches only interfaces
+func matchesType(typ reflect.Type, value any) bool {
+	if typ == nil {
+		return value == nil
+	}
+
+	actual := reflect.TypeOf(value)
+	if typ.Kind() == reflect.Interface {
+		return actual == nil || actual.Implements(typ)
+	}
+
+	return actual == typ
+}
@@ -0,0 +1,113 @@
+package memkey
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_EnableSchema(t *testing.T) {
+	s := &Store[string]{}
+	s.Set("a", "text")
+	s.EnableSchema()
+
+	// Type of stored value is kept
+	err := s.TrySet("a", 1.5)
+	var typeErr *TypeError
+	require.ErrorAs(t, err, &typeErr)
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	assert.Equal(t, "a", typeErr.Key)
+	assert.Equal(t, reflect.TypeOf(""), typeErr.Declared)
+	assert.Equal(t, reflect.TypeOf(1.5), typeErr.Actual)
+	assert.Equal(t, "memkey: key a is declared with type string, not float64", err.Error())
+	assert.Equal(t, "text", s.MustGet("a"))
+
+	require.NoError(t, s.TrySet("a", "other"))
+
+	// First write fixes type of the key, even after it's deleted
+	require.NoError(t, s.TrySetWithTTL("b", 1, time.Hour))
+	s.Delete("b")
+	require.ErrorIs(t, s.TrySet("b", "text"), ErrTypeMismatch)
+	require.ErrorIs(t, s.TrySetWithTTL("b", "text", time.Hour), ErrTypeMismatch)
+	assert.False(t, s.Has("b"))
+
+	typ, ok := s.Type("b")
+	assert.True(t, ok)
+	assert.Equal(t, "int", typ)
+
+	// Set ignores rejected writes
+	s.Set("b", "text")
+	SetWithTTL(s, "b", "text", time.Hour)
+	assert.False(t, s.Has("b"))
+
+	_, err = Incr(s, "b", 1)
+	require.NoError(t, err)
+	_, err = Incr(s, "b", 1.5)
+	require.ErrorIs(t, err, ErrTypeMismatch)
+	assert.Equal(t, 1, s.MustGet("b"))
+}
+
+func TestDeclare(t *testing.T) {
+	s := &Store[string]{}
+
+	require.NoError(t, Declare[time.Duration](s, "timeout"))
+	require.NoError(t, Declare[time.Duration](s, "timeout"))
+	require.ErrorIs(t, Declare[int](s, "timeout"), ErrTypeMismatch)
+
+	typ, ok := Type(s, "timeout")
+	assert.True(t, ok)
+	assert.Equal(t, "time.Duration", typ)
+	assert.False(t, s.Has("timeout"))
+
+	// Declared keys are checked without strict mode
+	require.ErrorIs(t, s.TrySet("timeout", 5), ErrTypeMismatch)
+	require.NoError(t, s.TrySet("timeout", time.Second))
+
+	// Other keys are not checked without strict mode
+	require.NoError(t, s.TrySet("other", 5))
+	require.NoError(t, s.TrySet("other", "text"))
+
+	require.ErrorIs(t, Declare[int](s, "other"), ErrTypeMismatch)
+	require.NoError(t, Declare[string](s, "other"))
+
+	Register[testSession]("schema-session")
+	require.NoError(t, Declare[testSession](s, "session"))
+	assert.Equal(t, "schema-session", MustType(s, "session"))
+}
+
+func TestDeclare_Interface(t *testing.T) {
+	s := &Store[string]{}
+	require.NoError(t, Declare[fmt.Stringer](s, "name"))
+
+	require.NoError(t, s.TrySet("name", time.Second))
+	require.NoError(t, s.TrySet("name", nil))
+	require.ErrorIs(t, s.TrySet("name", 1), ErrTypeMismatch)
+
+	typ, ok := s.Type("name")
+	assert.True(t, ok)
+	assert.Equal(t, "<nil>", typ)
+
+	s.Delete("name")
+	assert.Equal(t, "fmt.Stringer", s.MustType("name"))
+}
+
+func TestStore_EnableSchema_Load(t *testing.T) {
+	source := &Store[string]{}
+	source.Set("a", 1)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, source.Save(buf, JSONCodec{}))
+
+	s := &Store[string]{}
+	s.EnableSchema()
+	require.NoError(t, s.Load(buf, JSONCodec{}))
+
+	require.ErrorIs(t, s.TrySet("a", "text"), ErrTypeMismatch)
+	s.Delete("a")
+	assert.Equal(t, "int", s.MustType("a"))
+}
@@ -0,0 +1,481 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mymmrac/memkey"
+)
+
+const (
+	httpKeysPath        = "/keys"
+	httpDefaultPageSize = 100
+	httpMaxPageSize     = 1000
+	httpDefaultMaxBody  = 1024 * 1024
+)
+
+// ValueCodec encodes and decodes values in HTTP request and response bodies
+type ValueCodec[V any] interface {
+	ContentType() string
+	Marshal(value V) ([]byte, error)
+	Unmarshal(data []byte) (V, error)
+}
+
+// JSONValueCodec encodes values using encoding/json
+type JSONValueCodec[V any] struct{}
+
+// ContentType returns JSON content type
+func (JSONValueCodec[V]) ContentType() string {
+	return "application/json"
+}
+
+// Marshal encodes value as JSON
+func (JSONValueCodec[V]) Marshal(value V) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Unmarshal decodes value from JSON
+func (JSONValueCodec[V]) Unmarshal(data []byte) (V, error) {
+	var value V
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// BytesValueCodec passes byte slices as is
+type BytesValueCodec struct{}
+
+// ContentType returns binary content type
+func (BytesValueCodec) ContentType() string {
+	return "application/octet-stream"
+}
+
+// Marshal returns value as is
+func (BytesValueCodec) Marshal(value []byte) ([]byte, error) {
+	return value, nil
+}
+
+// Unmarshal returns data as is
+func (BytesValueCodec) Unmarshal(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// TextValueCodec passes strings as plain text
+type TextValueCodec struct{}
+
+// ContentType returns plain text content type
+func (TextValueCodec) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+// Marshal returns value as bytes
+func (TextValueCodec) Marshal(value string) ([]byte, error) {
+	return []byte(value), nil
+}
+
+// Unmarshal returns data as string
+func (TextValueCodec) Unmarshal(data []byte) (string, error) {
+	return string(data), nil
+}
+
+// HTTPConfig represents configuration of HTTP handler
+type HTTPConfig[V any] struct {
+	// Codec used to encode and decode values, JSONValueCodec is used by default
+	Codec ValueCodec[V]
+
+	// ReadOnly rejects PUT and DELETE requests with 405 Method Not Allowed
+	ReadOnly bool
+
+	// PageSize is a default number of keys returned by list request, 100 is used by default
+	PageSize int
+
+	// MaxBodySize limits size of PUT request body, 1 MiB is used by default
+	MaxBodySize int64
+}
+
+// httpBackend represents store that is served over HTTP
+type httpBackend[V any] interface {
+	get(key string) (V, bool)
+	set(key string, value V, ttl time.Duration) error
+	del(key string) bool
+	has(key string) bool
+	deadline(key string) (time.Time, bool)
+	scan(cursor uint64, count int) ([]string, uint64)
+}
+
+// httpStore serves Store
+type httpStore struct {
+	store *memkey.Store[string]
+}
+
+func (b httpStore) get(key string) (any, bool) { return b.store.Get(key) }
+
+func (b httpStore) set(key string, value any, ttl time.Duration) error {
+	if ttl > 0 {
+		return b.store.TrySetWithTTL(key, value, ttl)
+	}
+	return b.store.TrySet(key, value)
+}
+
+func (b httpStore) del(key string) bool                   { return b.store.Delete(key) }
+func (b httpStore) has(key string) bool                   { return b.store.Has(key) }
+func (b httpStore) deadline(key string) (time.Time, bool) { return b.store.Deadline(key) }
+func (b httpStore) scan(cursor uint64, count int) ([]string, uint64) {
+	return b.store.Scan(cursor, count)
+}
+
+// httpTypedStore serves TypedStore
+type httpTypedStore[V any] struct {
+	store *memkey.TypedStore[string, V]
+}
+
+func (b httpTypedStore[V]) get(key string) (V, bool) { return b.store.Get(key) }
+
+func (b httpTypedStore[V]) set(key string, value V, ttl time.Duration) error {
+	if ttl > 0 {
+		return b.store.TrySetWithTTL(key, value, ttl)
+	}
+	return b.store.TrySet(key, value)
+}
+
+func (b httpTypedStore[V]) del(key string) bool                   { return b.store.Delete(key) }
+func (b httpTypedStore[V]) has(key string) bool                   { return b.store.Has(key) }
+func (b httpTypedStore[V]) deadline(key string) (time.Time, bool) { return b.store.Deadline(key) }
+func (b httpTypedStore[V]) scan(cursor uint64, count int) ([]string, uint64) {
+	return b.store.Scan(cursor, count)
+}
+
+// HTTP is an http.Handler that serves store as REST resource:
+//
+//	GET    /keys/{key}  returns value encoded by codec, 404 if key doesn't exist
+//	HEAD   /keys/{key}  returns 200 if key exists and 404 otherwise
+//	PUT    /keys/{key}  sets value decoded from request body, optional ttl query parameter sets TTL (e.g. ?ttl=10s),
+//	                    409 if value is rejected by the store and 503 if the store is closed
+//	DELETE /keys/{key}  deletes value, 404 if key doesn't exist
+//	GET    /keys        returns JSON object with keys and cursor of the next page, supports cursor, limit and
+//	                    match (glob pattern) query parameters, keys are sorted only within a page, cursor 0 (same
+//	                    as no cursor) returns the first page
+//
+// Responses with values have ETag derived from encoded value, GET respects If-None-Match, PUT and DELETE respect
+// If-Match and If-None-Match. Conditional writes are atomic only with respect to other requests of the same handler.
+// Use http.StripPrefix to mount handler under a different path. Values expire only if ExpireTTL of the store is
+// running
+type HTTP[V any] struct {
+	backend httpBackend[V]
+	config  HTTPConfig[V]
+
+	lock sync.Mutex
+}
+
+// httpKeysPage represents single page of keys list
+type httpKeysPage struct {
+	Keys []string `json:"keys"`
+	Next string   `json:"next,omitempty"`
+}
+
+// NewHTTP creates HTTP handler over Store
+func NewHTTP(store *memkey.Store[string], config HTTPConfig[any]) *HTTP[any] {
+	return newHTTP[any](httpStore{store: store}, config)
+}
+
+// NewHTTPTyped creates HTTP handler over TypedStore
+func NewHTTPTyped[V any](store *memkey.TypedStore[string, V], config HTTPConfig[V]) *HTTP[V] {
+	return newHTTP[V](httpTypedStore[V]{store: store}, config)
+}
+
+func newHTTP[V any](backend httpBackend[V], config HTTPConfig[V]) *HTTP[V] {
+	if config.Codec == nil {
+		config.Codec = JSONValueCodec[V]{}
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = httpDefaultPageSize
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = httpDefaultMaxBody
+	}
+
+	return &HTTP[V]{
+		backend: backend,
+		config:  config,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *HTTP[V]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if path == httpKeysPath || path == httpKeysPath+"/" {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			h.methodNotAllowed(w, http.MethodGet, http.MethodHead)
+			return
+		}
+		h.list(w, r)
+		return
+	}
+
+	key := strings.TrimPrefix(path, httpKeysPath+"/")
+	if key == path {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet:
+		h.get(w, r, key)
+	case r.Method == http.MethodHead:
+		h.has(w, key)
+	case r.Method == http.MethodPut && !h.config.ReadOnly:
+		h.put(w, r, key)
+	case r.Method == http.MethodDelete && !h.config.ReadOnly:
+		h.del(w, r, key)
+	case h.config.ReadOnly:
+		h.methodNotAllowed(w, http.MethodGet, http.MethodHead)
+	default:
+		h.methodNotAllowed(w, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete)
+	}
+}
+
+func (h *HTTP[V]) get(w http.ResponseWriter, r *http.Request, key string) {
+	value, ok := h.backend.get(key)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := h.config.Codec.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tag := etag(data)
+	w.Header().Set("ETag", tag)
+	if deadline, _ := h.backend.deadline(key); !deadline.IsZero() {
+		w.Header().Set("Expires", deadline.UTC().Format(http.TimeFormat))
+	}
+
+	if matchETag(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", h.config.Codec.ContentType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+func (h *HTTP[V]) has(w http.ResponseWriter, key string) {
+	if !h.backend.has(key) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *HTTP[V]) put(w http.ResponseWriter, r *http.Request, key string) {
+	var ttl time.Duration
+	if ttlParam := r.URL.Query().Get("ttl"); ttlParam != "" {
+		var err error
+		ttl, err = time.ParseDuration(ttlParam)
+		if err != nil || ttl <= 0 {
+			http.Error(w, "invalid ttl", http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.config.MaxBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	value, err := h.config.Codec.Unmarshal(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Re-encode value, so ETag matches one returned by GET
+	data, err = h.config.Codec.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	exists, ok := h.precondition(w, r, key)
+	if !ok {
+		return
+	}
+
+	if err = h.backend.set(key, value, ttl); err != nil {
+		http.Error(w, err.Error(), writeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("ETag", etag(data))
+	if exists {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (h *HTTP[V]) del(w http.ResponseWriter, r *http.Request, key string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if _, ok := h.precondition(w, r, key); !ok {
+		return
+	}
+
+	if !h.backend.del(key) {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// precondition checks If-Match and If-None-Match headers against current value, replies with 412 Precondition
+// Failed and returns false if check fails
+func (h *HTTP[V]) precondition(w http.ResponseWriter, r *http.Request, key string) (exists bool, ok bool) {
+	ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+
+	value, exists := h.backend.get(key)
+	if ifMatch == "" && ifNoneMatch == "" {
+		return exists, true
+	}
+
+	tag := ""
+	if exists {
+		data, err := h.config.Codec.Marshal(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return exists, false
+		}
+		tag = etag(data)
+	}
+
+	if ifMatch != "" && (!exists || !matchETag(ifMatch, tag)) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return exists, false
+	}
+
+	if ifNoneMatch != "" && exists && matchETag(ifNoneMatch, tag) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return exists, false
+	}
+
+	return exists, true
+}
+
+func (h *HTTP[V]) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := h.config.PageSize
+	if limitParam := query.Get("limit"); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > httpMaxPageSize {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var cursor uint64
+	if cursorParam := query.Get("cursor"); cursorParam != "" {
+		var err error
+		cursor, err = strconv.ParseUint(cursorParam, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Cursor is a cursor of the store scan, so keys that are stored during the whole listing are returned exactly
+	// once and keys are examined only until the page is filled
+	pattern := query.Get("match")
+	page := httpKeysPage{Keys: make([]string, 0, limit)}
+	for {
+		var keys []string
+		keys, cursor = h.backend.scan(cursor, limit-len(page.Keys))
+
+		for _, key := range keys {
+			if pattern == "" || memkey.MatchGlob(pattern, key) {
+				page.Keys = append(page.Keys, key)
+			}
+		}
+
+		if cursor == 0 || len(page.Keys) == limit {
+			break
+		}
+	}
+	sort.Strings(page.Keys)
+
+	if cursor != 0 {
+		page.Next = strconv.FormatUint(cursor, 10)
+	}
+
+	data, err := json.Marshal(page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(data)
+}
+
+func (h *HTTP[V]) methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// writeErrorStatus returns status code of write rejected by the store
+func writeErrorStatus(err error) int {
+	if errors.Is(err, memkey.ErrClosed) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusConflict
+}
+
+// etag returns strong entity tag of encoded value
+func etag(data []byte) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write(data)
+	return `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+}
+
+// matchETag checks if header value (list of entity tags or *) matches entity tag, W/ prefix of tags is ignored
+func matchETag(header, tag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymmrac/memkey"
+)
+
+func doHTTP(t *testing.T, h http.Handler, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHTTP_Typed(t *testing.T) {
+	s := &memkey.TypedStore[string, int]{}
+	h := NewHTTPTyped(s, HTTPConfig[int]{})
+
+	assert.Equal(t, http.StatusNotFound, doHTTP(t, h, http.MethodGet, "/keys/a", "").Code)
+	assert.Equal(t, http.StatusNotFound, doHTTP(t, h, http.MethodHead, "/keys/a", "").Code)
+
+	assert.Equal(t, http.StatusCreated, doHTTP(t, h, http.MethodPut, "/keys/a", "1").Code)
+	assert.Equal(t, http.StatusNoContent, doHTTP(t, h, http.MethodPut, "/keys/a", "2").Code)
+
+	value, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+
+	rec := doHTTP(t, h, http.MethodGet, "/keys/a", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Body.String())
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Empty(t, rec.Header().Get("Expires"))
+
+	assert.Equal(t, http.StatusOK, doHTTP(t, h, http.MethodHead, "/keys/a", "").Code)
+
+	assert.Equal(t, http.StatusBadRequest, doHTTP(t, h, http.MethodPut, "/keys/a", "x").Code)
+	assert.Equal(t, http.StatusBadRequest, doHTTP(t, h, http.MethodPut, "/keys/a?ttl=x", "1").Code)
+
+	assert.Equal(t, http.StatusNoContent, doHTTP(t, h, http.MethodDelete, "/keys/a", "").Code)
+	assert.Equal(t, http.StatusNotFound, doHTTP(t, h, http.MethodDelete, "/keys/a", "").Code)
+	assert.False(t, s.Has("a"))
+
+	rec = doHTTP(t, h, http.MethodPost, "/keys/a", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD, PUT, DELETE", rec.Header().Get("Allow"))
+	assert.Equal(t, http.StatusNotFound, doHTTP(t, h, http.MethodGet, "/other", "").Code)
+}
+
+func TestHTTP_RejectedWrite(t *testing.T) {
+	s := &memkey.Store[string]{}
+	h := NewHTTP(s, HTTPConfig[any]{})
+
+	require.NoError(t, memkey.Declare[string](s, "a"))
+	assert.Equal(t, http.StatusConflict, doHTTP(t, h, http.MethodPut, "/keys/a", "1").Code)
+	assert.False(t, s.Has("a"))
+
+	require.NoError(t, s.Close())
+	assert.Equal(t, http.StatusServiceUnavailable, doHTTP(t, h, http.MethodPut, "/keys/b", "1").Code)
+	assert.False(t, s.Has("b"))
+}
+
+func TestHTTP_TTL(t *testing.T) {
+	s := &memkey.TypedStore[string, string]{}
+	h := NewHTTPTyped[string](s, HTTPConfig[string]{Codec: TextValueCodec{}})
+
+	assert.Equal(t, http.StatusCreated, doHTTP(t, h, http.MethodPut, "/keys/a?ttl=1m", "hello").Code)
+
+	deadline, ok := s.Deadline("a")
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	rec := doHTTP(t, h, http.MethodGet, "/keys/a", "")
+	assert.Equal(t, "hello", rec.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	expires, err := http.ParseTime(rec.Header().Get("Expires"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, deadline, expires, time.Second)
+}
+
+func TestHTTP_Conditional(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	h := NewHTTPTyped[[]byte](s, HTTPConfig[[]byte]{Codec: BytesValueCodec{}})
+
+	assert.Equal(t, http.StatusPreconditionFailed, doHTTP(t, h, http.MethodPut, "/keys/a", "1", "If-Match", "*").Code)
+	assert.Equal(t, http.StatusCreated, doHTTP(t, h, http.MethodPut, "/keys/a", "1", "If-None-Match", "*").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, doHTTP(t, h, http.MethodPut, "/keys/a", "2", "If-None-Match", "*").Code)
+
+	tag := doHTTP(t, h, http.MethodGet, "/keys/a", "").Header().Get("ETag")
+	require.NotEmpty(t, tag)
+
+	assert.Equal(t, http.StatusNotModified, doHTTP(t, h, http.MethodGet, "/keys/a", "", "If-None-Match", tag).Code)
+	assert.Equal(t, http.StatusOK, doHTTP(t, h, http.MethodGet, "/keys/a", "", "If-None-Match", `"other"`).Code)
+
+	rec := doHTTP(t, h, http.MethodPut, "/keys/a", "2", "If-Match", `"other", `+tag)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	newTag := rec.Header().Get("ETag")
+	assert.NotEqual(t, tag, newTag)
+	assert.Equal(t, newTag, doHTTP(t, h, http.MethodGet, "/keys/a", "").Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusPreconditionFailed, doHTTP(t, h, http.MethodPut, "/keys/a", "3", "If-Match", tag).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, doHTTP(t, h, http.MethodDelete, "/keys/a", "", "If-Match", tag).Code)
+	assert.Equal(t, http.StatusNoContent, doHTTP(t, h, http.MethodDelete, "/keys/a", "", "If-Match", newTag).Code)
+}
+
+func TestHTTP_List(t *testing.T) {
+	s := &memkey.Store[string]{}
+	h := NewHTTP(s, HTTPConfig[any]{PageSize: 4})
+
+	for i := 0; i < 10; i++ {
+		memkey.Set(s, fmt.Sprintf("user:%02d", i), i)
+	}
+	memkey.Set(s, "other", "x")
+
+	var expected []string
+	for i := 0; i < 10; i++ {
+		expected = append(expected, fmt.Sprintf("user:%02d", i))
+	}
+
+	var keys []string
+	cursor := ""
+	for {
+		rec := doHTTP(t, h, http.MethodGet, "/keys?match=user:*&cursor="+cursor, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var page httpKeysPage
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+		assert.LessOrEqual(t, len(page.Keys), 4)
+		keys = append(keys, page.Keys...)
+
+		if page.Next == "" {
+			break
+		}
+		cursor = page.Next
+	}
+	assert.ElementsMatch(t, expected, keys)
+
+	rec := doHTTP(t, h, http.MethodGet, "/keys?limit=100", "")
+	assert.JSONEq(t, `{"keys":["other","user:00","user:01","user:02","user:03","user:04","user:05","user:06",`+
+		`"user:07","user:08","user:09"]}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, doHTTP(t, h, http.MethodGet, "/keys?limit=0", "").Code)
+	assert.Equal(t, rec.Body.String(), doHTTP(t, h, http.MethodGet, "/keys?limit=100&cursor=0", "").Body.String())
+	assert.Equal(t, http.StatusBadRequest, doHTTP(t, h, http.MethodGet, "/keys?cursor=x", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, doHTTP(t, h, http.MethodPost, "/keys", "").Code)
+}
+
+func TestHTTP_ReadOnly(t *testing.T) {
+	s := &memkey.Store[string]{}
+	memkey.Set(s, "a", map[string]any{"n": 1.0})
+	h := NewHTTP(s, HTTPConfig[any]{ReadOnly: true})
+
+	rec := doHTTP(t, h, http.MethodGet, "/keys/a", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"n":1}`, rec.Body.String())
+
+	rec = doHTTP(t, h, http.MethodPut, "/keys/a", "1")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+	assert.Equal(t, http.StatusMethodNotAllowed, doHTTP(t, h, http.MethodDelete, "/keys/a", "").Code)
+	assert.True(t, s.Has("a"))
+}
+
+func TestHTTP_Server(t *testing.T) {
+	s := &memkey.Store[string]{}
+	srv := httptest.NewServer(http.StripPrefix("/api", NewHTTP(s, HTTPConfig[any]{MaxBodySize: 8})))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/api/keys/a%2Fb", strings.NewReader(`"value"`))
+	require.NoError(t, err)
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "value", memkey.MustGet[string](s, "a/b"))
+
+	req, err = http.NewRequest(http.MethodPut, srv.URL+"/api/keys/c", strings.NewReader(`"too long value"`))
+	require.NoError(t, err)
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
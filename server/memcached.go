@@ -0,0 +1,596 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mymmrac/memkey"
+)
+
+const (
+	memcachedMaxKeyLength   = 250
+	memcachedMaxValueLength = 1024 * 1024
+	memcachedMaxLineLength  = 2048
+	memcachedRelativeExpire = 60 * 60 * 24 * 30
+	memcachedVersion        = "1.6.0-memkey"
+)
+
+var (
+	errMemcachedLine  = errors.New("line too long")
+	errMemcachedValue = errors.New("bad data chunk")
+)
+
+// memcachedStats represents counters reported by stats command
+type memcachedStats struct {
+	connections atomic.Uint64
+	totalItems  atomic.Uint64
+	cmdGet      atomic.Uint64
+	cmdSet      atomic.Uint64
+	cmdTouch    atomic.Uint64
+	getHits     atomic.Uint64
+	getMisses   atomic.Uint64
+}
+
+// memcachedItems represents side table of flags and versions of keys, versions are used as CAS values and are changed
+// by every write of the key
+type memcachedItems struct {
+	lock sync.Mutex
+	// last is the last assigned version, it's never reset, so versions of keys are never reused
+	last uint64
+	keys map[string]memcachedItem
+}
+
+// memcachedItem represents flags and version of a key
+type memcachedItem struct {
+	flags   uint32
+	version uint64
+}
+
+// bump sets a new version of key and resets its flags, flags of values written by the server are set after the write
+func (i *memcachedItems) bump(key string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.last++
+	i.keys[key] = memcachedItem{version: i.last}
+}
+
+// setFlags sets flags of key keeping its version, if key was deleted in the meantime nothing is changed
+func (i *memcachedItems) setFlags(key string, flags uint32) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if item, ok := i.keys[key]; ok {
+		item.flags = flags
+		i.keys[key] = item
+	}
+}
+
+// remove removes flags and version of deleted key
+func (i *memcachedItems) remove(key string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	delete(i.keys, key)
+}
+
+// reset removes flags and versions of all keys
+func (i *memcachedItems) reset() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.keys = make(map[string]memcachedItem)
+}
+
+// get returns flags and version of stored key, keys that were not written through hooks of the server (for example,
+// restored from AOF) get a new version
+func (i *memcachedItems) get(key string) memcachedItem {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	item, ok := i.keys[key]
+	if !ok {
+		i.last++
+		item = memcachedItem{version: i.last}
+		i.keys[key] = item
+	}
+	return item
+}
+
+// Memcached serves TypedStore of byte slices over memcached text protocol, it supports get, gets, set, add, replace,
+// append, prepend, cas, delete, incr, decr, touch, stats, version and quit commands. Flags and CAS values are kept
+// by the server in a side table, CAS values are versions of keys that are changed by every write of the store,
+// including writes made to the store directly, such writes reset flags to zero. Commands that check current value
+// before writing are atomic only with respect to other commands of the same server.
+// Values expire only if ExpireTTL of the store is running
+type Memcached struct {
+	store  *memkey.TypedStore[string, []byte]
+	server *tcpServer
+	unhook []func()
+
+	lock  sync.Mutex
+	items memcachedItems
+
+	started time.Time
+	stats   memcachedStats
+}
+
+// NewMemcached creates memcached server over TypedStore of byte slices, the server registers hooks of the store to
+// track flags and versions of keys, they are removed by Close
+func NewMemcached(store *memkey.TypedStore[string, []byte]) *Memcached {
+	m := &Memcached{
+		store:   store,
+		items:   memcachedItems{keys: make(map[string]memcachedItem)},
+		started: time.Now(),
+	}
+	m.server = newTCPServer(m.serveConn)
+
+	bump := func(key string, _, _ []byte) { m.items.bump(key) }
+	remove := func(key string, _, _ []byte) { m.items.remove(key) }
+	m.unhook = []func(){
+		store.OnSet(bump, memkey.HookSync),
+		store.OnDelete(remove, memkey.HookSync),
+		store.OnExpire(remove, memkey.HookSync),
+		store.OnEvict(remove, memkey.HookSync),
+		store.OnClear(m.items.reset),
+	}
+
+	return m
+}
+
+// ListenAndServe listens on TCP address and serves connections until server is closed
+func (m *Memcached) ListenAndServe(addr string) error {
+	return m.server.listenAndServe(addr)
+}
+
+// Serve serves connections accepted by listener until server is closed
+func (m *Memcached) Serve(listener net.Listener) error {
+	return m.server.serve(listener)
+}
+
+// Close closes all listeners and connections and removes hooks of the store
+func (m *Memcached) Close() error {
+	for _, unhook := range m.unhook {
+		unhook()
+	}
+	return m.server.close()
+}
+
+func (m *Memcached) serveConn(_ net.Conn, r *bufio.Reader, w *bufio.Writer) {
+	m.stats.connections.Add(1)
+
+	for {
+		line, err := readMemcachedLine(r)
+		if err != nil {
+			if errors.Is(err, errMemcachedLine) {
+				_, _ = w.WriteString("CLIENT_ERROR " + err.Error() + "\r\n")
+				_ = w.Flush()
+			}
+			return
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			_, _ = w.WriteString("ERROR\r\n")
+		} else if !m.execute(r, w, args) {
+			_ = w.Flush()
+			return
+		}
+
+		if r.Buffered() == 0 {
+			if err = w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// execute executes single command and returns false if connection should be closed
+func (m *Memcached) execute(r *bufio.Reader, w *bufio.Writer, args []string) bool {
+	name, args := args[0], args[1:]
+
+	var reply string
+	switch name {
+	case "get", "gets":
+		m.get(w, args, name == "gets")
+		return true
+	case "set", "add", "replace", "append", "prepend", "cas":
+		var ok bool
+		reply, ok = m.storage(r, name, args)
+		if !ok {
+			_, _ = w.WriteString(reply)
+			return false
+		}
+	case "delete":
+		reply = m.delete(args)
+	case "incr", "decr":
+		reply = m.incr(args, name == "incr")
+	case "touch":
+		reply = m.touch(args)
+	case "stats":
+		m.writeStats(w)
+		return true
+	case "version":
+		reply = "VERSION " + memcachedVersion + "\r\n"
+	case "quit":
+		return false
+	default:
+		reply = "ERROR\r\n"
+	}
+
+	if !isNoReply(args) || strings.HasPrefix(reply, "CLIENT_ERROR") || reply == "ERROR\r\n" {
+		_, _ = w.WriteString(reply)
+	}
+
+	return true
+}
+
+func (m *Memcached) get(w *bufio.Writer, keys []string, withCAS bool) {
+	if len(keys) == 0 {
+		_, _ = w.WriteString("ERROR\r\n")
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, key := range keys {
+		m.stats.cmdGet.Add(1)
+
+		value, ok := m.store.Get(key)
+		if !ok {
+			m.stats.getMisses.Add(1)
+			continue
+		}
+		m.stats.getHits.Add(1)
+
+		item := m.items.get(key)
+		_, _ = w.WriteString("VALUE " + key + " " + strconv.FormatUint(uint64(item.flags), 10) + " " +
+			strconv.Itoa(len(value)))
+		if withCAS {
+			_, _ = w.WriteString(" " + strconv.FormatUint(item.version, 10))
+		}
+		_, _ = w.WriteString("\r\n")
+		_, _ = w.Write(value)
+		_, _ = w.WriteString("\r\n")
+	}
+
+	_, _ = w.WriteString("END\r\n")
+}
+
+// storage handles storage commands, returns false if connection should be closed because data can't be read
+//
+//nolint:gocyclo,cyclop,funlen // Storage commands share parsing and differ only in conditions
+func (m *Memcached) storage(r *bufio.Reader, name string, args []string) (string, bool) {
+	required := 4
+	if name == "cas" {
+		required = 5
+	}
+
+	if len(args) < required || len(args) > required+1 {
+		return "ERROR\r\n", true
+	}
+	if len(args) > required && !isNoReply(args) {
+		return "CLIENT_ERROR bad command line format\r\n", true
+	}
+
+	key := args[0]
+	flags, errFlags := strconv.ParseUint(args[1], 10, 32)
+	exptime, errExptime := strconv.ParseInt(args[2], 10, 64)
+	length, errLength := strconv.Atoi(args[3])
+	if errFlags != nil || errExptime != nil || errLength != nil || length < 0 {
+		return "CLIENT_ERROR bad command line format\r\n", true
+	}
+
+	var casUnique uint64
+	if name == "cas" {
+		var err error
+		if casUnique, err = strconv.ParseUint(args[4], 10, 64); err != nil {
+			return "CLIENT_ERROR bad command line format\r\n", true
+		}
+	}
+
+	if length > memcachedMaxValueLength {
+		// Skip data to keep connection usable
+		if _, err := io.CopyN(io.Discard, r, int64(length)+2); err != nil {
+			return "", false
+		}
+		return "SERVER_ERROR object too large for cache\r\n", true
+	}
+
+	data := make([]byte, length+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", false
+	}
+
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		return "CLIENT_ERROR " + errMemcachedValue.Error() + "\r\n", false
+	}
+	data = data[:length]
+
+	if !validMemcachedKey(key) {
+		return "CLIENT_ERROR bad command line format\r\n", true
+	}
+
+	m.stats.cmdSet.Add(1)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	current, exists := m.store.Get(key)
+
+	switch name {
+	case "add":
+		if exists {
+			return "NOT_STORED\r\n", true
+		}
+	case "replace":
+		if !exists {
+			return "NOT_STORED\r\n", true
+		}
+	case "append", "prepend":
+		if !exists {
+			return "NOT_STORED\r\n", true
+		}
+
+		value := make([]byte, 0, len(current)+len(data))
+		if name == "append" {
+			value = append(append(value, current...), data...)
+		} else {
+			value = append(append(value, data...), current...)
+		}
+
+		if err := m.setKeepTTL(key, value, m.items.get(key).flags); err != nil {
+			return memcachedServerError(err), true
+		}
+		return "STORED\r\n", true
+	case "cas":
+		if !exists {
+			return "NOT_FOUND\r\n", true
+		}
+		if m.items.get(key).version != casUnique {
+			return "EXISTS\r\n", true
+		}
+	default:
+	}
+
+	stored, err := m.set(key, data, uint32(flags), exptime)
+	if err != nil {
+		return memcachedServerError(err), true
+	}
+	if stored {
+		m.stats.totalItems.Add(1)
+	}
+
+	return "STORED\r\n", true
+}
+
+func (m *Memcached) delete(args []string) string {
+	if len(args) < 1 || len(args) > 2 {
+		return "ERROR\r\n"
+	}
+	if len(args) > 1 && !isNoReply(args) {
+		return "CLIENT_ERROR bad command line format\r\n"
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.store.Delete(args[0]) {
+		return "NOT_FOUND\r\n"
+	}
+
+	return "DELETED\r\n"
+}
+
+func (m *Memcached) incr(args []string, increment bool) string {
+	if len(args) < 2 || len(args) > 3 {
+		return "ERROR\r\n"
+	}
+	if len(args) > 2 && !isNoReply(args) {
+		return "CLIENT_ERROR bad command line format\r\n"
+	}
+
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return "CLIENT_ERROR invalid numeric delta argument\r\n"
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	current, ok := m.store.Get(args[0])
+	if !ok {
+		return "NOT_FOUND\r\n"
+	}
+
+	number, err := strconv.ParseUint(string(current), 10, 64)
+	if err != nil {
+		return "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"
+	}
+
+	switch {
+	case increment:
+		// Overflow wraps around as in memcached
+		number += delta
+	case delta > number:
+		number = 0
+	default:
+		number -= delta
+	}
+
+	value := []byte(strconv.FormatUint(number, 10))
+	if err = m.setKeepTTL(args[0], value, m.items.get(args[0]).flags); err != nil {
+		return memcachedServerError(err)
+	}
+
+	return string(value) + "\r\n"
+}
+
+func (m *Memcached) touch(args []string) string {
+	if len(args) < 2 || len(args) > 3 {
+		return "ERROR\r\n"
+	}
+	if len(args) > 2 && !isNoReply(args) {
+		return "CLIENT_ERROR bad command line format\r\n"
+	}
+
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return "CLIENT_ERROR invalid exptime argument\r\n"
+	}
+
+	m.stats.cmdTouch.Add(1)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	value, ok := m.store.Get(args[0])
+	if !ok {
+		return "NOT_FOUND\r\n"
+	}
+
+	if _, err = m.set(args[0], value, m.items.get(args[0]).flags, exptime); err != nil {
+		return memcachedServerError(err)
+	}
+	return "TOUCHED\r\n"
+}
+
+// set stores value with flags and expiration time, returns false if value expired immediately or error if write is
+// rejected by the store, must be called while server lock is held
+func (m *Memcached) set(key string, value []byte, flags uint32, exptime int64) (bool, error) {
+	var ttl time.Duration
+	switch {
+	case exptime < 0:
+		m.store.Delete(key)
+		return false, nil
+	case exptime == 0:
+	case exptime <= memcachedRelativeExpire:
+		ttl = time.Duration(exptime) * time.Second
+	default:
+		ttl = time.Until(time.Unix(exptime, 0))
+		if ttl <= 0 {
+			m.store.Delete(key)
+			return false, nil
+		}
+	}
+
+	var err error
+	if ttl > 0 {
+		err = m.store.TrySetWithTTL(key, value, ttl)
+	} else {
+		err = m.store.TrySet(key, value)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Hook of the write has reset flags, so they are set after it
+	m.items.setFlags(key, flags)
+	return true, nil
+}
+
+// setKeepTTL stores value with flags keeping its current TTL, returns error if write is rejected by the store, must
+// be called while server lock is held
+func (m *Memcached) setKeepTTL(key string, value []byte, flags uint32) error {
+	deadline, _ := m.store.Deadline(key)
+
+	var err error
+	if deadline.IsZero() {
+		err = m.store.TrySet(key, value)
+	} else {
+		ttl := time.Until(deadline)
+		if ttl <= 0 {
+			ttl = time.Nanosecond
+		}
+		err = m.store.TrySetWithTTL(key, value, ttl)
+	}
+	if err != nil {
+		return err
+	}
+
+	m.items.setFlags(key, flags)
+	return nil
+}
+
+// memcachedServerError returns reply to command with write rejected by the store
+func memcachedServerError(err error) string {
+	return "SERVER_ERROR " + err.Error() + "\r\n"
+}
+
+func (m *Memcached) writeStats(w *bufio.Writer) {
+	now := time.Now()
+	stats := []struct {
+		name  string
+		value any
+	}{
+		{"pid", os.Getpid()},
+		{"uptime", int64(now.Sub(m.started).Seconds())},
+		{"time", now.Unix()},
+		{"version", memcachedVersion},
+		{"curr_connections", m.server.connections()},
+		{"total_connections", m.stats.connections.Load()},
+		{"curr_items", m.store.Len()},
+		{"total_items", m.stats.totalItems.Load()},
+		{"cmd_get", m.stats.cmdGet.Load()},
+		{"cmd_set", m.stats.cmdSet.Load()},
+		{"cmd_touch", m.stats.cmdTouch.Load()},
+		{"get_hits", m.stats.getHits.Load()},
+		{"get_misses", m.stats.getMisses.Load()},
+	}
+
+	for _, stat := range stats {
+		_, _ = fmt.Fprintf(w, "STAT %s %v\r\n", stat.name, stat.value)
+	}
+	_, _ = w.WriteString("END\r\n")
+}
+
+// validMemcachedKey checks that key has valid length and has no control characters or whitespace
+func validMemcachedKey(key string) bool {
+	if len(key) == 0 || len(key) > memcachedMaxKeyLength {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isNoReply checks if the last argument of command is noreply
+func isNoReply(args []string) bool {
+	return len(args) > 0 && args[len(args)-1] == "noreply"
+}
+
+// readMemcachedLine reads command line without CRLF terminator
+func readMemcachedLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+
+		line = append(line, chunk...)
+		if len(line) > memcachedMaxLineLength {
+			return "", errMemcachedLine
+		}
+
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymmrac/memkey"
+)
+
+type testMemcachedClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func startMemcached(t *testing.T, s *memkey.TypedStore[string, []byte]) *testMemcachedClient {
+	t.Helper()
+
+	m := NewMemcached(s)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() { _ = m.Serve(listener) }()
+	t.Cleanup(func() { _ = m.Close() })
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, conn.SetDeadline(time.Now().Add(time.Second*5)))
+
+	return &testMemcachedClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// do sends request and reads specified number of reply lines
+func (c *testMemcachedClient) do(request string, lines int) []string {
+	c.t.Helper()
+
+	_, err := c.conn.Write([]byte(request))
+	require.NoError(c.t, err)
+
+	reply := make([]string, 0, lines)
+	for i := 0; i < lines; i++ {
+		line, err := c.r.ReadString('\n')
+		require.NoError(c.t, err)
+		reply = append(reply, strings.TrimSuffix(line, "\r\n"))
+	}
+
+	return reply
+}
+
+func TestMemcached_Storage(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	c := startMemcached(t, s)
+
+	assert.Equal(t, []string{"END"}, c.do("get a\r\n", 1))
+	assert.Equal(t, []string{"STORED"}, c.do("set a 5 0 3\r\nabc\r\n", 1))
+	assert.Equal(t, []string{"VALUE a 5 3", "abc", "END"}, c.do("get a\r\n", 3))
+
+	value, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("abc"), value)
+
+	assert.Equal(t, []string{"NOT_STORED"}, c.do("add a 0 0 1\r\nx\r\n", 1))
+	assert.Equal(t, []string{"STORED"}, c.do("add b 0 0 1\r\nx\r\n", 1))
+	assert.Equal(t, []string{"NOT_STORED"}, c.do("replace c 0 0 1\r\nx\r\n", 1))
+	assert.Equal(t, []string{"STORED"}, c.do("replace b 0 0 1\r\ny\r\n", 1))
+	assert.Equal(t, []string{"STORED"}, c.do("append a 0 0 2\r\nde\r\n", 1))
+	assert.Equal(t, []string{"STORED"}, c.do("prepend a 0 0 2\r\n12\r\n", 1))
+	assert.Equal(t, []string{"NOT_STORED"}, c.do("append c 0 0 2\r\nde\r\n", 1))
+
+	assert.Equal(t, []string{"VALUE a 5 7", "12abcde", "VALUE b 0 1", "y", "END"}, c.do("get a b c\r\n", 5))
+
+	assert.Equal(t, []string{"DELETED"}, c.do("delete b\r\n", 1))
+	assert.Equal(t, []string{"NOT_FOUND"}, c.do("delete b\r\n", 1))
+
+	c.do("set q 0 0 1 noreply\r\nq\r\n", 0)
+	assert.Equal(t, []string{"VALUE q 0 1", "q", "END"}, c.do("get q\r\n", 3))
+
+	assert.Equal(t, []string{"ERROR"}, c.do("unknown\r\n", 1))
+	assert.Equal(t, []string{"CLIENT_ERROR bad command line format"}, c.do("set a x 0 1\r\n", 1))
+
+	// Extra token must be noreply
+	assert.Equal(t, []string{"CLIENT_ERROR bad command line format"}, c.do("set a 0 0 1 x\r\n", 1))
+	assert.Equal(t, []string{"CLIENT_ERROR bad command line format"}, c.do("delete a x\r\n", 1))
+	assert.Equal(t, []string{"CLIENT_ERROR bad command line format"}, c.do("incr a 1 x\r\n", 1))
+	assert.Equal(t, []string{"CLIENT_ERROR bad command line format"}, c.do("touch a 1 x\r\n", 1))
+	assert.Equal(t, []string{"VALUE a 5 7", "12abcde", "END"}, c.do("get a\r\n", 3))
+}
+
+func TestMemcached_CAS(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	c := startMemcached(t, s)
+
+	assert.Equal(t, []string{"NOT_FOUND"}, c.do("cas a 0 0 1 1\r\nx\r\n", 1))
+	c.do("set a 0 0 1\r\nx\r\n", 1)
+
+	reply := c.do("gets a\r\n", 3)
+	fields := strings.Fields(reply[0])
+	require.Len(t, fields, 5)
+	casUnique := fields[4]
+
+	assert.Equal(t, []string{"STORED"}, c.do("cas a 0 0 1 "+casUnique+"\r\ny\r\n", 1))
+	assert.Equal(t, []string{"EXISTS"}, c.do("cas a 0 0 1 "+casUnique+"\r\nz\r\n", 1))
+
+	reply = c.do("gets a\r\n", 3)
+	casUnique = strings.Fields(reply[0])[4]
+
+	s.Set("a", []byte("external"))
+	assert.Equal(t, []string{"EXISTS"}, c.do("cas a 0 0 1 "+casUnique+"\r\nz\r\n", 1))
+
+	reply = c.do("gets a\r\n", 3)
+	casUnique = strings.Fields(reply[0])[4]
+
+	// Value is changed and then restored, so only version can detect the change
+	s.Set("a", []byte("other"))
+	s.Set("a", []byte("external"))
+	assert.Equal(t, []string{"EXISTS"}, c.do("cas a 0 0 1 "+casUnique+"\r\nz\r\n", 1))
+
+	// Direct writes reset flags
+	c.do("set a 7 0 1\r\nx\r\n", 1)
+	assert.Equal(t, []string{"VALUE a 7 1", "x", "END"}, c.do("get a\r\n", 3))
+	s.Set("a", []byte("y"))
+	assert.Equal(t, []string{"VALUE a 0 1", "y", "END"}, c.do("get a\r\n", 3))
+
+	// Versions are not reused after the store is cleared, even for values restored without hooks
+	config := memkey.AOFConfig{Path: filepath.Join(t.TempDir(), "store.aof")}
+	source := &memkey.TypedStore[string, []byte]{}
+	aof, err := source.OpenAOF(config)
+	require.NoError(t, err)
+	source.Set("a", []byte("y"))
+	require.NoError(t, aof.Close())
+
+	reply = c.do("gets a\r\n", 3)
+	casUnique = strings.Fields(reply[0])[4]
+	s.Clear()
+
+	aof, err = s.OpenAOF(config)
+	require.NoError(t, err)
+	defer func() { _ = aof.Close() }()
+
+	assert.Equal(t, []string{"EXISTS"}, c.do("cas a 0 0 1 "+casUnique+"\r\nz\r\n", 1))
+}
+
+func TestMemcached_IncrDecr(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	c := startMemcached(t, s)
+
+	assert.Equal(t, []string{"NOT_FOUND"}, c.do("incr a 1\r\n", 1))
+	c.do("set a 0 0 2\r\n10\r\n", 1)
+	assert.Equal(t, []string{"15"}, c.do("incr a 5\r\n", 1))
+	assert.Equal(t, []string{"5"}, c.do("decr a 10\r\n", 1))
+	assert.Equal(t, []string{"0"}, c.do("decr a 10\r\n", 1))
+
+	c.do("set b 0 0 1\r\nx\r\n", 1)
+	assert.Equal(t, []string{"CLIENT_ERROR cannot increment or decrement non-numeric value"}, c.do("incr b 1\r\n", 1))
+}
+
+func TestMemcached_Expiration(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	c := startMemcached(t, s)
+
+	c.do("set a 0 100 1\r\nx\r\n", 1)
+	deadline, ok := s.Deadline("a")
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Second*100), deadline, time.Second)
+
+	c.do("append a 0 0 1\r\ny\r\n", 1)
+	deadline, _ = s.Deadline("a")
+	assert.WithinDuration(t, time.Now().Add(time.Second*100), deadline, time.Second)
+
+	absolute := time.Now().Add(time.Hour).Unix()
+	c.do("set b 0 "+strconv.FormatInt(absolute, 10)+" 1\r\nx\r\n", 1)
+	deadline, _ = s.Deadline("b")
+	assert.WithinDuration(t, time.Unix(absolute, 0), deadline, time.Second)
+
+	assert.Equal(t, []string{"TOUCHED"}, c.do("touch a 0\r\n", 1))
+	deadline, _ = s.Deadline("a")
+	assert.True(t, deadline.IsZero())
+	assert.Equal(t, []string{"NOT_FOUND"}, c.do("touch c 10\r\n", 1))
+
+	assert.Equal(t, []string{"STORED"}, c.do("set a 0 -1 1\r\nx\r\n", 1))
+	assert.False(t, s.Has("a"))
+}
+
+func TestMemcached_RejectedWrite(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	c := startMemcached(t, s)
+
+	assert.Equal(t, []string{"STORED"}, c.do("set a 0 0 1\r\n1\r\n", 1))
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, []string{"SERVER_ERROR memkey: closed"}, c.do("set b 0 0 1\r\nx\r\n", 1))
+	assert.Equal(t, []string{"SERVER_ERROR memkey: closed"}, c.do("append a 0 0 1\r\nx\r\n", 1))
+	assert.Equal(t, []string{"SERVER_ERROR memkey: closed"}, c.do("incr a 1\r\n", 1))
+	assert.Equal(t, []string{"VALUE a 0 1", "1", "END"}, c.do("get a b\r\n", 3))
+}
+
+func TestMemcached_Stats(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	c := startMemcached(t, s)
+
+	c.do("set a 0 0 1\r\nx\r\n", 1)
+	c.do("get a b\r\n", 3)
+
+	_, err := c.conn.Write([]byte("stats\r\n"))
+	require.NoError(t, err)
+
+	stats := make(map[string]string)
+	for {
+		line, err := c.r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\r\n")
+		if line == "END" {
+			break
+		}
+
+		fields := strings.Fields(line)
+		require.Len(t, fields, 3)
+		stats[fields[1]] = fields[2]
+	}
+
+	assert.Equal(t, "1", stats["curr_items"])
+	assert.Equal(t, "2", stats["cmd_get"])
+	assert.Equal(t, "1", stats["get_hits"])
+	assert.Equal(t, "1", stats["get_misses"])
+
+	assert.Equal(t, []string{"VERSION " + memcachedVersion}, c.do("version\r\n", 1))
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+
+	"github.com/mymmrac/memkey"
+	"github.com/mymmrac/memkey/internal/wire"
+)
+
+// Native serves TypedStore of byte slices over memkey framed protocol used by client package, requests of single
+// connection are executed in order, so clients may pipeline them. Writes rejected by the store are answered with
+// error status. Values expire only if ExpireTTL of the store is running
+type Native struct {
+	store  *memkey.TypedStore[string, []byte]
+	server *tcpServer
+}
+
+// NewNative creates server over TypedStore of byte slices
+func NewNative(store *memkey.TypedStore[string, []byte]) *Native {
+	n := &Native{store: store}
+	n.server = newTCPServer(n.serveConn)
+	return n
+}
+
+// ListenAndServe listens on TCP address and serves connections until server is closed
+func (n *Native) ListenAndServe(addr string) error {
+	return n.server.listenAndServe(addr)
+}
+
+// Serve serves connections accepted by listener until server is closed
+func (n *Native) Serve(listener net.Listener) error {
+	return n.server.serve(listener)
+}
+
+// Close closes all listeners and connections
+func (n *Native) Close() error {
+	return n.server.close()
+}
+
+func (n *Native) serveConn(_ net.Conn, r *bufio.Reader, w *bufio.Writer) {
+	for {
+		req, err := wire.ReadRequest(r)
+		if err != nil {
+			return
+		}
+
+		if err = wire.WriteResponse(w, n.execute(req)); err != nil {
+			_ = wire.WriteResponse(w, wire.Response{Status: wire.StatusError, Value: []byte(err.Error())})
+		}
+
+		if r.Buffered() == 0 {
+			if err = w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (n *Native) execute(req wire.Request) wire.Response {
+	switch req.Op {
+	case wire.OpPing:
+		return wire.Response{Status: wire.StatusOK}
+	case wire.OpGet:
+		value, ok := n.store.Get(req.Key)
+		return wire.Response{Status: status(ok), Value: value}
+	case wire.OpSet:
+		var err error
+		if req.TTL > 0 {
+			err = n.store.TrySetWithTTL(req.Key, req.Value, req.TTL)
+		} else {
+			err = n.store.TrySet(req.Key, req.Value)
+		}
+		if err != nil {
+			return wire.Response{Status: wire.StatusError, Value: []byte(err.Error())}
+		}
+		return wire.Response{Status: wire.StatusOK}
+	case wire.OpDelete:
+		return wire.Response{Status: status(n.store.Delete(req.Key))}
+	case wire.OpHas:
+		return wire.Response{Status: status(n.store.Has(req.Key))}
+	case wire.OpKeys:
+		return wire.Response{Status: wire.StatusOK, Keys: n.store.Keys()}
+	case wire.OpLen:
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(n.store.Len()))
+		return wire.Response{Status: wire.StatusOK, Value: value}
+	default:
+		return wire.Response{Status: wire.StatusError, Value: []byte("unknown operation")}
+	}
+}
+
+// status returns StatusOK if ok is true and StatusNotFound otherwise
+func status(ok bool) wire.Status {
+	if ok {
+		return wire.StatusOK
+	}
+	return wire.StatusNotFound
+}
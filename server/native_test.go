@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymmrac/memkey"
+	"github.com/mymmrac/memkey/internal/wire"
+)
+
+func startNative(t *testing.T, s *memkey.TypedStore[string, []byte]) (*bufio.Reader, *bufio.Writer) {
+	t.Helper()
+
+	n := NewNative(s)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() { _ = n.Serve(listener) }()
+	t.Cleanup(func() { _ = n.Close() })
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, conn.SetDeadline(time.Now().Add(time.Second*5)))
+
+	return bufio.NewReader(conn), bufio.NewWriter(conn)
+}
+
+func TestNative(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	r, w := startNative(t, s)
+
+	requests := []wire.Request{
+		{Op: wire.OpPing},
+		{Op: wire.OpSet, Key: "a", Value: []byte("1")},
+		{Op: wire.OpSet, Key: "b", Value: []byte("2"), TTL: time.Minute},
+		{Op: wire.OpGet, Key: "a"},
+		{Op: wire.OpGet, Key: "c"},
+		{Op: wire.OpHas, Key: "b"},
+		{Op: wire.OpDelete, Key: "a"},
+		{Op: wire.OpDelete, Key: "a"},
+		{Op: wire.OpKeys},
+		{Op: wire.OpLen},
+		{Op: 0},
+	}
+
+	// All requests are pipelined
+	for _, req := range requests {
+		require.NoError(t, wire.WriteRequest(w, req))
+	}
+	require.NoError(t, w.Flush())
+
+	expected := []wire.Response{
+		{Status: wire.StatusOK, Value: []byte{}},
+		{Status: wire.StatusOK, Value: []byte{}},
+		{Status: wire.StatusOK, Value: []byte{}},
+		{Status: wire.StatusOK, Value: []byte("1")},
+		{Status: wire.StatusNotFound, Value: []byte{}},
+		{Status: wire.StatusOK, Value: []byte{}},
+		{Status: wire.StatusOK, Value: []byte{}},
+		{Status: wire.StatusNotFound, Value: []byte{}},
+		{Status: wire.StatusOK, Value: []byte{}, Keys: []string{"b"}},
+		{Status: wire.StatusOK, Value: []byte{0, 0, 0, 0, 0, 0, 0, 1}},
+		{Status: wire.StatusError, Value: []byte("unknown operation")},
+	}
+
+	for _, exp := range expected {
+		resp, err := wire.ReadResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, exp, resp)
+	}
+
+	deadline, ok := s.Deadline("b")
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
+
+func TestNative_RejectedWrite(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	s.Set("a", []byte("1"))
+	require.NoError(t, s.Close())
+
+	r, w := startNative(t, s)
+
+	requests := []wire.Request{
+		{Op: wire.OpSet, Key: "b", Value: []byte("2")},
+		{Op: wire.OpSet, Key: "a", Value: []byte("2"), TTL: time.Minute},
+		{Op: wire.OpGet, Key: "a"},
+	}
+	for _, req := range requests {
+		require.NoError(t, wire.WriteRequest(w, req))
+	}
+	require.NoError(t, w.Flush())
+
+	expected := []wire.Response{
+		{Status: wire.StatusError, Value: []byte("memkey: closed")},
+		{Status: wire.StatusError, Value: []byte("memkey: closed")},
+		{Status: wire.StatusOK, Value: []byte("1")},
+	}
+	for _, exp := range expected {
+		resp, err := wire.ReadResponse(r)
+		require.NoError(t, err)
+		assert.Equal(t, exp, resp)
+	}
+}
@@ -0,0 +1,659 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mymmrac/memkey"
+)
+
+const (
+	respMaxArgs       = 1024 * 1024
+	respMaxBulkLength = 512 * 1024 * 1024
+	respMaxInline     = 64 * 1024
+	// respPreallocArgs and respBulkChunk limit memory allocated from lengths in headers before payload arrives
+	respPreallocArgs = 16
+	respBulkChunk    = 64 * 1024
+	respDefaultScan   = 10
+)
+
+var (
+	errRESPProtocol = errors.New("protocol error")
+	errWrongType    = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+)
+
+// redisBackend represents store that is served over Redis protocol
+type redisBackend interface {
+	get(key string) ([]byte, bool, error)
+	set(key string, value []byte, ttl time.Duration) error
+	del(key string) bool
+	has(key string) bool
+	deadline(key string) (time.Time, bool)
+	persist(key string) bool
+	keys() []string
+	scan(cursor uint64, count int) ([]string, uint64)
+	len() int
+	typ(key string) (string, bool)
+}
+
+// redisStore serves Store, values are stored as strings
+type redisStore struct {
+	store *memkey.Store[string]
+}
+
+func (b redisStore) get(key string) ([]byte, bool, error) {
+	value, ok := b.store.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []byte(v), true, nil
+	case []byte:
+		return v, true, nil
+	default:
+		return nil, false, errWrongType
+	}
+}
+
+func (b redisStore) set(key string, value []byte, ttl time.Duration) error {
+	if ttl > 0 {
+		return b.store.TrySetWithTTL(key, string(value), ttl)
+	}
+	return b.store.TrySet(key, string(value))
+}
+
+func (b redisStore) del(key string) bool                   { return b.store.Delete(key) }
+func (b redisStore) has(key string) bool                   { return b.store.Has(key) }
+func (b redisStore) deadline(key string) (time.Time, bool) { return b.store.Deadline(key) }
+func (b redisStore) persist(key string) bool               { return b.store.Persist(key) }
+func (b redisStore) keys() []string                        { return b.store.Keys() }
+func (b redisStore) len() int                              { return b.store.Len() }
+func (b redisStore) typ(key string) (string, bool)         { return b.store.Type(key) }
+
+func (b redisStore) scan(cursor uint64, count int) ([]string, uint64) {
+	return b.store.Scan(cursor, count)
+}
+
+// redisTypedStore serves TypedStore of byte slices
+type redisTypedStore struct {
+	store *memkey.TypedStore[string, []byte]
+}
+
+func (b redisTypedStore) get(key string) ([]byte, bool, error) {
+	value, ok := b.store.Get(key)
+	return value, ok, nil
+}
+
+func (b redisTypedStore) set(key string, value []byte, ttl time.Duration) error {
+	if ttl > 0 {
+		return b.store.TrySetWithTTL(key, value, ttl)
+	}
+	return b.store.TrySet(key, value)
+}
+
+func (b redisTypedStore) del(key string) bool                   { return b.store.Delete(key) }
+func (b redisTypedStore) has(key string) bool                   { return b.store.Has(key) }
+func (b redisTypedStore) deadline(key string) (time.Time, bool) { return b.store.Deadline(key) }
+func (b redisTypedStore) persist(key string) bool               { return b.store.Persist(key) }
+func (b redisTypedStore) keys() []string                        { return b.store.Keys() }
+func (b redisTypedStore) len() int                              { return b.store.Len() }
+
+func (b redisTypedStore) scan(cursor uint64, count int) ([]string, uint64) {
+	return b.store.Scan(cursor, count)
+}
+
+func (b redisTypedStore) typ(key string) (string, bool) {
+	if !b.store.Has(key) {
+		return "", false
+	}
+	return "string", true
+}
+
+// Redis serves store over Redis serialization protocol (RESP2 and RESP3), it supports GET, SET (with EX and PX),
+// DEL, EXISTS, TTL, PTTL, PERSIST, KEYS, SCAN, DBSIZE, TYPE, PING, ECHO, HELLO, SELECT, CLIENT, COMMAND and QUIT
+// commands. Values expire only if ExpireTTL of the store is running
+type Redis struct {
+	backend redisBackend
+	server  *tcpServer
+}
+
+// NewRedis creates Redis server over Store, SET stores values as strings, GET returns string and []byte values and
+// replies with WRONGTYPE error for values of other types, TYPE replies with type name returned by Store.Type
+func NewRedis(store *memkey.Store[string]) *Redis {
+	return newRedis(redisStore{store: store})
+}
+
+// NewRedisTyped creates Redis server over TypedStore of byte slices
+func NewRedisTyped(store *memkey.TypedStore[string, []byte]) *Redis {
+	return newRedis(redisTypedStore{store: store})
+}
+
+func newRedis(backend redisBackend) *Redis {
+	r := &Redis{backend: backend}
+	r.server = newTCPServer(r.serveConn)
+	return r
+}
+
+// ListenAndServe listens on TCP address and serves connections until server is closed
+func (r *Redis) ListenAndServe(addr string) error {
+	return r.server.listenAndServe(addr)
+}
+
+// Serve serves connections accepted by listener until server is closed
+func (r *Redis) Serve(listener net.Listener) error {
+	return r.server.serve(listener)
+}
+
+// Close closes all listeners and connections
+func (r *Redis) Close() error {
+	return r.server.close()
+}
+
+// respConn represents state of single client connection
+type respConn struct {
+	w     *bufio.Writer
+	proto int
+	quit  bool
+}
+
+func (r *Redis) serveConn(_ net.Conn, reader *bufio.Reader, writer *bufio.Writer) {
+	conn := &respConn{w: writer, proto: 2}
+
+	for !conn.quit {
+		args, err := readRESPCommand(reader)
+		if err != nil {
+			if errors.Is(err, errRESPProtocol) {
+				conn.writeError("ERR Protocol error: " + err.Error())
+				_ = writer.Flush()
+			}
+			return
+		}
+
+		if len(args) > 0 {
+			r.execute(conn, args)
+		}
+
+		if reader.Buffered() == 0 {
+			if err = writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+
+	_ = writer.Flush()
+}
+
+//nolint:gocyclo,cyclop // Command dispatch is a flat switch
+func (r *Redis) execute(conn *respConn, args [][]byte) {
+	name := strings.ToUpper(string(args[0]))
+	args = args[1:]
+
+	switch name {
+	case "PING":
+		r.ping(conn, args)
+	case "ECHO":
+		if len(args) != 1 {
+			conn.writeArgsError(name)
+			return
+		}
+		conn.writeBulk(args[0])
+	case "HELLO":
+		r.hello(conn, args)
+	case "SELECT":
+		if len(args) != 1 {
+			conn.writeArgsError(name)
+			return
+		}
+		if string(args[0]) != "0" {
+			conn.writeError("ERR DB index is out of range")
+			return
+		}
+		conn.writeSimple("OK")
+	case "CLIENT":
+		conn.writeSimple("OK")
+	case "COMMAND":
+		conn.writeArrayHeader(0)
+	case "QUIT":
+		conn.writeSimple("OK")
+		conn.quit = true
+	case "GET":
+		r.get(conn, args)
+	case "SET":
+		r.set(conn, args)
+	case "DEL":
+		r.del(conn, args)
+	case "EXISTS":
+		r.exists(conn, args)
+	case "TTL", "PTTL":
+		r.ttl(conn, name, args)
+	case "PERSIST":
+		if len(args) != 1 {
+			conn.writeArgsError(name)
+			return
+		}
+		conn.writeBool(r.backend.persist(string(args[0])))
+	case "KEYS":
+		r.keys(conn, args)
+	case "SCAN":
+		r.scan(conn, args)
+	case "DBSIZE":
+		conn.writeInt(int64(r.backend.len()))
+	case "TYPE":
+		r.typ(conn, args)
+	default:
+		conn.writeError(fmt.Sprintf("ERR unknown command '%s'", name))
+	}
+}
+
+func (r *Redis) ping(conn *respConn, args [][]byte) {
+	switch len(args) {
+	case 0:
+		conn.writeSimple("PONG")
+	case 1:
+		conn.writeBulk(args[0])
+	default:
+		conn.writeArgsError("PING")
+	}
+}
+
+func (r *Redis) hello(conn *respConn, args [][]byte) {
+	if len(args) > 0 {
+		proto, err := strconv.Atoi(string(args[0]))
+		if err != nil || proto < 2 || proto > 3 {
+			conn.writeError("NOPROTO unsupported protocol version")
+			return
+		}
+		conn.proto = proto
+	}
+
+	fields := []struct {
+		key   string
+		value any
+	}{
+		{"server", "memkey"},
+		{"version", "1.0.0"},
+		{"proto", int64(conn.proto)},
+		{"id", int64(0)},
+		{"mode", "standalone"},
+		{"role", "master"},
+		{"modules", nil},
+	}
+
+	conn.writeMapHeader(len(fields))
+	for _, field := range fields {
+		conn.writeBulk([]byte(field.key))
+
+		switch v := field.value.(type) {
+		case string:
+			conn.writeBulk([]byte(v))
+		case int64:
+			conn.writeInt(v)
+		default:
+			conn.writeArrayHeader(0)
+		}
+	}
+}
+
+func (r *Redis) get(conn *respConn, args [][]byte) {
+	if len(args) != 1 {
+		conn.writeArgsError("GET")
+		return
+	}
+
+	value, ok, err := r.backend.get(string(args[0]))
+	if err != nil {
+		conn.writeError(err.Error())
+		return
+	}
+
+	if !ok {
+		conn.writeNull()
+		return
+	}
+
+	conn.writeBulk(value)
+}
+
+func (r *Redis) set(conn *respConn, args [][]byte) {
+	if len(args) < 2 {
+		conn.writeArgsError("SET")
+		return
+	}
+
+	var ttl time.Duration
+	for i := 2; i < len(args); i++ {
+		option := strings.ToUpper(string(args[i]))
+		if (option != "EX" && option != "PX") || i+1 >= len(args) || ttl != 0 {
+			conn.writeError("ERR syntax error")
+			return
+		}
+
+		i++
+		amount, err := strconv.ParseInt(string(args[i]), 10, 64)
+		if err != nil || amount <= 0 {
+			conn.writeError("ERR invalid expire time in 'set' command")
+			return
+		}
+
+		if option == "EX" {
+			ttl = time.Duration(amount) * time.Second
+		} else {
+			ttl = time.Duration(amount) * time.Millisecond
+		}
+	}
+
+	if err := r.backend.set(string(args[0]), args[1], ttl); err != nil {
+		conn.writeError("ERR " + err.Error())
+		return
+	}
+	conn.writeSimple("OK")
+}
+
+func (r *Redis) del(conn *respConn, args [][]byte) {
+	if len(args) == 0 {
+		conn.writeArgsError("DEL")
+		return
+	}
+
+	var count int64
+	for _, key := range args {
+		if r.backend.del(string(key)) {
+			count++
+		}
+	}
+
+	conn.writeInt(count)
+}
+
+func (r *Redis) exists(conn *respConn, args [][]byte) {
+	if len(args) == 0 {
+		conn.writeArgsError("EXISTS")
+		return
+	}
+
+	var count int64
+	for _, key := range args {
+		if r.backend.has(string(key)) {
+			count++
+		}
+	}
+
+	conn.writeInt(count)
+}
+
+func (r *Redis) ttl(conn *respConn, name string, args [][]byte) {
+	if len(args) != 1 {
+		conn.writeArgsError(name)
+		return
+	}
+
+	deadline, ok := r.backend.deadline(string(args[0]))
+	switch {
+	case !ok:
+		conn.writeInt(-2)
+	case deadline.IsZero():
+		conn.writeInt(-1)
+	default:
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		if name == "TTL" {
+			conn.writeInt(int64((remaining + time.Second/2) / time.Second))
+		} else {
+			conn.writeInt(remaining.Milliseconds())
+		}
+	}
+}
+
+func (r *Redis) keys(conn *respConn, args [][]byte) {
+	if len(args) != 1 {
+		conn.writeArgsError("KEYS")
+		return
+	}
+
+	pattern := string(args[0])
+
+	var keys []string
+	for _, key := range r.backend.keys() {
+		if memkey.MatchGlob(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	conn.writeArrayHeader(len(keys))
+	for _, key := range keys {
+		conn.writeBulk([]byte(key))
+	}
+}
+
+func (r *Redis) scan(conn *respConn, args [][]byte) {
+	if len(args) == 0 {
+		conn.writeArgsError("SCAN")
+		return
+	}
+
+	cursor, err := strconv.ParseUint(string(args[0]), 10, 64)
+	if err != nil {
+		conn.writeError("ERR invalid cursor")
+		return
+	}
+
+	pattern, count, typeName := "*", respDefaultScan, ""
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			conn.writeError("ERR syntax error")
+			return
+		}
+
+		value := string(args[i+1])
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = value
+		case "COUNT":
+			count, err = strconv.Atoi(value)
+			if err != nil || count < 1 {
+				conn.writeError("ERR syntax error")
+				return
+			}
+		case "TYPE":
+			typeName = value
+		default:
+			conn.writeError("ERR syntax error")
+			return
+		}
+	}
+
+	keys, next := r.backend.scan(cursor, count)
+
+	page := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !memkey.MatchGlob(pattern, key) {
+			continue
+		}
+
+		if typeName != "" {
+			if typ, ok := r.backend.typ(key); !ok || typ != typeName {
+				continue
+			}
+		}
+
+		page = append(page, key)
+	}
+
+	conn.writeArrayHeader(2)
+	conn.writeBulk([]byte(strconv.FormatUint(next, 10)))
+	conn.writeArrayHeader(len(page))
+	for _, key := range page {
+		conn.writeBulk([]byte(key))
+	}
+}
+
+func (r *Redis) typ(conn *respConn, args [][]byte) {
+	if len(args) != 1 {
+		conn.writeArgsError("TYPE")
+		return
+	}
+
+	typ, ok := r.backend.typ(string(args[0]))
+	if !ok {
+		typ = "none"
+	}
+
+	conn.writeSimple(typ)
+}
+
+func (c *respConn) writeSimple(s string) {
+	_, _ = c.w.WriteString("+" + s + "\r\n")
+}
+
+func (c *respConn) writeError(s string) {
+	_, _ = c.w.WriteString("-" + s + "\r\n")
+}
+
+func (c *respConn) writeArgsError(name string) {
+	c.writeError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+func (c *respConn) writeInt(n int64) {
+	_, _ = c.w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (c *respConn) writeBool(b bool) {
+	if b {
+		c.writeInt(1)
+	} else {
+		c.writeInt(0)
+	}
+}
+
+func (c *respConn) writeBulk(b []byte) {
+	_, _ = c.w.WriteString("$" + strconv.Itoa(len(b)) + "\r\n")
+	_, _ = c.w.Write(b)
+	_, _ = c.w.WriteString("\r\n")
+}
+
+func (c *respConn) writeNull() {
+	if c.proto == 3 {
+		_, _ = c.w.WriteString("_\r\n")
+		return
+	}
+	_, _ = c.w.WriteString("$-1\r\n")
+}
+
+func (c *respConn) writeArrayHeader(n int) {
+	_, _ = c.w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
+
+func (c *respConn) writeMapHeader(n int) {
+	if c.proto == 3 {
+		_, _ = c.w.WriteString("%" + strconv.Itoa(n) + "\r\n")
+		return
+	}
+	c.writeArrayHeader(n * 2)
+}
+
+// readRESPCommand reads command as array of bulk strings or as inline command
+func readRESPCommand(r *bufio.Reader) ([][]byte, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 || line[0] != '*' {
+		return bytes.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(string(line[1:]))
+	if err != nil || count < -1 || count > respMaxArgs {
+		return nil, fmt.Errorf("%w: invalid multibulk length", errRESPProtocol)
+	}
+
+	// Null array is an empty command
+	if count == -1 {
+		return nil, nil
+	}
+
+	args := make([][]byte, 0, minInt(count, respPreallocArgs))
+	for i := 0; i < count; i++ {
+		line, err = readRESPLine(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("%w: expected '$', got '%s'", errRESPProtocol, line)
+		}
+
+		var length int
+		length, err = strconv.Atoi(string(line[1:]))
+		if err != nil || length < 0 || length > respMaxBulkLength {
+			return nil, fmt.Errorf("%w: invalid bulk length", errRESPProtocol)
+		}
+
+		var arg []byte
+		arg, err = readRESPBulk(r, length)
+		if err != nil {
+			return nil, err
+		}
+
+		if arg[length] != '\r' || arg[length+1] != '\n' {
+			return nil, fmt.Errorf("%w: invalid bulk terminator", errRESPProtocol)
+		}
+
+		args = append(args, arg[:length])
+	}
+
+	return args, nil
+}
+
+// readRESPBulk reads bulk string of specified length with its terminator, buffer grows with received data, so
+// length from header doesn't allocate memory by itself
+func readRESPBulk(r *bufio.Reader, length int) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, minInt(length+2, respBulkChunk)))
+	if _, err := io.CopyN(buf, r, int64(length+2)); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// minInt returns the smaller of a and b
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// readRESPLine reads line terminated by CRLF (or LF) without terminator
+func readRESPLine(r *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+
+		line = append(line, chunk...)
+		if len(line) > respMaxInline {
+			return nil, fmt.Errorf("%w: too big inline request", errRESPProtocol)
+		}
+
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
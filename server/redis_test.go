@@ -0,0 +1,240 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mymmrac/memkey"
+)
+
+type testRESPClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func startRedis(t *testing.T, r *Redis) *testRESPClient {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() { _ = r.Serve(listener) }()
+	t.Cleanup(func() { _ = r.Close() })
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, conn.SetDeadline(time.Now().Add(time.Second*5)))
+
+	return &testRESPClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *testRESPClient) do(args ...string) any {
+	c.t.Helper()
+
+	cmd := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		cmd += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+
+	_, err := c.conn.Write([]byte(cmd))
+	require.NoError(c.t, err)
+
+	return c.read()
+}
+
+func (c *testRESPClient) read() any {
+	c.t.Helper()
+
+	line, err := c.r.ReadString('\n')
+	require.NoError(c.t, err)
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:]
+	case '-':
+		return fmt.Errorf("%s", line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		require.NoError(c.t, err)
+		return n
+	case '_':
+		return nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		require.NoError(c.t, err)
+		if n < 0 {
+			return nil
+		}
+		buf := make([]byte, n+2)
+		_, err = io.ReadFull(c.r, buf)
+		require.NoError(c.t, err)
+		return string(buf[:n])
+	case '*', '%':
+		n, err := strconv.Atoi(line[1:])
+		require.NoError(c.t, err)
+		if line[0] == '%' {
+			n *= 2
+		}
+		items := make([]any, n)
+		for i := range items {
+			items[i] = c.read()
+		}
+		return items
+	default:
+		require.FailNow(c.t, "unexpected reply", line)
+		return nil
+	}
+}
+
+func TestRedis_Store(t *testing.T) {
+	s := &memkey.Store[string]{}
+	c := startRedis(t, NewRedis(s))
+
+	assert.Equal(t, "PONG", c.do("PING"))
+	assert.Equal(t, "hi", c.do("PING", "hi"))
+
+	assert.Nil(t, c.do("GET", "a"))
+	assert.Equal(t, "OK", c.do("SET", "a", "1"))
+	assert.Equal(t, "1", c.do("GET", "a"))
+	assert.Equal(t, "1", memkey.MustGet[string](s, "a"))
+
+	memkey.Set(s, "n", 42)
+	assert.EqualError(t, c.do("GET", "n").(error), errWrongType.Error())
+	assert.Equal(t, "int", c.do("TYPE", "n"))
+	assert.Equal(t, "string", c.do("TYPE", "a"))
+	assert.Equal(t, "none", c.do("TYPE", "missing"))
+
+	require.NoError(t, memkey.Declare[int](s, "d"))
+	assert.ErrorContains(t, c.do("SET", "d", "1").(error), "ERR memkey: key d is declared with type int")
+	assert.False(t, s.Has("d"))
+
+	assert.Equal(t, int64(2), c.do("EXISTS", "a", "n", "missing"))
+	assert.Equal(t, int64(2), c.do("DBSIZE"))
+	assert.Equal(t, int64(1), c.do("DEL", "n", "missing"))
+	assert.Equal(t, int64(1), c.do("DBSIZE"))
+}
+
+func TestRedis_TTL(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	c := startRedis(t, NewRedisTyped(s))
+
+	assert.Equal(t, "OK", c.do("SET", "a", "1", "EX", "100"))
+	assert.Equal(t, int64(100), c.do("TTL", "a"))
+	assert.InDelta(t, int64(100000), c.do("PTTL", "a"), 1000)
+
+	assert.Equal(t, "OK", c.do("SET", "b", "2", "PX", "100000"))
+	assert.Equal(t, int64(100), c.do("TTL", "b"))
+
+	assert.Equal(t, int64(1), c.do("PERSIST", "a"))
+	assert.Equal(t, int64(0), c.do("PERSIST", "a"))
+	assert.Equal(t, int64(-1), c.do("TTL", "a"))
+	assert.Equal(t, int64(-2), c.do("TTL", "missing"))
+
+	assert.Error(t, c.do("SET", "a", "1", "EX", "0").(error))
+	assert.Error(t, c.do("SET", "a", "1", "NX").(error))
+	assert.Equal(t, "string", c.do("TYPE", "a"))
+}
+
+func TestRedis_KeysAndScan(t *testing.T) {
+	s := &memkey.TypedStore[string, []byte]{}
+	c := startRedis(t, NewRedisTyped(s))
+
+	for i := 0; i < 25; i++ {
+		s.Set(fmt.Sprintf("user:%02d", i), nil)
+	}
+	s.Set("other", nil)
+
+	assert.Len(t, c.do("KEYS", "user:*"), 25)
+	assert.ElementsMatch(t, []any{"user:01", "user:11", "user:21"}, c.do("KEYS", "user:?1"))
+
+	var keys []any
+	cursor := "0"
+	for {
+		reply := c.do("SCAN", cursor, "MATCH", "user:*", "COUNT", "7").([]any)
+		cursor = reply[0].(string)
+		keys = append(keys, reply[1].([]any)...)
+		if cursor == "0" {
+			break
+		}
+	}
+	assert.Len(t, keys, 25)
+}
+
+func TestRedis_Protocol(t *testing.T) {
+	s := &memkey.Store[string]{}
+	c := startRedis(t, NewRedis(s))
+
+	hello := c.do("HELLO", "3").([]any)
+	assert.Equal(t, "server", hello[0])
+	assert.Nil(t, c.do("GET", "missing"))
+
+	_, err := c.conn.Write([]byte("PING\r\nSET a b\r\nGET a\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", c.read())
+	assert.Equal(t, "OK", c.read())
+	assert.Equal(t, "b", c.read())
+
+	assert.Error(t, c.do("UNKNOWN").(error))
+	assert.Equal(t, "OK", c.do("QUIT"))
+}
+
+func TestRedis_ProtocolNullArray(t *testing.T) {
+	c := startRedis(t, NewRedis(&memkey.Store[string]{}))
+
+	_, err := c.conn.Write([]byte("*-1\r\n*0\r\nPING\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", c.read())
+
+	_, err = c.conn.Write([]byte("*-5\r\n"))
+	require.NoError(t, err)
+	assert.ErrorContains(t, c.read().(error), "invalid multibulk length")
+
+	_, err = c.r.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestReadRESPCommand_Limits(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	// Lengths from headers alone don't allocate memory
+	_, err := readRESPCommand(bufio.NewReader(strings.NewReader("*1048576\r\n$536870912\r\nabc")))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	runtime.ReadMemStats(&after)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1024*1024))
+
+	args, err := readRESPCommand(bufio.NewReader(strings.NewReader("*2\r\n$3\r\nGET\r\n$1\r\na\r\n")))
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("GET"), []byte("a")}, args)
+}
+
+func TestRedis_Close(t *testing.T) {
+	r := NewRedis(&memkey.Store[string]{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() { done <- r.Serve(listener) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	require.NoError(t, r.Close())
+	assert.ErrorIs(t, <-done, ErrServerClosed)
+}
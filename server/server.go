@@ -0,0 +1,142 @@
+/*
+Package server provides network servers that expose memkey stores over well-known protocols.
+*/
+package server
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+)
+
+// ErrServerClosed returned by Serve and ListenAndServe after Close was called
+var ErrServerClosed = errors.New("memkey/server: server closed")
+
+// handler serves single connection until it's closed or handler returns
+type handler func(conn net.Conn, r *bufio.Reader, w *bufio.Writer)
+
+// tcpServer accepts connections and tracks them to be closed on shutdown
+type tcpServer struct {
+	handle handler
+
+	lock      sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+func newTCPServer(handle handler) *tcpServer {
+	return &tcpServer{
+		handle:    handle,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// listenAndServe listens on TCP address and serves connections
+func (s *tcpServer) listenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.serve(listener)
+}
+
+// serve accepts connections on listener until listener fails or server is closed, listener is closed on return
+func (s *tcpServer) serve(listener net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		_ = listener.Close()
+		return ErrServerClosed
+	}
+	s.listeners[listener] = struct{}{}
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.listeners, listener)
+		s.lock.Unlock()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.lock.Lock()
+			closed := s.closed
+			s.lock.Unlock()
+
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		if !s.track(conn) {
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+// track adds connection to the list of active connections, returns false if server is closed
+func (s *tcpServer) track(conn net.Conn) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+// serveConn serves single connection and closes it on return
+func (s *tcpServer) serveConn(conn net.Conn) {
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+
+		_ = conn.Close()
+		s.wg.Done()
+	}()
+
+	s.handle(conn, bufio.NewReader(conn), bufio.NewWriter(conn))
+}
+
+// connections returns number of active connections
+func (s *tcpServer) connections() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.conns)
+}
+
+// close closes all listeners and connections and waits for connection handlers to return
+func (s *tcpServer) close() error {
+	s.lock.Lock()
+	s.closed = true
+
+	var err error
+	for listener := range s.listeners {
+		if closeErr := listener.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+	return err
+}
@@ -0,0 +1,235 @@
+package memkey
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SnapshotVersion is a version of snapshot format that is written by Save
+const SnapshotVersion = 1
+
+var (
+	// ErrSnapshotFormat returned when snapshot can't be loaded because of unknown format or version
+	ErrSnapshotFormat = errors.New("memkey: unsupported snapshot format")
+
+	// ErrNotRegistered returned when value of type that was not registered with Register is saved or loaded
+	ErrNotRegistered = errors.New("memkey: type not registered")
+)
+
+// Encoder represents encoder of values in snapshot
+type Encoder interface {
+	Encode(value any) error
+}
+
+// Decoder represents decoder of values in snapshot
+type Decoder interface {
+	Decode(value any) error
+}
+
+// Codec represents encoding used to save and load snapshots
+type Codec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// GobCodec encodes snapshots using encoding/gob
+type GobCodec struct{}
+
+// NewEncoder returns gob encoder
+func (GobCodec) NewEncoder(w io.Writer) Encoder {
+	return gob.NewEncoder(w)
+}
+
+// NewDecoder returns gob decoder
+func (GobCodec) NewDecoder(r io.Reader) Decoder {
+	return gob.NewDecoder(r)
+}
+
+// JSONCodec encodes snapshots using encoding/json, each record is written as a separate JSON value
+type JSONCodec struct{}
+
+// NewEncoder returns JSON encoder
+func (JSONCodec) NewEncoder(w io.Writer) Encoder {
+	return json.NewEncoder(w)
+}
+
+// NewDecoder returns JSON decoder
+func (JSONCodec) NewDecoder(r io.Reader) Decoder {
+	return json.NewDecoder(r)
+}
+
+const (
+	snapshotFormatTyped = "memkey/typed"
+	snapshotFormatStore = "memkey/store"
+)
+
+// snapshotHeader is the first record of every snapshot
+type snapshotHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	Entries int    `json:"entries"`
+}
+
+// storeSnapshotEntry is a single stored value in snapshot of Store, it's followed by the value encoded as registered
+// type, deadline is nil if value has no TTL
+type storeSnapshotEntry[K comparable] struct {
+	Key      K          `json:"key"`
+	Type     string     `json:"type"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// snapshotEntry is a single stored value in snapshot, deadline is nil if value has no TTL
+type snapshotEntry[K comparable, V any] struct {
+	Key      K          `json:"key"`
+	Value    V          `json:"value"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// writeSnapshot writes header and entries using specified codec
+func writeSnapshot[K comparable, V any](w io.Writer, codec Codec, format string, entries []snapshotEntry[K, V]) error {
+	encoder := codec.NewEncoder(w)
+	if err := encodeSnapshotHeader(encoder, format, len(entries)); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		if err := encoder.Encode(&entries[i]); err != nil {
+			return fmt.Errorf("memkey: encode snapshot entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// readSnapshot reads header and entries using specified codec, expired entries are skipped
+func readSnapshot[K comparable, V any](r io.Reader, codec Codec, format string) ([]snapshotEntry[K, V], error) {
+	decoder := codec.NewDecoder(r)
+	count, err := decodeSnapshotHeader(decoder, format)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var entries []snapshotEntry[K, V]
+	for i := 0; i < count; i++ {
+		var entry snapshotEntry[K, V]
+		if err = decoder.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("memkey: decode snapshot entry: %w", err)
+		}
+
+		if entry.Deadline != nil && !entry.Deadline.After(now) {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// encodeSnapshotHeader writes snapshot header of current version
+func encodeSnapshotHeader(encoder Encoder, format string, entries int) error {
+	if err := encoder.Encode(snapshotHeader{
+		Format:  format,
+		Version: SnapshotVersion,
+		Entries: entries,
+	}); err != nil {
+		return fmt.Errorf("memkey: encode snapshot header: %w", err)
+	}
+	return nil
+}
+
+// decodeSnapshotHeader reads snapshot header, checks its format and version and returns number of entries
+func decodeSnapshotHeader(decoder Decoder, format string) (int, error) {
+	var header snapshotHeader
+	if err := decoder.Decode(&header); err != nil {
+		return 0, fmt.Errorf("memkey: decode snapshot header: %w", err)
+	}
+
+	if header.Format != format || header.Version < 1 || header.Version > SnapshotVersion {
+		return 0, fmt.Errorf("%w: %q version %d", ErrSnapshotFormat, header.Format, header.Version)
+	}
+
+	return header.Entries, nil
+}
+
+// writeStoreSnapshot writes header and entries of Store, each entry is followed by its value encoded as registered
+// type
+func writeStoreSnapshot[K comparable](w io.Writer, codec Codec, entries []snapshotEntry[K, any]) error {
+	records := make([]storeSnapshotEntry[K], len(entries))
+	for i, entry := range entries {
+		registered, ok := registry.lookupValue(entry.Value)
+		if !ok {
+			return fmt.Errorf("%w: %T (key %v)", ErrNotRegistered, entry.Value, entry.Key)
+		}
+
+		records[i] = storeSnapshotEntry[K]{
+			Key:      entry.Key,
+			Type:     registered.name,
+			Deadline: entry.Deadline,
+		}
+	}
+
+	encoder := codec.NewEncoder(w)
+	if err := encodeSnapshotHeader(encoder, snapshotFormatStore, len(entries)); err != nil {
+		return err
+	}
+
+	for i := range records {
+		if err := encoder.Encode(&records[i]); err != nil {
+			return fmt.Errorf("memkey: encode snapshot entry: %w", err)
+		}
+
+		if err := encoder.Encode(entries[i].Value); err != nil {
+			return fmt.Errorf("memkey: encode snapshot value of %v: %w", entries[i].Key, err)
+		}
+	}
+
+	return nil
+}
+
+// readStoreSnapshot reads header and entries of Store, values are decoded as registered types, expired entries are
+// skipped
+func readStoreSnapshot[K comparable](r io.Reader, codec Codec) ([]snapshotEntry[K, any], error) {
+	decoder := codec.NewDecoder(r)
+	count, err := decodeSnapshotHeader(decoder, snapshotFormatStore)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var entries []snapshotEntry[K, any]
+	for i := 0; i < count; i++ {
+		var record storeSnapshotEntry[K]
+		if err = decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("memkey: decode snapshot entry: %w", err)
+		}
+
+		registered, ok := registry.lookupName(record.Type)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q (key %v)", ErrNotRegistered, record.Type, record.Key)
+		}
+
+		var value any
+		value, err = registered.decode(decoder)
+		if err != nil {
+			return nil, fmt.Errorf("memkey: decode snapshot value of %v: %w", record.Key, err)
+		}
+
+		if record.Deadline != nil && !record.Deadline.After(now) {
+			continue
+		}
+
+		entries = append(entries, snapshotEntry[K, any]{
+			Key:      record.Key,
+			Value:    value,
+			Deadline: record.Deadline,
+		})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,85 @@
+package memkey
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedStore_SaveAndLoad(t *testing.T) {
+	codecs := map[string]Codec{
+		"gob":  GobCodec{},
+		"json": JSONCodec{},
+	}
+
+	for name, codec := range codecs {
+		codec := codec
+		t.Run(name, func(t *testing.T) {
+			s := &TypedStore[string, int]{}
+			s.Set("a", 1)
+			s.SetWithTTL("b", 2, time.Hour)
+			s.SetWithTTL("c", 3, time.Millisecond)
+
+			buf := &bytes.Buffer{}
+			require.NoError(t, s.Save(buf, codec))
+
+			time.Sleep(time.Millisecond * 2)
+
+			loaded := &TypedStore[string, int]{}
+			require.NoError(t, loaded.Load(buf, codec))
+
+			assert.ElementsMatch(t, []Entry[string, int]{{"a", 1}, {"b", 2}}, loaded.Entries())
+
+			_, ok := loaded.ttl["a"]
+			assert.False(t, ok)
+			assert.WithinDuration(t, s.ttl["b"], loaded.ttl["b"], time.Millisecond)
+		})
+	}
+}
+
+func TestTypedStore_Load(t *testing.T) {
+	t.Run("unsupported_version", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, json.NewEncoder(buf).Encode(snapshotHeader{
+			Format:  snapshotFormatTyped,
+			Version: SnapshotVersion + 1,
+		}))
+
+		s := &TypedStore[string, int]{}
+		assert.ErrorIs(t, s.Load(buf, JSONCodec{}), ErrSnapshotFormat)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		s := &TypedStore[string, int]{}
+		s.Set("a", 1)
+
+		buf := &bytes.Buffer{}
+		require.NoError(t, s.Save(buf, JSONCodec{}))
+		buf.Truncate(buf.Len() - 4)
+
+		loaded := &TypedStore[string, int]{}
+		assert.Error(t, loaded.Load(buf, JSONCodec{}))
+		assert.Equal(t, 0, loaded.Len())
+	})
+
+	t.Run("ttl_cleared_by_set", func(t *testing.T) {
+		s := &TypedStore[string, int]{}
+		s.SetWithTTL("a", 1, time.Millisecond)
+		s.Set("a", 2)
+
+		buf := &bytes.Buffer{}
+		require.NoError(t, s.Save(buf, GobCodec{}))
+
+		time.Sleep(time.Millisecond * 2)
+
+		loaded := &TypedStore[string, int]{}
+		require.NoError(t, loaded.Load(buf, GobCodec{}))
+		value, ok := loaded.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 2, value)
+	})
+}
@@ -4,8 +4,10 @@ Package memkey provides basic type-safe, thread-safe in memory key-value store w
 package memkey
 
 import (
-	"fmt"
+	"io"
+	"reflect" //nolint:depguard // Declared types of keys are stored as reflect types
 	"sync"
+	"time"
 )
 
 // Store represents key-value storage with defined keys that is type-safe and thread-safe to use
@@ -13,6 +15,27 @@ type Store[K comparable] struct {
 	data map[K]any
 	init sync.Once
 	lock sync.RWMutex
+
+	initTTL sync.Once
+	ttl     map[K]time.Time
+
+	watchers watchers[K, any]
+	hooks    hooks[K, any]
+	journals []journal[K, any]
+	// cursors is created by the first scan
+	cursors *scanIndex[K]
+	types   typeIndex[K]
+
+	strict bool
+	schema map[K]reflect.Type
+
+	// namespaces contains state of namespaces created by NewNamespace, it's used only by stores with string keys
+	namespaces map[string]*namespaceState
+	// namespaceKeys is created by the first namespace and attached as a journal, it's used only by stores with
+	// string keys
+	namespaceKeys *namespaceIndex
+
+	lifecycle lifecycle
 }
 
 // Entry represents a pair of key and value that can be retrieved from Store
@@ -66,22 +89,44 @@ func (s *Store[K]) MustGet(key K) any {
 
 // Set stores value with the specified type in the store
 func Set[V any, K comparable](store *Store[K], key K, value V) {
-	store.lock.Lock()
-	defer store.lock.Unlock()
+	store.Set(key, value)
+}
 
-	store.init.Do(func() {
-		if store.data == nil {
-			store.data = make(map[K]any)
+// Set stores value in the store, previously set TTL of the key is removed. Value is not stored if it's rejected by
+// declared type of the key, use TrySet to get an error
+func (s *Store[K]) Set(key K, value any) {
+	_ = s.TrySet(key, value)
+}
+
+// TrySet stores value in the store, previously set TTL of the key is removed, returns error if write is rejected
+func (s *Store[K]) TrySet(key K, value any) error {
+	s.lock.Lock()
+
+	s.init.Do(func() {
+		if s.data == nil {
+			s.data = make(map[K]any)
 		}
 	})
 
-	store.data[key] = value
+	if err := s.checkWrite(key, value); err != nil {
+		s.lock.Unlock()
+		return err
+	}
+
+	oldValue := s.data[key]
+	s.data[key] = value
+	delete(s.ttl, key)
+	s.record(EventSet, key, value, time.Time{})
+	s.lock.Unlock()
+
+	s.emit(EventSet, key, oldValue, value)
+	return nil
 }
 
-// Set stores value in the store
-func (s *Store[K]) Set(key K, value any) {
+// update replaces value stored with key by result of f while the store lock is held, TTL of the key is kept.
+// If f returns error or new value is rejected by declared type, value is not changed and error is returned
+func (s *Store[K]) update(key K, f func(value any, ok bool) (any, error)) (any, error) {
 	s.lock.Lock()
-	defer s.lock.Unlock()
 
 	s.init.Do(func() {
 		if s.data == nil {
@@ -89,25 +134,170 @@ func (s *Store[K]) Set(key K, value any) {
 		}
 	})
 
+	oldValue, ok := s.data[key]
+	value, err := f(oldValue, ok)
+	if err == nil {
+		err = s.checkWrite(key, value)
+	}
+	if err != nil {
+		s.lock.Unlock()
+		return nil, err
+	}
+
+	s.data[key] = value
+	s.record(EventSet, key, value, s.ttl[key])
+	s.lock.Unlock()
+
+	s.emit(EventSet, key, oldValue, value)
+	return value, nil
+}
+
+// SetWithTTL stores value with the specified type in the store with TTL, expiration happens only if ExpireTTL was
+// called
+func SetWithTTL[V any, K comparable](store *Store[K], key K, value V, ttl time.Duration) {
+	store.SetWithTTL(key, value, ttl)
+}
+
+// SetWithTTL stores value in the store with TTL, expiration happens only if ExpireTTL was called. Value is not
+// stored if it's rejected by declared type of the key, use TrySetWithTTL to get an error
+func (s *Store[K]) SetWithTTL(key K, value any, ttl time.Duration) {
+	_ = s.TrySetWithTTL(key, value, ttl)
+}
+
+// TrySetWithTTL stores value in the store with TTL, returns error if write is rejected
+func (s *Store[K]) TrySetWithTTL(key K, value any, ttl time.Duration) error {
+	s.lock.Lock()
+
+	s.init.Do(func() {
+		if s.data == nil {
+			s.data = make(map[K]any)
+		}
+	})
+
+	s.initTTL.Do(func() {
+		if s.ttl == nil {
+			s.ttl = make(map[K]time.Time)
+		}
+	})
+
+	if err := s.checkWrite(key, value); err != nil {
+		s.lock.Unlock()
+		return err
+	}
+
+	deadline := time.Now().Add(ttl)
+
+	oldValue := s.data[key]
 	s.data[key] = value
+	s.ttl[key] = deadline
+	s.record(EventSet, key, value, deadline)
+	s.lock.Unlock()
+
+	s.emit(EventSet, key, oldValue, value)
+	return nil
 }
 
-// Type returns type name of value that is stored, if not found returns empty string and false
+// ExpireTTL run check for TTL in specified time, and if expired func not nil it will be called with removed item,
+// expired func is called after the store lock is released, so it can safely call back into the store, except Close.
+// ExpireTTL returns when the store is closed
+func (s *Store[K]) ExpireTTL(check time.Duration, expired func(key K, value any)) {
+	s.initTTL.Do(func() {
+		if s.ttl == nil {
+			s.ttl = make(map[K]time.Time)
+		}
+	})
+
+	s.lock.Lock()
+	done, ok := s.lifecycle.startWorker()
+	s.lock.Unlock()
+	if !ok {
+		return
+	}
+	defer s.lifecycle.workers.Done()
+
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case <-done:
+			return
+		case now = <-ticker.C:
+		}
+
+		s.lock.Lock()
+
+		var entries []Entry[K, any]
+		for k, v := range s.ttl {
+			if v.Before(now) {
+				entries = append(entries, Entry[K, any]{Key: k, Value: s.data[k]})
+
+				delete(s.data, k)
+				delete(s.ttl, k)
+				s.record(EventExpire, k, nil, time.Time{})
+			}
+		}
+
+		s.lock.Unlock()
+
+		for _, entry := range entries {
+			if expired != nil {
+				expired(entry.Key, entry.Value)
+			}
+
+			s.emit(EventExpire, entry.Key, entry.Value, nil)
+		}
+	}
+}
+
+// Deadline returns time when value expires, or zero time if value has no TTL, if not found returns false
+func (s *Store[K]) Deadline(key K) (time.Time, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if _, ok := s.data[key]; !ok {
+		return time.Time{}, false
+	}
+
+	return s.ttl[key], true
+}
+
+// Persist removes TTL of value and returns true, if not found or value has no TTL returns false
+func (s *Store[K]) Persist(key K) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.ttl[key]; !ok || s.lifecycle.closed {
+		return false
+	}
+
+	delete(s.ttl, key)
+	s.record(EventSet, key, s.data[key], time.Time{})
+	return true
+}
+
+// Type returns type name of value that is stored, if not found returns declared type of the key, or empty string
+// and false if key is not declared, for types registered with Register registered name is returned
 func Type[K comparable](store *Store[K], key K) (string, bool) {
 	return store.Type(key)
 }
 
-// Type returns type name of value that is stored, if not found returns empty string and false
+// Type returns type name of value that is stored, if not found returns declared type of the key, or empty string
+// and false if key is not declared, for types registered with Register registered name is returned
 func (s *Store[K]) Type(key K) (string, bool) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
 	data, ok := s.data[key]
 	if !ok {
+		if declared, isDeclared := s.schema[key]; isDeclared {
+			return registry.typeNameOf(declared), true
+		}
 		return "", false
 	}
 
-	return fmt.Sprintf("%T", data), true
+	return registry.typeName(data), true
 }
 
 // MustType returns type name of value that is stored, if not found returns an empty string
@@ -148,51 +338,55 @@ func (s *Store[K]) Has(key K) bool {
 // Delete deletes value from the store if it exists with a specified type and returns true, if not found returns false
 func Delete[V any, K comparable](store *Store[K], key K) bool {
 	store.lock.Lock()
-	defer store.lock.Unlock()
 
 	data, ok := store.data[key]
-	if !ok {
+	if !ok || store.lifecycle.closed {
+		store.lock.Unlock()
 		return false
 	}
 
 	_, ok = data.(V)
 	if !ok {
+		store.lock.Unlock()
 		return false
 	}
 
 	delete(store.data, key)
+	delete(store.ttl, key)
+	store.record(EventDelete, key, nil, time.Time{})
+	store.lock.Unlock()
+
+	store.emit(EventDelete, key, data, nil)
 	return true
 }
 
 // Delete deletes value from the store and returns true or if not found reruns false
 func (s *Store[K]) Delete(key K) bool {
 	s.lock.Lock()
-	defer s.lock.Unlock()
 
-	_, ok := s.data[key]
-	if !ok {
+	value, ok := s.data[key]
+	if !ok || s.lifecycle.closed {
+		s.lock.Unlock()
 		return false
 	}
 
 	delete(s.data, key)
+	delete(s.ttl, key)
+	s.record(EventDelete, key, nil, time.Time{})
+	s.lock.Unlock()
+
+	s.emit(EventDelete, key, value, nil)
 	return true
 }
 
-// Len returns number of values with a specified type that are stored
+// Len returns number of values with a specified type that are stored. Values are grouped by their dynamic types,
+// so Len, Keys, Values and Entries with a specified type don't check values of other types, for interface types only
+// types of stored values are checked
 func Len[V any, K comparable](store *Store[K]) int {
 	store.lock.RLock()
 	defer store.lock.RUnlock()
 
-	count := 0
-	for _, rawValue := range store.data {
-		if _, ok := rawValue.(V); !ok {
-			continue
-		}
-
-		count++
-	}
-
-	return count
+	return countOfType[V](&store.types)
 }
 
 // Len returns number of values that are stored
@@ -208,14 +402,11 @@ func Keys[V any, K comparable](store *Store[K]) []K {
 	store.lock.RLock()
 	defer store.lock.RUnlock()
 
-	keys := make([]K, 0, len(store.data))
-	for key, rawValue := range store.data {
-		if _, ok := rawValue.(V); !ok {
-			continue
-		}
-
+	keys := make([]K, 0, countOfType[V](&store.types))
+	eachOfType(&store.types, store.data, func(key K, _ V) bool {
 		keys = append(keys, key)
-	}
+		return false
+	})
 
 	return keys
 }
@@ -233,17 +424,73 @@ func (s *Store[K]) Keys() []K {
 	return keys
 }
 
+// Scan returns page of keys of values with a specified type and cursor of the next page, see Store.Scan. Count is
+// a number of keys examined, so page may contain fewer keys even if it is not the last one
+func Scan[V any, K comparable](store *Store[K], cursor uint64, count int) ([]K, uint64) {
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+
+	store.lockScan()
+	defer store.lock.RUnlock()
+
+	var keys []K
+	next := store.cursors.scan(cursor, count, func(key K) {
+		if _, ok := store.data[key].(V); ok {
+			keys = append(keys, key)
+		}
+	})
+
+	return keys, next
+}
+
+// Scan returns page of keys and cursor of the next page, scan starts with zero cursor and ends when returned cursor
+// is zero. Keys that are stored during the whole scan are returned exactly once, keys that are stored or deleted
+// during the scan may be returned or not. Count is a number of keys in page, if not positive DefaultScanCount is
+// used, the store lock is held only while a single page is collected
+func (s *Store[K]) Scan(cursor uint64, count int) ([]K, uint64) {
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+
+	s.lockScan()
+	defer s.lock.RUnlock()
+
+	keys := make([]K, 0, count)
+	next := s.cursors.scan(cursor, count, func(key K) {
+		keys = append(keys, key)
+	})
+
+	return keys, next
+}
+
+// lockScan acquires the store read lock, creating scan cursors if they don't exist yet
+func (s *Store[K]) lockScan() {
+	s.lock.RLock()
+	if s.cursors != nil {
+		return
+	}
+	s.lock.RUnlock()
+
+	s.lock.Lock()
+	if s.cursors == nil {
+		s.cursors = newScanIndex(s.data)
+	}
+	s.lock.Unlock()
+
+	s.lock.RLock()
+}
+
 // Values returns all values with a specified type that are stored, no order is expected
 func Values[V any, K comparable](store *Store[K]) []V {
 	store.lock.RLock()
 	defer store.lock.RUnlock()
 
-	values := make([]V, 0, len(store.data))
-	for _, rawValue := range store.data {
-		if value, ok := rawValue.(V); ok {
-			values = append(values, value)
-		}
-	}
+	values := make([]V, 0, countOfType[V](&store.types))
+	eachOfType(&store.types, store.data, func(_ K, value V) bool {
+		values = append(values, value)
+		return false
+	})
 
 	return values
 }
@@ -266,15 +513,14 @@ func Entries[V any, K comparable](store *Store[K]) []Entry[K, V] {
 	store.lock.RLock()
 	defer store.lock.RUnlock()
 
-	entries := make([]Entry[K, V], 0, len(store.data))
-	for key, rawValue := range store.data {
-		if value, ok := rawValue.(V); ok {
-			entries = append(entries, Entry[K, V]{
-				Key:   key,
-				Value: value,
-			})
-		}
-	}
+	entries := make([]Entry[K, V], 0, countOfType[V](&store.types))
+	eachOfType(&store.types, store.data, func(key K, value V) bool {
+		entries = append(entries, Entry[K, V]{
+			Key:   key,
+			Value: value,
+		})
+		return false
+	})
 
 	return entries
 }
@@ -314,3 +560,190 @@ func (s *Store[K]) ForEach(f func(key K, value any) (stop bool)) {
 		}
 	}
 }
+
+// Watch subscribes to changes of values with a specified type, watcher should be closed when it is no longer needed
+func Watch[V any, K comparable](store *Store[K], config WatchConfig[K, V]) *Watcher[K, V] {
+	watcher := newWatcher(config)
+	watcher.unsubscribe = store.watchers.subscribe(typedSubscriber[K, V]{watcher: watcher})
+	return watcher
+}
+
+// Watch subscribes to changes in the store, watcher should be closed when it is no longer needed
+func (s *Store[K]) Watch(config WatchConfig[K, any]) *Watcher[K, any] {
+	watcher := newWatcher(config)
+	watcher.unsubscribe = s.watchers.subscribe(watcher)
+	return watcher
+}
+
+// WatchFunc subscribes to changes of values with a specified type and calls f with each event in a separate
+// goroutine until watcher is closed
+func WatchFunc[V any, K comparable](
+	store *Store[K], config WatchConfig[K, V], f func(event Event[K, V]),
+) *Watcher[K, V] {
+	watcher := Watch(store, config)
+	go watcher.handle(f)
+	return watcher
+}
+
+// WatchFunc subscribes to changes in the store and calls f with each event in a separate goroutine until
+// watcher is closed
+func (s *Store[K]) WatchFunc(config WatchConfig[K, any], f func(event Event[K, any])) *Watcher[K, any] {
+	watcher := s.Watch(config)
+	go watcher.handle(f)
+	return watcher
+}
+
+// Save writes snapshot of all values and their TTL deadlines to w using specified codec, all types of stored values
+// must be registered with Register
+func (s *Store[K]) Save(w io.Writer, codec Codec) error {
+	return writeStoreSnapshot(w, codec, s.snapshotEntries(nil))
+}
+
+// Load reads snapshot from r using specified codec and stores all values that are not expired yet, existing values
+// with the same keys are replaced, all types of stored values must be registered with Register
+func (s *Store[K]) Load(r io.Reader, codec Codec) error {
+	entries, err := readStoreSnapshot[K](r, codec)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	if s.lifecycle.closed {
+		s.lock.Unlock()
+		return ErrClosed
+	}
+	oldValues := s.restoreEntries(entries)
+	s.lock.Unlock()
+
+	for i, entry := range entries {
+		s.emit(EventSet, entry.Key, oldValues[i], entry.Value)
+	}
+
+	return nil
+}
+
+// snapshotEntries returns all values with their TTL deadlines, if during func not nil it will be called while
+// the store lock is still held
+func (s *Store[K]) snapshotEntries(during func()) []snapshotEntry[K, any] {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	entries := make([]snapshotEntry[K, any], 0, len(s.data))
+	for key, value := range s.data {
+		entry := snapshotEntry[K, any]{
+			Key:   key,
+			Value: value,
+		}
+
+		if deadline, ok := s.ttl[key]; ok {
+			entry.Deadline = &deadline
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if during != nil {
+		during()
+	}
+
+	return entries
+}
+
+// restoreEntries stores values with their TTL deadlines and returns replaced values, must be called while the store
+// lock is held
+func (s *Store[K]) restoreEntries(entries []snapshotEntry[K, any]) []any {
+	s.init.Do(func() {
+		if s.data == nil {
+			s.data = make(map[K]any)
+		}
+	})
+
+	s.initTTL.Do(func() {
+		if s.ttl == nil {
+			s.ttl = make(map[K]time.Time)
+		}
+	})
+
+	oldValues := make([]any, len(entries))
+	for i, entry := range entries {
+		oldValues[i] = s.data[entry.Key]
+		s.data[entry.Key] = entry.Value
+		s.declareValue(entry.Key, entry.Value)
+
+		deadline := time.Time{}
+		if entry.Deadline != nil {
+			deadline = *entry.Deadline
+			s.ttl[entry.Key] = deadline
+		} else {
+			delete(s.ttl, entry.Key)
+		}
+
+		s.record(EventSet, entry.Key, entry.Value, deadline)
+	}
+
+	return oldValues
+}
+
+// attachJournal restores values and attaches journal that records all following changes, returns a func that
+// detaches the journal and number of values stored after restoring
+func (s *Store[K]) attachJournal(
+	entries []snapshotEntry[K, any], j journal[K, any],
+) (detach func(), stored int) {
+	s.lock.Lock()
+	s.restoreEntries(entries)
+	s.journals = append(s.journals, j)
+	stored = len(s.data)
+	s.lock.Unlock()
+
+	return func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.journals = removeJournal(s.journals, j)
+	}, stored
+}
+
+// record writes change to scan cursors, type index and all attached journals, must be called while the store lock
+// is held
+func (s *Store[K]) record(kind EventKind, key K, value any, deadline time.Time) {
+	if s.cursors != nil {
+		s.cursors.record(kind, key)
+	}
+	s.types.record(kind, key, value)
+
+	for _, j := range s.journals {
+		j.record(kind, key, value, deadline)
+	}
+}
+
+// OnSet registers hook that is called when value is stored and returns a func that removes the hook
+func (s *Store[K]) OnSet(hook Hook[K, any], mode HookMode) (remove func()) {
+	return s.hooks.add(EventSet, hook, mode)
+}
+
+// OnDelete registers hook that is called when value is deleted and returns a func that removes the hook
+func (s *Store[K]) OnDelete(hook Hook[K, any], mode HookMode) (remove func()) {
+	return s.hooks.add(EventDelete, hook, mode)
+}
+
+// OnExpire registers hook that is called when value is removed because of TTL and returns a func that removes the hook
+func (s *Store[K]) OnExpire(hook Hook[K, any], mode HookMode) (remove func()) {
+	return s.hooks.add(EventExpire, hook, mode)
+}
+
+// OnEvict registers hook that is called when value is evicted by the store and returns a func that removes the hook
+func (s *Store[K]) OnEvict(hook Hook[K, any], mode HookMode) (remove func()) {
+	return s.hooks.add(EventEvict, hook, mode)
+}
+
+// emit calls hooks and notifies watchers about the change, must not be called while store lock is held
+func (s *Store[K]) emit(kind EventKind, key K, oldValue, newValue any) {
+	s.hooks.run(kind, key, oldValue, newValue)
+
+	value := newValue
+	if kind != EventSet {
+		value = oldValue
+	}
+
+	s.watchers.notify(Event[K, any]{Kind: kind, Key: key, Value: value})
+}
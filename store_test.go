@@ -2,6 +2,7 @@ package memkey
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -411,3 +412,29 @@ func TestStore_ForEach(t *testing.T) {
 	})
 	assert.Equal(t, 2, count)
 }
+
+func TestStore_SetWithTTL(t *testing.T) {
+	s := &Store[int]{}
+
+	done := make(chan struct{})
+
+	go s.ExpireTTL(time.Millisecond, func(key int, value any) {
+		assert.Equal(t, 2, key)
+		assert.Equal(t, "a", value)
+		done <- struct{}{}
+	})
+
+	Set(s, 1, 1)
+	SetWithTTL(s, 2, "a", time.Millisecond*2)
+	s.SetWithTTL(3, 1.0, time.Hour)
+
+	assert.Equal(t, 3, s.Len())
+
+	select {
+	case <-time.After(time.Second):
+		assert.FailNow(t, "timeout")
+		return
+	case <-done:
+		assert.Equal(t, 2, s.Len())
+	}
+}
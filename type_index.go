@@ -0,0 +1,90 @@
+package memkey
+
+import "reflect" //nolint:depguard // Values are grouped by their exact dynamic types
+
+// typeIndex groups keys of the store by dynamic types of their values, so values of a single type can be found
+// without checking all values. It's updated while the store lock is held
+type typeIndex[K comparable] struct {
+	keys  map[reflect.Type]map[K]struct{}
+	types map[K]reflect.Type
+}
+
+// record updates index with change of the store
+func (t *typeIndex[K]) record(kind EventKind, key K, value any) {
+	t.remove(key)
+	if kind == EventSet {
+		t.add(key, reflect.TypeOf(value))
+	}
+}
+
+func (t *typeIndex[K]) add(key K, typ reflect.Type) {
+	if t.keys == nil {
+		t.keys = make(map[reflect.Type]map[K]struct{})
+		t.types = make(map[K]reflect.Type)
+	}
+
+	keys, ok := t.keys[typ]
+	if !ok {
+		keys = make(map[K]struct{})
+		t.keys[typ] = keys
+	}
+
+	keys[key] = struct{}{}
+	t.types[key] = typ
+}
+
+func (t *typeIndex[K]) remove(key K) {
+	typ, ok := t.types[key]
+	if !ok {
+		return
+	}
+
+	keys := t.keys[typ]
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(t.keys, typ)
+	}
+	delete(t.types, key)
+}
+
+// countOfType returns number of values with type V, for interface types only types of stored values are checked
+func countOfType[V any, K comparable](t *typeIndex[K]) int {
+	typ := reflect.TypeOf((*V)(nil)).Elem()
+	if typ.Kind() != reflect.Interface {
+		return len(t.keys[typ])
+	}
+
+	n := 0
+	for valueType, keys := range t.keys {
+		if valueType != nil && valueType.Implements(typ) {
+			n += len(keys)
+		}
+	}
+	return n
+}
+
+// eachOfType calls f with values of type V stored in data until f returns true, for interface types only types of
+// stored values are checked. Must be called while the store lock is held
+func eachOfType[V any, K comparable](t *typeIndex[K], data map[K]any, f func(key K, value V) (stop bool)) {
+	typ := reflect.TypeOf((*V)(nil)).Elem()
+	if typ.Kind() != reflect.Interface {
+		eachKey(t.keys[typ], data, f)
+		return
+	}
+
+	for valueType, keys := range t.keys {
+		if valueType != nil && valueType.Implements(typ) && eachKey(keys, data, f) {
+			return
+		}
+	}
+}
+
+// eachKey calls f with values of keys until f returns true, returns true if stopped
+func eachKey[V any, K comparable](keys map[K]struct{}, data map[K]any, f func(key K, value V) (stop bool)) bool {
+	for key := range keys {
+		if f(key, data[key].(V)) { //nolint:forcetypeassert // Keys are grouped by type of values
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package memkey
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeIndex(t *testing.T) {
+	s := &Store[int]{}
+	for i := 0; i < 10; i++ {
+		s.Set(i, i)
+	}
+	s.Set(10, "text")
+	s.Set(11, time.Second)
+	s.Set(12, nil)
+
+	assert.Equal(t, 10, Len[int](s))
+	assert.Equal(t, 1, Len[string](s))
+	assert.Zero(t, Len[float64](s))
+	assert.Len(t, s.types.keys, 4)
+
+	// Overwrite with another type moves key to another group
+	s.Set(0, "other")
+	assert.Equal(t, 9, Len[int](s))
+	assert.Equal(t, 2, Len[string](s))
+
+	keys := Keys[string](s)
+	sort.Ints(keys)
+	assert.Equal(t, []int{0, 10}, keys)
+	assert.ElementsMatch(t, []string{"other", "text"}, Values[string](s))
+	assert.ElementsMatch(t, []Entry[int, string]{{Key: 0, Value: "other"}, {Key: 10, Value: "text"}},
+		Entries[string](s))
+
+	s.Delete(10)
+	Delete[string](s, 0)
+	assert.Zero(t, Len[string](s))
+	assert.Empty(t, Keys[string](s))
+	_, ok := s.types.keys[reflect.TypeOf("")]
+	assert.False(t, ok)
+	assert.Len(t, s.types.types, s.Len())
+}
+
+func TestTypeIndex_Interface(t *testing.T) {
+	s := &Store[int]{}
+	s.Set(1, time.Second)
+	s.Set(2, time.Minute)
+	s.Set(3, 5)
+	s.Set(4, nil)
+
+	assert.Equal(t, 2, Len[fmt.Stringer](s))
+	assert.ElementsMatch(t, []int{1, 2}, Keys[fmt.Stringer](s))
+	assert.ElementsMatch(t, []fmt.Stringer{time.Second, time.Minute}, Values[fmt.Stringer](s))
+
+	// Nil values have no type
+	assert.Equal(t, 3, Len[any](s))
+	assert.Len(t, Entries[any](s), 3)
+
+	value, ok := Get[fmt.Stringer](s, 1)
+	require.True(t, ok)
+	assert.Equal(t, "1s", value.String())
+
+	assert.Equal(t, 1, NewQuery[fmt.Stringer](s).Limit(1).Count())
+	assert.Equal(t, 1, NewQuery[int](s).Count())
+}
+
+func TestTypeIndex_Restore(t *testing.T) {
+	source := &Store[string]{}
+	source.Set("a", 1)
+	source.Set("b", "text")
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, source.Save(buf, JSONCodec{}))
+
+	s := &Store[string]{}
+	s.Set("a", "old")
+	require.NoError(t, s.Load(buf, JSONCodec{}))
+
+	assert.Equal(t, []string{"a"}, Keys[int](s))
+	assert.Equal(t, []string{"b"}, Keys[string](s))
+
+	s.SetWithTTL("c", 2, -time.Second)
+	_, err := Incr(s, "d", 3)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "c", "d"}, Keys[int](s))
+
+	go s.ExpireTTL(time.Millisecond, nil)
+	assert.Eventually(t, func() bool {
+		return Len[int](s) == 2
+	}, time.Second, time.Millisecond)
+}
@@ -1,6 +1,7 @@
 package memkey
 
 import (
+	"io"
 	"sync"
 	"time"
 )
@@ -13,6 +14,15 @@ type TypedStore[K comparable, V any] struct {
 
 	initTTL sync.Once
 	ttl     map[K]time.Time
+
+	watchers watchers[K, V]
+	hooks    hooks[K, V]
+	journals []journal[K, V]
+	// cursors is created by the first scan
+	cursors *scanIndex[K]
+	indexes map[string]*valueIndex[K, V]
+
+	lifecycle lifecycle
 }
 
 // Get return value stored in the store if it exists, or zero value and false
@@ -24,8 +34,31 @@ func (s *TypedStore[K, V]) Get(key K) (V, bool) {
 	return value, ok
 }
 
-// Set stores value in the store
+// Set stores value in the store, previously set TTL of the key is removed. Value is silently not stored if write is
+// rejected by index, use TrySet to get an error
 func (s *TypedStore[K, V]) Set(key K, value V) {
+	_ = s.TrySet(key, value)
+}
+
+// TrySet stores value in the store, previously set TTL of the key is removed, returns error if write is rejected
+func (s *TypedStore[K, V]) TrySet(key K, value V) error {
+	return s.setWithDeadline(key, value, time.Time{})
+}
+
+// update replaces value stored with key by result of f while the store lock is held, TTL of the key is kept.
+// If f returns error or new value is rejected by index, value is not changed and error is returned
+func (s *TypedStore[K, V]) update(key K, f func(value V, ok bool) (V, error)) (V, error) {
+	oldValue, value, err := s.updateValue(key, f)
+	if err != nil {
+		return zero[V](), err
+	}
+
+	s.emit(EventSet, key, oldValue, value)
+	return value, nil
+}
+
+// updateValue replaces value like update and returns replaced and new values without notifying hooks and watchers
+func (s *TypedStore[K, V]) updateValue(key K, f func(value V, ok bool) (V, error)) (oldValue, value V, err error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
@@ -35,12 +68,46 @@ func (s *TypedStore[K, V]) Set(key K, value V) {
 		}
 	})
 
+	oldValue, ok := s.data[key]
+	value, err = f(oldValue, ok)
+	if err == nil {
+		err = s.checkWrite(key, value)
+	}
+	if err != nil {
+		return oldValue, value, err
+	}
+
 	s.data[key] = value
+	s.record(EventSet, key, value, s.ttl[key])
+	return oldValue, value, nil
 }
 
-// SetWithTTL stores value in the store with TTL, expiration happens only if ExpireTTL was called
+// SetWithTTL stores value in the store with TTL, expiration happens only if ExpireTTL was called. Value is silently
+// not stored if write is rejected by index, use TrySetWithTTL to get an error
 func (s *TypedStore[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	_ = s.setWithDeadline(key, value, time.Now().Add(ttl))
+}
+
+// TrySetWithTTL stores value in the store with TTL, returns error if write is rejected
+func (s *TypedStore[K, V]) TrySetWithTTL(key K, value V, ttl time.Duration) error {
+	return s.setWithDeadline(key, value, time.Now().Add(ttl))
+}
+
+// setWithDeadline stores value in the store that expires at deadline, if deadline is zero TTL of the key is removed
+func (s *TypedStore[K, V]) setWithDeadline(key K, value V, deadline time.Time) error {
+	oldValue, err := s.set(key, value, deadline)
+	if err != nil {
+		return err
+	}
+
+	s.emit(EventSet, key, oldValue, value)
+	return nil
+}
+
+// set stores value like setWithDeadline and returns replaced value without notifying hooks and watchers
+func (s *TypedStore[K, V]) set(key K, value V, deadline time.Time) (V, error) {
 	s.lock.Lock()
+	// Index funcs are called while the lock is held, so it's released by defer in case they panic
 	defer s.lock.Unlock()
 
 	s.init.Do(func() {
@@ -55,11 +122,25 @@ func (s *TypedStore[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
 		}
 	})
 
+	if err := s.checkWrite(key, value); err != nil {
+		return zero[V](), err
+	}
+
+	oldValue := s.data[key]
 	s.data[key] = value
-	s.ttl[key] = time.Now().Add(ttl)
+	if deadline.IsZero() {
+		delete(s.ttl, key)
+	} else {
+		s.ttl[key] = deadline
+	}
+	s.record(EventSet, key, value, deadline)
+
+	return oldValue, nil
 }
 
-// ExpireTTL run check for TTL in specified time, and if expired func not nil it will be called with removed item
+// ExpireTTL run check for TTL in specified time, and if expired func not nil it will be called with removed item,
+// expired func is called after the store lock is released, so it can safely call back into the store, except Close.
+// ExpireTTL returns when the store is closed
 func (s *TypedStore[K, V]) ExpireTTL(check time.Duration, expired func(key K, value V)) {
 	s.initTTL.Do(func() {
 		if s.ttl == nil {
@@ -67,24 +148,76 @@ func (s *TypedStore[K, V]) ExpireTTL(check time.Duration, expired func(key K, va
 		}
 	})
 
-	for now := range time.Tick(check) {
+	s.lock.Lock()
+	done, ok := s.lifecycle.startWorker()
+	s.lock.Unlock()
+	if !ok {
+		return
+	}
+	defer s.lifecycle.workers.Done()
+
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case <-done:
+			return
+		case now = <-ticker.C:
+		}
+
 		s.lock.Lock()
 
+		var entries []Entry[K, V]
 		for k, v := range s.ttl {
 			if v.Before(now) {
-				if expired != nil {
-					expired(k, s.data[k])
-				}
+				entries = append(entries, Entry[K, V]{Key: k, Value: s.data[k]})
 
 				delete(s.data, k)
 				delete(s.ttl, k)
+				s.record(EventExpire, k, zero[V](), time.Time{})
 			}
 		}
 
 		s.lock.Unlock()
+
+		for _, entry := range entries {
+			if expired != nil {
+				expired(entry.Key, entry.Value)
+			}
+
+			s.emit(EventExpire, entry.Key, entry.Value, zero[V]())
+		}
 	}
 }
 
+// Deadline returns time when value expires, or zero time if value has no TTL, if not found returns false
+func (s *TypedStore[K, V]) Deadline(key K) (time.Time, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if _, ok := s.data[key]; !ok {
+		return time.Time{}, false
+	}
+
+	return s.ttl[key], true
+}
+
+// Persist removes TTL of value and returns true, if not found or value has no TTL returns false
+func (s *TypedStore[K, V]) Persist(key K) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.ttl[key]; !ok || s.lifecycle.closed {
+		return false
+	}
+
+	delete(s.ttl, key)
+	s.record(EventSet, key, s.data[key], time.Time{})
+	return true
+}
+
 // Has returns a true if value with the specified key exists in the store
 func (s *TypedStore[K, V]) Has(key K) bool {
 	s.lock.RLock()
@@ -96,18 +229,102 @@ func (s *TypedStore[K, V]) Has(key K) bool {
 
 // Delete deletes value from the store and returns true or if not found reruns false
 func (s *TypedStore[K, V]) Delete(key K) bool {
+	return s.remove(EventDelete, key)
+}
+
+// remove removes value from the store reporting change of specified kind, returns false if value not found
+func (s *TypedStore[K, V]) remove(kind EventKind, key K) bool {
 	s.lock.Lock()
-	defer s.lock.Unlock()
 
-	_, ok := s.data[key]
-	if !ok {
+	value, ok := s.data[key]
+	if !ok || s.lifecycle.closed {
+		s.lock.Unlock()
 		return false
 	}
 
 	delete(s.data, key)
+	delete(s.ttl, key)
+	s.record(kind, key, zero[V](), time.Time{})
+	s.lock.Unlock()
+
+	s.emit(kind, key, value, zero[V]())
 	return true
 }
 
+// GetMany returns values stored with the specified keys, keys that are not found are omitted
+func (s *TypedStore[K, V]) GetMany(keys ...K) map[K]V {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, ok := s.data[key]; ok {
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
+// SetMany stores all values in the store at once, previously set TTL of the keys is removed. Values which writes
+// are rejected by index are silently not stored
+func (s *TypedStore[K, V]) SetMany(values map[K]V) {
+	for key, oldValue := range s.setMany(values) {
+		s.emit(EventSet, key, oldValue, values[key])
+	}
+}
+
+// setMany stores values like SetMany and returns replaced values of stored keys without notifying hooks and watchers
+func (s *TypedStore[K, V]) setMany(values map[K]V) map[K]V {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.init.Do(func() {
+		if s.data == nil {
+			s.data = make(map[K]V)
+		}
+	})
+
+	oldValues := make(map[K]V, len(values))
+	for key, value := range values {
+		if err := s.checkWrite(key, value); err != nil {
+			continue
+		}
+
+		oldValues[key] = s.data[key]
+		s.data[key] = value
+		delete(s.ttl, key)
+		s.record(EventSet, key, value, time.Time{})
+	}
+
+	return oldValues
+}
+
+// DeleteMany deletes values with the specified keys from the store at once and returns number of deleted values
+func (s *TypedStore[K, V]) DeleteMany(keys ...K) int {
+	s.lock.Lock()
+
+	deleted := make([]Entry[K, V], 0, len(keys))
+	for _, key := range keys {
+		value, ok := s.data[key]
+		if !ok || s.lifecycle.closed {
+			continue
+		}
+
+		delete(s.data, key)
+		delete(s.ttl, key)
+		s.record(EventDelete, key, zero[V](), time.Time{})
+		deleted = append(deleted, Entry[K, V]{Key: key, Value: value})
+	}
+	s.lock.Unlock()
+
+	for _, entry := range deleted {
+		s.emit(EventDelete, entry.Key, entry.Value, zero[V]())
+	}
+
+	return len(deleted)
+}
+
 // Len returns number of values that are stored
 func (s *TypedStore[K, V]) Len() int {
 	s.lock.RLock()
@@ -129,6 +346,43 @@ func (s *TypedStore[K, V]) Keys() []K {
 	return keys
 }
 
+// Scan returns page of keys and cursor of the next page, scan starts with zero cursor and ends when returned cursor
+// is zero. Keys that are stored during the whole scan are returned exactly once, keys that are stored or deleted
+// during the scan may be returned or not. Count is a number of keys in page, if not positive DefaultScanCount is
+// used, the store lock is held only while a single page is collected
+func (s *TypedStore[K, V]) Scan(cursor uint64, count int) ([]K, uint64) {
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+
+	s.lockScan()
+	defer s.lock.RUnlock()
+
+	keys := make([]K, 0, count)
+	next := s.cursors.scan(cursor, count, func(key K) {
+		keys = append(keys, key)
+	})
+
+	return keys, next
+}
+
+// lockScan acquires the store read lock, creating scan cursors if they don't exist yet
+func (s *TypedStore[K, V]) lockScan() {
+	s.lock.RLock()
+	if s.cursors != nil {
+		return
+	}
+	s.lock.RUnlock()
+
+	s.lock.Lock()
+	if s.cursors == nil {
+		s.cursors = newScanIndex(s.data)
+	}
+	s.lock.Unlock()
+
+	s.lock.RLock()
+}
+
 // Values returns all values that are stored, no order is expected
 func (s *TypedStore[K, V]) Values() []V {
 	s.lock.RLock()
@@ -167,3 +421,227 @@ func (s *TypedStore[K, V]) ForEach(f func(key K, value V) (stop bool)) {
 		}
 	}
 }
+
+// Watch subscribes to changes in the store, watcher should be closed when it is no longer needed
+func (s *TypedStore[K, V]) Watch(config WatchConfig[K, V]) *Watcher[K, V] {
+	watcher := newWatcher(config)
+	watcher.unsubscribe = s.watchers.subscribe(watcher)
+	return watcher
+}
+
+// WatchFunc subscribes to changes in the store and calls f with each event in a separate goroutine until
+// watcher is closed
+func (s *TypedStore[K, V]) WatchFunc(config WatchConfig[K, V], f func(event Event[K, V])) *Watcher[K, V] {
+	watcher := s.Watch(config)
+	go watcher.handle(f)
+	return watcher
+}
+
+// Save writes snapshot of all values and their TTL deadlines to w using specified codec
+func (s *TypedStore[K, V]) Save(w io.Writer, codec Codec) error {
+	return writeSnapshot(w, codec, snapshotFormatTyped, s.snapshotEntries(nil))
+}
+
+// Load reads snapshot from r using specified codec and stores all values that are not expired yet,
+// existing values with the same keys are replaced
+func (s *TypedStore[K, V]) Load(r io.Reader, codec Codec) error {
+	entries, err := readSnapshot[K, V](r, codec, snapshotFormatTyped)
+	if err != nil {
+		return err
+	}
+
+	oldValues, err := s.load(entries)
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		s.emit(EventSet, entry.Key, oldValues[i], entry.Value)
+	}
+
+	return nil
+}
+
+// load stores entries like Load and returns replaced values without notifying hooks and watchers
+func (s *TypedStore[K, V]) load(entries []snapshotEntry[K, V]) ([]V, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.lifecycle.closed {
+		return nil, ErrClosed
+	}
+
+	return s.restoreEntries(entries), nil
+}
+
+// snapshotEntries returns all values with their TTL deadlines, if during func not nil it will be called while
+// the store lock is still held
+func (s *TypedStore[K, V]) snapshotEntries(during func()) []snapshotEntry[K, V] {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	entries := make([]snapshotEntry[K, V], 0, len(s.data))
+	for key, value := range s.data {
+		entry := snapshotEntry[K, V]{
+			Key:   key,
+			Value: value,
+		}
+
+		if deadline, ok := s.ttl[key]; ok {
+			entry.Deadline = &deadline
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if during != nil {
+		during()
+	}
+
+	return entries
+}
+
+// restoreEntries stores values with their TTL deadlines and returns replaced values, must be called while the store
+// lock is held
+func (s *TypedStore[K, V]) restoreEntries(entries []snapshotEntry[K, V]) []V {
+	s.init.Do(func() {
+		if s.data == nil {
+			s.data = make(map[K]V)
+		}
+	})
+
+	s.initTTL.Do(func() {
+		if s.ttl == nil {
+			s.ttl = make(map[K]time.Time)
+		}
+	})
+
+	oldValues := make([]V, len(entries))
+	for i, entry := range entries {
+		oldValues[i] = s.data[entry.Key]
+		s.data[entry.Key] = entry.Value
+
+		deadline := time.Time{}
+		if entry.Deadline != nil {
+			deadline = *entry.Deadline
+			s.ttl[entry.Key] = deadline
+		} else {
+			delete(s.ttl, entry.Key)
+		}
+
+		s.record(EventSet, entry.Key, entry.Value, deadline)
+	}
+
+	return oldValues
+}
+
+// replaceEntries replaces all values of the store with entries, calling hooks and watchers for deleted and stored
+// values
+func (s *TypedStore[K, V]) replaceEntries(entries []snapshotEntry[K, V]) {
+	deleted, oldValues, ok := s.replace(entries)
+	if !ok {
+		return
+	}
+
+	for _, entry := range deleted {
+		s.emit(EventDelete, entry.Key, entry.Value, zero[V]())
+	}
+	for i, entry := range entries {
+		s.emit(EventSet, entry.Key, oldValues[i], entry.Value)
+	}
+}
+
+// replace replaces values like replaceEntries and returns deleted and replaced values without notifying hooks and
+// watchers, if the store is closed nothing is replaced and false is returned
+func (s *TypedStore[K, V]) replace(entries []snapshotEntry[K, V]) (deleted []Entry[K, V], oldValues []V, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.lifecycle.closed {
+		return nil, nil, false
+	}
+
+	keep := make(map[K]struct{}, len(entries))
+	for _, entry := range entries {
+		keep[entry.Key] = struct{}{}
+	}
+
+	for key, value := range s.data {
+		if _, kept := keep[key]; kept {
+			continue
+		}
+
+		delete(s.data, key)
+		delete(s.ttl, key)
+		s.record(EventDelete, key, zero[V](), time.Time{})
+		deleted = append(deleted, Entry[K, V]{Key: key, Value: value})
+	}
+
+	return deleted, s.restoreEntries(entries), true
+}
+
+// attachJournal restores values and attaches journal that records all following changes, returns a func that
+// detaches the journal and number of values stored after restoring
+func (s *TypedStore[K, V]) attachJournal(
+	entries []snapshotEntry[K, V], j journal[K, V],
+) (detach func(), stored int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.restoreEntries(entries)
+	s.journals = append(s.journals, j)
+
+	return func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.journals = removeJournal(s.journals, j)
+	}, len(s.data)
+}
+
+// record writes change to scan cursors, indexes and all attached journals, must be called while the store lock is held
+func (s *TypedStore[K, V]) record(kind EventKind, key K, value V, deadline time.Time) {
+	if s.cursors != nil {
+		s.cursors.record(kind, key)
+	}
+
+	for _, index := range s.indexes {
+		index.record(kind, key, value)
+	}
+
+	for _, j := range s.journals {
+		j.record(kind, key, value, deadline)
+	}
+}
+
+// OnSet registers hook that is called when value is stored and returns a func that removes the hook
+func (s *TypedStore[K, V]) OnSet(hook Hook[K, V], mode HookMode) (remove func()) {
+	return s.hooks.add(EventSet, hook, mode)
+}
+
+// OnDelete registers hook that is called when value is deleted and returns a func that removes the hook
+func (s *TypedStore[K, V]) OnDelete(hook Hook[K, V], mode HookMode) (remove func()) {
+	return s.hooks.add(EventDelete, hook, mode)
+}
+
+// OnExpire registers hook that is called when value is removed because of TTL and returns a func that removes the hook
+func (s *TypedStore[K, V]) OnExpire(hook Hook[K, V], mode HookMode) (remove func()) {
+	return s.hooks.add(EventExpire, hook, mode)
+}
+
+// OnEvict registers hook that is called when value is evicted by the store and returns a func that removes the hook
+func (s *TypedStore[K, V]) OnEvict(hook Hook[K, V], mode HookMode) (remove func()) {
+	return s.hooks.add(EventEvict, hook, mode)
+}
+
+// emit calls hooks and notifies watchers about the change, must not be called while store lock is held
+func (s *TypedStore[K, V]) emit(kind EventKind, key K, oldValue, newValue V) {
+	s.hooks.run(kind, key, oldValue, newValue)
+
+	value := newValue
+	if kind != EventSet {
+		value = oldValue
+	}
+
+	s.watchers.notify(Event[K, V]{Kind: kind, Key: key, Value: value})
+}
@@ -32,3 +32,80 @@ func TestTypedStore_SetWithTTL(t *testing.T) {
 		assert.Equal(t, 2, s.Len())
 	}
 }
+
+func TestTypedStore_SetRemovesTTL(t *testing.T) {
+	s := &TypedStore[int, bool]{}
+	defer func() { _ = s.Close() }()
+
+	expired := make(chan int, 2)
+	go s.ExpireTTL(time.Millisecond, func(key int, _ bool) {
+		expired <- key
+	})
+
+	// New value doesn't inherit TTL of the replaced one
+	s.SetWithTTL(1, true, time.Millisecond*2)
+	s.Set(1, false)
+	s.SetWithTTL(2, true, time.Millisecond*2)
+
+	assert.Equal(t, 2, <-expired)
+	value, ok := s.Get(1)
+	assert.True(t, ok)
+	assert.False(t, value)
+}
+
+func TestTypedStore_DeleteRemovesTTL(t *testing.T) {
+	s := &TypedStore[int, bool]{}
+
+	s.SetWithTTL(1, true, time.Hour)
+	assert.True(t, s.Delete(1))
+	assert.Empty(t, s.ttl)
+
+	s.SetWithTTL(2, true, time.Hour)
+	assert.Equal(t, 1, s.DeleteMany(2))
+	assert.Empty(t, s.ttl)
+}
+
+func TestTypedStore_DeadlineAndPersist(t *testing.T) {
+	s := &TypedStore[int, bool]{}
+
+	_, ok := s.Deadline(1)
+	assert.False(t, ok)
+	assert.False(t, s.Persist(1))
+
+	s.Set(1, true)
+	deadline, ok := s.Deadline(1)
+	assert.True(t, ok)
+	assert.True(t, deadline.IsZero())
+	assert.False(t, s.Persist(1))
+
+	s.SetWithTTL(2, true, time.Hour)
+	deadline, ok = s.Deadline(2)
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Second)
+
+	assert.True(t, s.Persist(2))
+	deadline, ok = s.Deadline(2)
+	assert.True(t, ok)
+	assert.True(t, deadline.IsZero())
+}
+
+func TestTypedStore_Batch(t *testing.T) {
+	s := &TypedStore[int, string]{}
+
+	sets, deletes := 0, 0
+	s.OnSet(func(key int, oldValue, newValue string) { sets++ }, HookSync)
+	s.OnDelete(func(key int, oldValue, newValue string) { deletes++ }, HookSync)
+
+	s.SetWithTTL(1, "old", time.Minute)
+	s.SetMany(map[int]string{1: "a", 2: "b", 3: "c"})
+
+	assert.Equal(t, map[int]string{1: "a", 3: "c"}, s.GetMany(1, 3, 4))
+	deadline, _ := s.Deadline(1)
+	assert.True(t, deadline.IsZero())
+
+	assert.Equal(t, 2, s.DeleteMany(1, 2, 4))
+	assert.Equal(t, []int{3}, s.Keys())
+
+	assert.Equal(t, 4, sets)
+	assert.Equal(t, 2, deletes)
+}
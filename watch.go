@@ -0,0 +1,289 @@
+package memkey
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// EventKind represents kind of change that happened to a value in the store
+type EventKind uint8
+
+const (
+	// EventSet happens when value is stored
+	EventSet EventKind = iota + 1
+	// EventDelete happens when value is explicitly deleted
+	EventDelete
+	// EventExpire happens when value is removed because its TTL has expired
+	EventExpire
+	// EventEvict happens when value is removed by the store itself, not by delete or expiration, for example, by
+	// ClearWithHooks
+	EventEvict
+)
+
+// String returns name of event kind
+func (k EventKind) String() string {
+	switch k {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	default:
+		return "unknown"
+	}
+}
+
+// Event represents change of a value in the store. Events are delivered after the store lock is released, so events
+// of concurrent writes to the same key may be delivered in a different order than the writes were applied
+type Event[K comparable, V any] struct {
+	Kind EventKind
+	Key  K
+	// Value is a stored value for EventSet or removed value for all other events
+	Value V
+}
+
+// OverflowPolicy defines what happens with events when watcher's buffer is full
+type OverflowPolicy uint8
+
+const (
+	// OverflowDropNewest drops new events while buffer is full
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest buffered event to make room for a new one
+	OverflowDropOldest
+	// OverflowBlock blocks the writer until there is room in the buffer
+	OverflowBlock
+)
+
+// DefaultWatchBuffer is a buffer size used by watchers if no buffer size specified
+const DefaultWatchBuffer = 64
+
+// WatchConfig represents configuration of a watcher
+type WatchConfig[K comparable, V any] struct {
+	// Match selects events to deliver, if nil all events are delivered
+	Match func(event Event[K, V]) bool
+	// Buffer is a number of events that can be buffered, if zero DefaultWatchBuffer is used
+	Buffer int
+	// Overflow is a policy to apply when buffer is full
+	Overflow OverflowPolicy
+}
+
+// MatchKey returns a match func that selects events of a single key
+func MatchKey[V any, K comparable](key K) func(event Event[K, V]) bool {
+	return func(event Event[K, V]) bool {
+		return event.Key == key
+	}
+}
+
+// MatchKeys returns a match func that selects events of keys accepted by the predicate
+func MatchKeys[V any, K comparable](predicate func(key K) bool) func(event Event[K, V]) bool {
+	return func(event Event[K, V]) bool {
+		return predicate(event.Key)
+	}
+}
+
+// Watcher represents subscription to changes in the store
+type Watcher[K comparable, V any] struct {
+	queue *queue[Event[K, V]]
+	match func(event Event[K, V]) bool
+
+	closeOnce   sync.Once
+	unsubscribe func()
+}
+
+func newWatcher[K comparable, V any](config WatchConfig[K, V]) *Watcher[K, V] {
+	return &Watcher[K, V]{
+		queue: newQueue[Event[K, V]](config.Buffer, config.Overflow),
+		match: config.Match,
+	}
+}
+
+// Events returns channel of events, channel is closed when watcher is closed
+func (w *Watcher[K, V]) Events() <-chan Event[K, V] {
+	return w.queue.items
+}
+
+// Dropped returns number of events that were dropped because of buffer overflow
+func (w *Watcher[K, V]) Dropped() uint64 {
+	return w.queue.dropped.Load()
+}
+
+// Close stops delivery of events and closes events channel, it is safe to call Close multiple times
+func (w *Watcher[K, V]) Close() {
+	w.closeOnce.Do(func() {
+		if w.unsubscribe != nil {
+			w.unsubscribe()
+		}
+		w.queue.close()
+	})
+}
+
+// handle calls f for each event until watcher is closed
+func (w *Watcher[K, V]) handle(f func(event Event[K, V])) {
+	for event := range w.queue.items {
+		f(event)
+	}
+}
+
+// deliver sends event to watcher according to its match func and overflow policy
+func (w *Watcher[K, V]) deliver(event Event[K, V]) {
+	if w.match != nil && !w.match(event) {
+		return
+	}
+
+	w.queue.push(event)
+}
+
+// queue is a bounded queue that applies overflow policy when it's full
+type queue[T any] struct {
+	items    chan T
+	done     chan struct{}
+	overflow OverflowPolicy
+
+	lock      sync.Mutex
+	closed    bool
+	dropped   atomic.Uint64
+	closeOnce sync.Once
+}
+
+// newQueue creates queue of specified size, if size is not positive DefaultWatchBuffer is used
+func newQueue[T any](size int, overflow OverflowPolicy) *queue[T] {
+	if size <= 0 {
+		size = DefaultWatchBuffer
+	}
+
+	return &queue[T]{
+		items:    make(chan T, size),
+		done:     make(chan struct{}),
+		overflow: overflow,
+	}
+}
+
+// push adds item to the queue according to overflow policy, items pushed after queue was closed are ignored
+func (q *queue[T]) push(item T) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	switch q.overflow {
+	case OverflowBlock:
+		select {
+		case q.items <- item:
+		case <-q.done:
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case q.items <- item:
+				return
+			default:
+			}
+
+			select {
+			case <-q.items:
+				q.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case q.items <- item:
+		default:
+			q.dropped.Add(1)
+		}
+	}
+}
+
+// close unblocks pushers and closes items channel, it is safe to call close multiple times
+func (q *queue[T]) close() {
+	q.closeOnce.Do(func() {
+		close(q.done)
+
+		q.lock.Lock()
+		q.closed = true
+		close(q.items)
+		q.lock.Unlock()
+	})
+}
+
+// subscriber represents anything that can receive events of the store
+type subscriber[K comparable, V any] interface {
+	deliver(event Event[K, V])
+}
+
+// typedSubscriber delivers only events with values of specified type to underlying watcher
+type typedSubscriber[K comparable, V any] struct {
+	watcher *Watcher[K, V]
+}
+
+func (t typedSubscriber[K, V]) deliver(event Event[K, any]) {
+	value, ok := event.Value.(V)
+	if !ok {
+		return
+	}
+
+	t.watcher.deliver(Event[K, V]{
+		Kind:  event.Kind,
+		Key:   event.Key,
+		Value: value,
+	})
+}
+
+// watchers represents list of subscribers to the store changes
+type watchers[K comparable, V any] struct {
+	lock        sync.RWMutex
+	subscribers map[*subscriberHandle[K, V]]struct{}
+}
+
+// subscriberHandle is a unique handle of subscriber in watchers list
+type subscriberHandle[K comparable, V any] struct {
+	subscriber[K, V]
+}
+
+// subscribe adds subscriber to the list and returns a func that removes it
+func (w *watchers[K, V]) subscribe(sub subscriber[K, V]) func() {
+	handle := &subscriberHandle[K, V]{subscriber: sub}
+
+	w.lock.Lock()
+	if w.subscribers == nil {
+		w.subscribers = make(map[*subscriberHandle[K, V]]struct{})
+	}
+	w.subscribers[handle] = struct{}{}
+	w.lock.Unlock()
+
+	return func() {
+		w.lock.Lock()
+		delete(w.subscribers, handle)
+		w.lock.Unlock()
+	}
+}
+
+// notify delivers events to all subscribers, must not be called while store lock is held
+func (w *watchers[K, V]) notify(events ...Event[K, V]) {
+	if len(events) == 0 {
+		return
+	}
+
+	w.lock.RLock()
+	if len(w.subscribers) == 0 {
+		w.lock.RUnlock()
+		return
+	}
+
+	subscribers := make([]subscriber[K, V], 0, len(w.subscribers))
+	for handle := range w.subscribers {
+		subscribers = append(subscribers, handle.subscriber)
+	}
+	w.lock.RUnlock()
+
+	for _, event := range events {
+		for _, sub := range subscribers {
+			sub.deliver(event)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package memkey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveEvent[K comparable, V any](t *testing.T, w *Watcher[K, V]) Event[K, V] {
+	t.Helper()
+
+	select {
+	case event, ok := <-w.Events():
+		require.True(t, ok, "events channel closed")
+		return event
+	case <-time.After(time.Second):
+		require.FailNow(t, "timeout")
+		return Event[K, V]{}
+	}
+}
+
+func TestEventKind_String(t *testing.T) {
+	assert.Equal(t, "set", EventSet.String())
+	assert.Equal(t, "delete", EventDelete.String())
+	assert.Equal(t, "expire", EventExpire.String())
+	assert.Equal(t, "evict", EventEvict.String())
+	assert.Equal(t, "unknown", EventKind(0).String())
+}
+
+func TestTypedStore_Watch(t *testing.T) {
+	s := &TypedStore[int, string]{}
+
+	t.Run("all", func(t *testing.T) {
+		w := s.Watch(WatchConfig[int, string]{})
+		defer w.Close()
+
+		k := testKey(t)
+		s.Set(k, "a")
+		s.Delete(k)
+		s.Delete(k)
+
+		assert.Equal(t, Event[int, string]{Kind: EventSet, Key: k, Value: "a"}, receiveEvent(t, w))
+		assert.Equal(t, Event[int, string]{Kind: EventDelete, Key: k, Value: "a"}, receiveEvent(t, w))
+		assert.Len(t, w.Events(), 0)
+	})
+
+	t.Run("key", func(t *testing.T) {
+		k := testKey(t)
+		w := s.Watch(WatchConfig[int, string]{Match: MatchKey[string](k)})
+		defer w.Close()
+
+		s.Set(testKey(t), "a")
+		s.Set(k, "b")
+
+		assert.Equal(t, Event[int, string]{Kind: EventSet, Key: k, Value: "b"}, receiveEvent(t, w))
+		assert.Len(t, w.Events(), 0)
+	})
+
+	t.Run("predicate", func(t *testing.T) {
+		w := s.Watch(WatchConfig[int, string]{Match: MatchKeys[string](func(key int) bool {
+			return key < 0
+		})})
+		defer w.Close()
+
+		s.Set(testKey(t), "a")
+		s.Set(-1, "b")
+
+		assert.Equal(t, Event[int, string]{Kind: EventSet, Key: -1, Value: "b"}, receiveEvent(t, w))
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		ts := &TypedStore[int, string]{}
+		w := ts.Watch(WatchConfig[int, string]{})
+		defer w.Close()
+
+		go ts.ExpireTTL(time.Millisecond, nil)
+
+		ts.SetWithTTL(1, "a", time.Millisecond)
+
+		assert.Equal(t, Event[int, string]{Kind: EventSet, Key: 1, Value: "a"}, receiveEvent(t, w))
+		assert.Equal(t, Event[int, string]{Kind: EventExpire, Key: 1, Value: "a"}, receiveEvent(t, w))
+	})
+
+	t.Run("close", func(t *testing.T) {
+		w := s.Watch(WatchConfig[int, string]{})
+		w.Close()
+		w.Close()
+
+		s.Set(testKey(t), "a")
+
+		_, ok := <-w.Events()
+		assert.False(t, ok)
+	})
+}
+
+func TestTypedStore_WatchFunc(t *testing.T) {
+	s := &TypedStore[int, string]{}
+
+	received := make(chan Event[int, string], 1)
+	w := s.WatchFunc(WatchConfig[int, string]{}, func(event Event[int, string]) {
+		received <- event
+	})
+	defer w.Close()
+
+	s.Set(1, "a")
+
+	select {
+	case event := <-received:
+		assert.Equal(t, Event[int, string]{Kind: EventSet, Key: 1, Value: "a"}, event)
+	case <-time.After(time.Second):
+		assert.FailNow(t, "timeout")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s := &Store[int]{}
+
+	w := Watch[string](s, WatchConfig[int, string]{})
+	defer w.Close()
+
+	k1 := testKey(t)
+	Set(s, k1, 1)
+	k2 := testKey(t)
+	Set(s, k2, "a")
+	Delete[int](s, k1)
+	s.Delete(k2)
+
+	assert.Equal(t, Event[int, string]{Kind: EventSet, Key: k2, Value: "a"}, receiveEvent(t, w))
+	assert.Equal(t, Event[int, string]{Kind: EventDelete, Key: k2, Value: "a"}, receiveEvent(t, w))
+	assert.Len(t, w.Events(), 0)
+}
+
+func TestStore_Watch(t *testing.T) {
+	s := &Store[int]{}
+
+	w := s.Watch(WatchConfig[int, any]{})
+	defer w.Close()
+
+	k := testKey(t)
+	Set(s, k, 1)
+	s.Set(k, "a")
+
+	assert.Equal(t, Event[int, any]{Kind: EventSet, Key: k, Value: 1}, receiveEvent(t, w))
+	assert.Equal(t, Event[int, any]{Kind: EventSet, Key: k, Value: "a"}, receiveEvent(t, w))
+}
+
+func TestWatcher_Overflow(t *testing.T) {
+	t.Run("drop_newest", func(t *testing.T) {
+		s := &TypedStore[int, int]{}
+		w := s.Watch(WatchConfig[int, int]{Buffer: 2})
+		defer w.Close()
+
+		for i := 0; i < 5; i++ {
+			s.Set(i, i)
+		}
+
+		assert.Equal(t, uint64(3), w.Dropped())
+		assert.Equal(t, 0, receiveEvent(t, w).Key)
+		assert.Equal(t, 1, receiveEvent(t, w).Key)
+	})
+
+	t.Run("drop_oldest", func(t *testing.T) {
+		s := &TypedStore[int, int]{}
+		w := s.Watch(WatchConfig[int, int]{Buffer: 2, Overflow: OverflowDropOldest})
+		defer w.Close()
+
+		for i := 0; i < 5; i++ {
+			s.Set(i, i)
+		}
+
+		assert.Equal(t, uint64(3), w.Dropped())
+		assert.Equal(t, 3, receiveEvent(t, w).Key)
+		assert.Equal(t, 4, receiveEvent(t, w).Key)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		s := &TypedStore[int, int]{}
+		w := s.Watch(WatchConfig[int, int]{Buffer: 1, Overflow: OverflowBlock})
+
+		s.Set(1, 1)
+
+		done := make(chan struct{})
+		go func() {
+			s.Set(2, 2)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			assert.FailNow(t, "writer not blocked")
+		case <-time.After(time.Millisecond * 10):
+		}
+
+		assert.Equal(t, 1, receiveEvent(t, w).Key)
+		assert.Equal(t, 2, receiveEvent(t, w).Key)
+		<-done
+
+		s.Set(3, 3)
+		go func() {
+			time.Sleep(time.Millisecond)
+			w.Close()
+		}()
+		s.Set(4, 4)
+		assert.Equal(t, uint64(0), w.Dropped())
+	})
+}